@@ -20,7 +20,9 @@ import (
 	"github.com/arxon31/metrics-collector/internal/agent/service/hasher"
 	"github.com/arxon31/metrics-collector/internal/agent/service/poller"
 	"github.com/arxon31/metrics-collector/internal/agent/service/reporter"
+	"github.com/arxon31/metrics-collector/internal/auth"
 	"github.com/arxon31/metrics-collector/internal/repository/memory"
+	"github.com/arxon31/metrics-collector/internal/signing"
 	"github.com/arxon31/metrics-collector/pkg/httpclient"
 )
 
@@ -53,27 +55,72 @@ func run() int {
 
 	repo := memory.NewMapStorage()
 
-	reportClient := httpclient.NewClient()
+	hashKeys, err := signing.ParseKeyring(cfg.HashKey)
+	if err != nil {
+		logger.Logger.Error("failed to parse hash key due to error: %v", err)
+		return 1
+	}
 
-	hashService := hasher.NewHasherService(cfg.HashKey)
+	hashService := hasher.NewHasherService(hashKeys)
 
-	compressService := compressor.NewCompressorService()
+	clientOpts := []httpclient.Option{
+		httpclient.WithHeader(auth.APITokenHeader, cfg.APIToken),
+		httpclient.WithBearerToken(cfg.AuthToken),
+		httpclient.WithHeader(auth.AgentIDHeader, cfg.AgentID),
+	}
+	if cfg.VerifyResponse && !hashKeys.Empty() {
+		clientOpts = append(clientOpts, httpclient.WithResponseVerifier(hashService))
+	}
 
-	cryptoService := encrypting.NewService(cfg.CryptoKey)
+	baseURL := "http://" + cfg.Address
+	if cfg.TLS() {
+		tlsConfig, err := httpclient.NewTLSConfig(cfg.TLSCA, cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			logger.Logger.Error("failed to configure tls due to error: %v", err)
+			return 1
+		}
+		clientOpts = append(clientOpts, httpclient.WithTLSConfig(tlsConfig))
+		baseURL = "https://" + cfg.Address
+	}
 
-	publicKey, err := cryptoService.GetPublicKey()
-	if err != nil {
-		logger.Logger.Error("failed to get public key due to error: %v", err)
-		return 1
+	reportClient := httpclient.NewClient(clientOpts...)
+
+	compressService := compressor.NewCompressorService()
+
+	var cryptoKey encrypting.PublicKey
+	switch {
+	case cfg.CryptoKey != "":
+		publicKey, err := encrypting.NewService(cfg.CryptoKey).GetPublicKey()
+		if err != nil {
+			logger.Logger.Error("failed to get public key due to error: %v", err)
+			return 1
+		}
+		cryptoKey = encrypting.PublicKey{ID: encrypting.KeyID(publicKey), Key: publicKey}
+	case cfg.FetchCryptoKey:
+		// unauthenticated key could be substituted in transit and data would be encrypted for whoever did it
+		if !cfg.TLS() && !(cfg.VerifyResponse && !hashKeys.Empty()) {
+			logger.Logger.Error("fetching public key requires tls or verified response signatures")
+			return 1
+		}
+		cryptoKey, err = encryptor.FetchPublicKey(reportClient, baseURL)
+		if err != nil {
+			logger.Logger.Error("failed to fetch public key due to error: %v", err)
+			return 1
+		}
+	}
+	if cryptoKey.ID != "" {
+		logger.Logger.Info("payload encryption key id: ", cryptoKey.ID)
 	}
 
-	encryptorService := encryptor.NewEncryptorService(publicKey)
+	encryptorService := encryptor.NewEncryptorService(cryptoKey)
 
 	pollService := poller.New(repo)
 
-	generateService := generator.New(cfg.Address, repo, hashService, compressService, encryptorService)
+	generateService := generator.New(baseURL, repo, hashService, compressService, encryptorService)
+
+	reportService := reporter.NewReporter(cfg.RateLimit, reportClient, hashService)
 
-	reportService := reporter.NewReporter(cfg.RateLimit, reportClient)
+	reportService.Report(generateService.GenerateMetadata(ctx))
 
 	pollTicker := time.NewTicker(cfg.PollInterval)
 	defer pollTicker.Stop()
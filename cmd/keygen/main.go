@@ -0,0 +1,182 @@
+// Command keygen bootstraps TLS and payload encryption keys for a new environment.
+// It creates a local CA, a server certificate, a client certificate per agent and RSA payload keys.
+// Existing CA and keys in the output directory are kept unless -force is set, so agents can be added later.
+//
+// Usage:
+//
+//	keygen -out ./certs -hosts localhost,127.0.0.1 -agents agent-1,agent-2 [-key-type ecdsa] [-days 365]
+//
+// Server is started with -tls-cert certs/server.pem -tls-key certs/server.key -tls-client-ca certs/ca.pem -crypto-key certs,
+// agent with -tls-ca certs/ca.pem -tls-cert certs/agent-1.pem -tls-key certs/agent-1.key -crypto-key certs.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/arxon31/metrics-collector/internal/encrypting"
+	"github.com/arxon31/metrics-collector/internal/pki"
+)
+
+const (
+	caName     = "ca"
+	serverName = "server"
+	day        = 24 * time.Hour
+)
+
+var (
+	outDir      = flag.String("out", "certs", "output directory")
+	keyType     = flag.String("key-type", pki.KeyECDSA, "certificate key type: rsa, ecdsa or ed25519")
+	days        = flag.Int("days", 365, "server and agent certificates validity in days")
+	caDays      = flag.Int("ca-days", 3650, "CA certificate validity in days")
+	caCN        = flag.String("ca-cn", "metrics-collector CA", "CA common name")
+	hosts       = flag.String("hosts", "localhost,127.0.0.1", "comma separated server DNS names and IP addresses, the first one is common name")
+	agents      = flag.String("agents", "", "comma separated agent identities to issue client certificates for")
+	payloadKeys = flag.Bool("payload-keys", true, "generate RSA keys for payload encryption")
+	rotate      = flag.Bool("rotate-payload-key", false, "retire current payload key to private.<key id>.pem and generate a new one")
+	force       = flag.Bool("force", false, "overwrite existing server and agent keys, CA and payload keys are never overwritten")
+)
+
+func main() {
+	flag.Parse()
+
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	if *days <= 0 || *caDays <= 0 {
+		return errors.New("validity must be positive")
+	}
+
+	err := os.MkdirAll(*outDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("can not create output directory: %w", err)
+	}
+
+	ca, err := loadOrCreateCA()
+	if err != nil {
+		return err
+	}
+
+	validity := time.Duration(*days) * day
+
+	serverHosts := split(*hosts)
+	if len(serverHosts) > 0 {
+		server, err := ca.IssueServer(serverHosts[0], serverHosts, *keyType, validity)
+		if err != nil {
+			return err
+		}
+		if err = write(server, serverName, *force); err != nil {
+			return err
+		}
+	}
+
+	for _, agent := range split(*agents) {
+		client, err := ca.IssueClient(agent, *keyType, validity)
+		if err != nil {
+			return err
+		}
+		if err = write(client, agent, *force); err != nil {
+			return err
+		}
+	}
+
+	if *payloadKeys {
+		return generatePayloadKeys()
+	}
+
+	return nil
+}
+
+func loadOrCreateCA() (*pki.Certificate, error) {
+	certPath, keyPath := paths(caName)
+
+	ca, err := pki.Load(certPath, keyPath)
+	if err == nil {
+		fmt.Printf("using existing CA %s\n", certPath)
+		return ca, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("can not load CA: %w", err)
+	}
+
+	ca, err = pki.NewCA(*caCN, *keyType, time.Duration(*caDays)*day)
+	if err != nil {
+		return nil, err
+	}
+
+	// CA is never overwritten, certificates issued by it would stop being trusted
+	err = ca.Write(certPath, keyPath, false)
+	if err != nil {
+		return nil, fmt.Errorf("can not save CA: %w", err)
+	}
+
+	fmt.Printf("%s: %s, %s valid until %s\n", caName, certPath, keyPath, ca.Cert.NotAfter.Format(time.DateOnly))
+	return ca, nil
+}
+
+// write saves certificate and key, existing files are kept unless overwrite is set
+func write(cert *pki.Certificate, name string, overwrite bool) error {
+	certPath, keyPath := paths(name)
+
+	err := cert.Write(certPath, keyPath, overwrite)
+	if errors.Is(err, pki.ErrFileExists) {
+		fmt.Printf("%s: keeping existing %s\n", name, keyPath)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: %s, %s valid until %s\n", name, certPath, keyPath, cert.Cert.NotAfter.Format(time.DateOnly))
+	return nil
+}
+
+func generatePayloadKeys() error {
+	keys := encrypting.NewService(*outDir)
+
+	if *rotate {
+		id, err := keys.Rotate()
+		if err != nil {
+			return fmt.Errorf("can not rotate payload key: %w", err)
+		}
+		fmt.Printf("payload keys: rotated, current key id %s\n", id)
+		return nil
+	}
+
+	privatePath := filepath.Join(*outDir, "private.pem")
+	if _, err := os.Stat(privatePath); err == nil {
+		fmt.Printf("payload keys: keeping existing %s\n", privatePath)
+		return nil
+	}
+
+	err := keys.GenerateIfNotExist()
+	if err != nil {
+		return fmt.Errorf("can not generate payload keys: %w", err)
+	}
+
+	fmt.Printf("payload keys: %s\n", *outDir)
+	return nil
+}
+
+func paths(name string) (string, string) {
+	return filepath.Join(*outDir, name+".pem"), filepath.Join(*outDir, name+".key")
+}
+
+func split(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
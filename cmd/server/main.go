@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
-	"crypto/rsa"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/arxon31/metrics-collector/internal/encrypting"
 
@@ -19,15 +21,32 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/idempotency"
 	"github.com/arxon31/metrics-collector/internal/repository"
+	"github.com/arxon31/metrics-collector/internal/repository/cache"
+	"github.com/arxon31/metrics-collector/internal/repository/postgres"
+	"github.com/arxon31/metrics-collector/internal/repository/wal"
 	"github.com/arxon31/metrics-collector/internal/server/config"
 	controllers "github.com/arxon31/metrics-collector/internal/server/controller/rest"
+	"github.com/arxon31/metrics-collector/internal/server/service/alerting"
+	"github.com/arxon31/metrics-collector/internal/server/service/audit"
+	"github.com/arxon31/metrics-collector/internal/server/service/authenticator"
+	"github.com/arxon31/metrics-collector/internal/server/service/backup"
+	"github.com/arxon31/metrics-collector/internal/server/service/deleter"
+	"github.com/arxon31/metrics-collector/internal/server/service/expiration"
 	"github.com/arxon31/metrics-collector/internal/server/service/pinger"
 	"github.com/arxon31/metrics-collector/internal/server/service/provider"
 	"github.com/arxon31/metrics-collector/internal/server/service/storage"
+	"github.com/arxon31/metrics-collector/internal/server/service/tenants"
+	"github.com/arxon31/metrics-collector/internal/signing"
+	"github.com/arxon31/metrics-collector/internal/snapshot"
 	"github.com/arxon31/metrics-collector/pkg/httpserver"
 )
 
+// idempotencyWait limits how long a retried batch waits for the original one still being processed
+const idempotencyWait = 10 * time.Second
+
 var (
 	buildVersion = "N/A"
 	buildDate    = "N/A"
@@ -35,6 +54,14 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrate(os.Args[2:], os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	exitCode := run()
 	if exitCode != 0 {
 		log.Fatal("exited with code", exitCode)
@@ -54,47 +81,243 @@ func run() int {
 		logger.Logger.Fatalf("failed to parse a config due to error: %v", err)
 	}
 
-	repo, err := repository.New(cfg.DBString)
+	storageBackend, err := repository.Backend(cfg.Storage)
+	if err != nil {
+		logger.Logger.Fatalf("failed to parse storage due to error: %v", err)
+	}
+
+	if cfg.CacheFlush > 0 && storageBackend == repository.BackendRedis {
+		logger.Logger.Fatalf("write-back cache can not be used with %s storage, it is shared by replicas and written directly", storageBackend)
+	}
+
+	repo, err := repository.New(ctx, cfg.Storage, postgres.PoolConfig{
+		MaxConns:        cfg.DBMaxConns,
+		MinConns:        cfg.DBMinConns,
+		MaxConnLifetime: cfg.DBConnLifetime,
+		MaxConnIdleTime: cfg.DBConnIdleTime,
+	})
 	if err != nil {
 		logger.Logger.Fatalf("failed to create repository due to error: %v", err)
 	}
 
+	snapCompression, err := snapshot.ParseCompression(cfg.SnapCompression)
+	if err != nil {
+		logger.Logger.Fatalf("failed to parse snapshot compression due to error: %v", err)
+	}
+
+	var failoverService interface {
+		Restore(ctx context.Context) error
+		Run(ctx context.Context)
+		Dump(ctx context.Context) error
+	}
+	if closer, ok := repo.(io.Closer); ok {
+		defer closer.Close()
+	}
+	logger.Logger.Infof("storage backend: %s", storageBackend)
+
+	var cacheRepo *cache.Repository
+	var cacheStats interface {
+		Stats() entity.CacheStats
+	}
+	if cfg.CacheFlush > 0 && storageBackend != repository.BackendMemory {
+		cacheRepo = cache.NewRepository(repo, cache.Options{FlushInterval: cfg.CacheFlush, MaxPending: cfg.CacheMaxPending})
+		err = cacheRepo.Load(ctx)
+		if err != nil {
+			logger.Logger.Fatalf("failed to load write-back cache due to error: %v", err)
+		}
+		repo = cacheRepo
+		cacheStats = cacheRepo
+		logger.Logger.Infof("write-back cache flush interval: %s, max pending: %d", cfg.CacheFlush, cfg.CacheMaxPending)
+	}
+
+	if storageBackend == repository.BackendMemory {
+		failoverOpts := []failover.Option{
+			failover.WithCompression(snapCompression),
+			failover.WithHistory(cfg.SnapKeep),
+		}
+		// dumps restore into the wrapped repository, restored data must not be logged again
+		failoverRepo := repo
+		if cfg.WALDir != "" && cfg.FileStoragePath != "" {
+			walLog, err := wal.Open(cfg.WALDir, wal.Options{Fsync: cfg.WALFsync, SegmentSize: cfg.WALSegmentSize})
+			if err != nil {
+				logger.Logger.Fatalf("failed to open write-ahead log due to error: %v", err)
+			}
+			walRepo := wal.NewRepository(repo, walLog)
+			defer walRepo.Close()
+
+			repo = walRepo
+			failoverOpts = append(failoverOpts, failover.WithWAL(walRepo))
+			logger.Logger.Infof("write-ahead log: %s, fsync: %s", cfg.WALDir, cfg.WALFsync)
+		}
+
+		failoverService = failover.NewService(failoverRepo, cfg.FileStoragePath, cfg.StoreInterval, cfg.Restore, failoverOpts...)
+		err = failoverService.Restore(ctx)
+		if err != nil {
+			logger.Logger.Fatalf("failed to restore data due to error: %v", err)
+		}
+	}
+
 	pingerService := pinger.NewPingerService(repo)
 
-	providerService := provider.NewProviderService(repo)
+	ttlPolicy, err := expiration.NewPolicy(cfg.MetricTTL, cfg.TTLOverrides)
+	if err != nil {
+		logger.Logger.Fatalf("failed to parse metric ttl overrides due to error: %v", err)
+	}
+
+	janitorService := expiration.NewJanitor(repo, ttlPolicy)
+
+	providerService := provider.NewProviderService(repo, ttlPolicy)
+
+	tenantQuota, err := storage.NewQuota(cfg.TenantQuota, cfg.QuotaOverrides)
+	if err != nil {
+		logger.Logger.Fatalf("failed to parse tenant quota overrides due to error: %v", err)
+	}
+
+	storageService := storage.NewStorageService(repo, tenantQuota)
+
+	auditService, err := audit.NewService(cfg.AuditLogPath)
+	if err != nil {
+		logger.Logger.Fatalf("failed to open audit trail due to error: %v", err)
+	}
+	defer auditService.Close()
+
+	deleterService := deleter.NewDeleterService(repo, auditService)
+
+	tenantService := tenants.NewTenantService(repo, auditService)
 
-	storageService := storage.NewStorageService(repo)
+	var snapshotDumper interface {
+		Dump(ctx context.Context) error
+	}
+	if failoverService != nil && cfg.FileStoragePath != "" {
+		snapshotDumper = failoverService
+	}
 
-	cryptoService := encrypting.NewService(cfg.CryptoKey)
+	backupService := backup.NewBackupService(repo, storageService, snapshotDumper, snapCompression, auditService, tenantService)
 
-	var privateKey *rsa.PrivateKey
+	authService, err := authenticator.NewAuthenticator(authenticator.Config{
+		StaticTokens: cfg.AuthTokens,
+		AdminToken:   cfg.AdminToken,
+		JWTSecret:    cfg.JWTSecret,
+		JWTPublicKey: cfg.JWTPublicKey,
+		JWTIssuer:    cfg.JWTIssuer,
+		JWTAudience:  cfg.JWTAudience,
+	})
+	if err != nil {
+		logger.Logger.Fatalf("failed to configure authentication due to error: %v", err)
+	}
 
-	privateKey, err = cryptoService.GetPrivateKey()
+	alertRules, err := alerting.LoadRules(cfg.AlertRulesPath)
 	if err != nil {
-		logger.Logger.Error(err)
+		logger.Logger.Fatalf("failed to load alert rules due to error: %v", err)
+	}
+
+	alertingService := alerting.NewService(repo, alertRules)
+
+	var cryptoKeys *encrypting.Keyring
+	if cfg.CryptoKey != "" {
+		cryptoService := encrypting.NewService(cfg.CryptoKey)
+
 		err = cryptoService.GenerateIfNotExist()
 		if err != nil {
-			logger.Logger.Fatal(err)
+			logger.Logger.Fatalf("failed to generate crypto key due to error: %v", err)
 		}
 
-		privateKey, err = cryptoService.GetPrivateKey()
+		// a corrupt key is fatal, regenerating it would make payloads of agents undecryptable
+		cryptoKeys, err = cryptoService.LoadKeyring()
 		if err != nil {
-			logger.Logger.Fatal(err)
+			logger.Logger.Fatalf("failed to load crypto keys due to error: %v", err)
+		}
+		logger.Logger.Infof("payload encryption key id: %s", cryptoKeys.PublicKey().ID)
+	}
+
+	hashKeys, err := signing.ParseKeyring(cfg.HashKey)
+	if err != nil {
+		logger.Logger.Fatalf("failed to parse hash keys due to error: %v", err)
+	}
+
+	limits := controllers.Limits{
+		RPS:            cfg.RateLimitRPS,
+		Burst:          cfg.RateLimitBurst,
+		MaxBodySize:    cfg.MaxBodySize,
+		MaxRestoreSize: cfg.MaxRestoreSize,
+		MaxBatchSize:   cfg.MaxBatchSize,
+	}
+
+	signature := controllers.Signature{
+		Keys:        hashKeys,
+		Skew:        cfg.HashSkew,
+		AllowLegacy: cfg.HashAllowLegacy,
+		Strict:      cfg.HashStrict,
+	}
+
+	var idempotent controllers.Idempotency
+	if cfg.IdempotencyTTL > 0 {
+		idempotent.Wait = idempotencyWait
+		if storageBackend == repository.BackendPostgres {
+			idempotencyStore, err := idempotency.NewPostgresStore(cfg.Storage, cfg.IdempotencyTTL)
+			if err != nil {
+				logger.Logger.Fatalf("failed to create idempotency store due to error: %v", err)
+			}
+			defer idempotencyStore.Close()
+			idempotent.Store = idempotencyStore
+		} else {
+			idempotent.Store = idempotency.NewMemoryStore(cfg.IdempotencyTTL, cfg.IdempotencyKeys)
 		}
 	}
 
 	mux := chi.NewRouter()
-	controller := controllers.NewController(mux, storageService, providerService, pingerService, cfg.HashKey, privateKey)
+	controller := controllers.NewController(mux, storageService, providerService, pingerService, alertingService, deleterService, tenantService, backupService, cacheStats, authService, limits, signature, cryptoKeys, idempotent)
+
+	serverOpts := []httpserver.Option{httpserver.WithAddr(cfg.Address)}
+	if cfg.TLSCert != "" {
+		tlsMinVersion, err := httpserver.ParseTLSVersion(cfg.TLSMinVersion)
+		if err != nil {
+			logger.Logger.Fatalf("failed to parse tls version due to error: %v", err)
+		}
+
+		tlsCiphers, err := httpserver.ParseCipherSuites(cfg.TLSCiphers)
+		if err != nil {
+			logger.Logger.Fatalf("failed to parse tls cipher suites due to error: %v", err)
+		}
+
+		serverOpts = append(serverOpts,
+			httpserver.WithTLS(cfg.TLSCert, cfg.TLSKey),
+			httpserver.WithTLSPolicy(tlsMinVersion, tlsCiphers),
+		)
+		if cfg.TLSClientCA != "" {
+			serverOpts = append(serverOpts, httpserver.WithClientCA(cfg.TLSClientCA))
+		}
+	}
 
-	server := httpserver.NewHTTPServer(controller, httpserver.WithAddr(cfg.Address))
+	server := httpserver.NewHTTPServer(controller, serverOpts...)
 	logger.Logger.Infof("server listening on: %s", cfg.Address)
 
+	// background services outlive the signal so cache and snapshots are flushed after in-flight requests are served
+	servicesCtx, stopServices := context.WithCancel(context.Background())
+	defer stopServices()
+
 	services := errgroup.Group{}
 
-	if cfg.DBString == "" {
-		failoverService := failover.NewService(repo, cfg.FileStoragePath, cfg.StoreInterval, cfg.Restore)
+	services.Go(func() error {
+		alertingService.Run(servicesCtx)
+		return nil
+	})
+
+	services.Go(func() error {
+		janitorService.Run(servicesCtx)
+		return nil
+	})
+
+	// cache is flushed on shutdown before the wrapped repository is closed
+	if cacheRepo != nil {
 		services.Go(func() error {
-			failoverService.Run(ctx)
+			return cacheRepo.Run(servicesCtx)
+		})
+	}
+
+	if failoverService != nil {
+		services.Go(func() error {
+			failoverService.Run(servicesCtx)
 			return nil
 		})
 	}
@@ -103,24 +326,23 @@ func run() int {
 	case s := <-server.Notify():
 		logger.Logger.Infof("server error: %v", s)
 	case <-ctx.Done():
-		err = services.Wait()
-		if err != nil && !errors.Is(err, context.Canceled) {
-			logger.Logger.Errorf("failed to gracefully shutdown services: %v", err)
-		}
 		logger.Logger.Infof("server terminated")
 	}
 
+	exitCode := 0
+
 	err = server.Shutdown()
 	if err != nil {
 		logger.Logger.Errorf("failed to gracefully shutdown server: %v", err)
-		return 1
+		exitCode = 1
 	}
 
+	stopServices()
 	err = services.Wait()
 	if err != nil && !errors.Is(err, context.Canceled) {
 		logger.Logger.Errorf("failed to gracefully shutdown services: %v", err)
-		return 1
+		exitCode = 1
 	}
 
-	return 0
+	return exitCode
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/arxon31/metrics-collector/internal/repository/postgres"
+)
+
+const migrateUsage = `usage: server migrate [-d dsn] <command>
+
+commands:
+  up             apply all pending migrations
+  down [-steps]  roll back last migrations, one by default
+  status         print applied version and pending migrations
+  force VERSION  set version and clear dirty flag after fixing a failed migration
+
+dsn defaults to DATABASE_DSN environment variable
+`
+
+var errMigrateUsage = errors.New("invalid migrate command")
+
+// runMigrate handles "server migrate" subcommand, schema is also migrated up on every server start
+func runMigrate(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	flags.Usage = func() { fmt.Fprint(stdout, migrateUsage) }
+	dsn := flags.String("d", os.Getenv("DATABASE_DSN"), "database connection string")
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 || *dsn == "" {
+		flags.Usage()
+		return errMigrateUsage
+	}
+
+	migrator, err := postgres.NewMigrator(*dsn)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	command, commandArgs := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		downFlags := flag.NewFlagSet("down", flag.ContinueOnError)
+		downFlags.SetOutput(stdout)
+		steps := downFlags.Int("steps", 1, "number of migrations to roll back")
+		err = downFlags.Parse(commandArgs)
+		if err != nil {
+			return err
+		}
+		err = migrator.Down(*steps)
+	case "force":
+		if len(commandArgs) != 1 {
+			flags.Usage()
+			return errMigrateUsage
+		}
+		version, convErr := strconv.Atoi(commandArgs[0])
+		if convErr != nil {
+			return fmt.Errorf("invalid version %q: %w", commandArgs[0], convErr)
+		}
+		err = migrator.Force(version)
+	case "status":
+	default:
+		flags.Usage()
+		return errMigrateUsage
+	}
+	if err != nil {
+		return err
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	printMigrationStatus(stdout, status)
+
+	return nil
+}
+
+func printMigrationStatus(w io.Writer, status postgres.MigrationStatus) {
+	fmt.Fprintf(w, "version: %d, latest: %d\n", status.Version, status.Latest)
+	if status.Dirty {
+		fmt.Fprintf(w, "dirty: migration %d failed, fix schema and run force\n", status.Version)
+	}
+	if len(status.Pending) == 0 {
+		fmt.Fprintln(w, "pending: none")
+		return
+	}
+	fmt.Fprintf(w, "pending: %v\n", status.Pending)
+}
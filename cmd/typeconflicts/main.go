@@ -0,0 +1,117 @@
+// Command typeconflicts reports metric names stored both as gauge and counter
+// and optionally resolves conflicts by deleting the metric of the other type.
+//
+// Usage:
+//
+//	typeconflicts -d postgres://... [-resolve gauge|counter]
+//	typeconflicts -f /tmp/metrics-db.json
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository"
+	"github.com/arxon31/metrics-collector/internal/repository/postgres"
+	"github.com/arxon31/metrics-collector/internal/snapshot"
+)
+
+var (
+	dbstring = flag.String("d", "", "database connection string")
+	dumpPath = flag.String("f", "", "failover dump file path")
+	resolve  = flag.String("resolve", "", "metric type to keep for conflicting names: gauge or counter, database only")
+)
+
+func main() {
+	flag.Parse()
+
+	if err := run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context) error {
+	if *resolve != "" && *resolve != entity.GaugeType && *resolve != entity.CounterType {
+		return fmt.Errorf("%s:%w", *resolve, entity.ErrMetricType)
+	}
+
+	var (
+		conflicts []string
+		repo      repository.Repository
+		err       error
+	)
+
+	switch {
+	case *dbstring != "":
+		repo, err = repository.New(ctx, *dbstring, postgres.PoolConfig{})
+		if err != nil {
+			return fmt.Errorf("can not connect to database: %w", err)
+		}
+		var metrics []entity.MetricDTO
+		metrics, err = repo.Metrics(ctx)
+		conflicts = entity.FindTypeConflicts(metrics)
+	case *dumpPath != "":
+		if *resolve != "" {
+			return fmt.Errorf("-resolve is supported only with -d")
+		}
+		conflicts, err = dumpConflicts(*dumpPath)
+	default:
+		return fmt.Errorf("either -d or -f must be provided")
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(conflicts) == 0 {
+		fmt.Println("no type conflicts found")
+		return nil
+	}
+
+	for _, name := range conflicts {
+		fmt.Printf("%s: stored both as %s and %s\n", name, entity.GaugeType, entity.CounterType)
+	}
+
+	if *resolve == "" {
+		return nil
+	}
+
+	drop := entity.CounterType
+	if *resolve == entity.CounterType {
+		drop = entity.GaugeType
+	}
+
+	for _, name := range conflicts {
+		if err = repo.Delete(ctx, drop, name); err != nil {
+			return fmt.Errorf("can not delete %s %s: %w", drop, name, err)
+		}
+		fmt.Printf("%s: deleted %s, kept %s\n", name, drop, *resolve)
+	}
+
+	return nil
+}
+
+// dumpConflicts returns conflicting names of every tenant of snapshot file, names of tenants other than default
+// are prefixed with tenant name
+func dumpConflicts(path string) ([]string, error) {
+	snap, err := snapshot.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can not read dump file: %w", err)
+	}
+
+	var conflicts []string
+	for tenant, ns := range snap.Namespaces() {
+		for _, name := range entity.FindTypeConflicts(ns.Metrics) {
+			if tenant != "" {
+				name = tenant + "/" + name
+			}
+			conflicts = append(conflicts, name)
+		}
+	}
+	sort.Strings(conflicts)
+
+	return conflicts, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/snapshot"
+)
+
+func TestDumpConflicts(t *testing.T) {
+	gauge, counter := 1.5, int64(2)
+	conflicting := []entity.MetricDTO{
+		{Name: "load", MetricType: entity.GaugeType, Gauge: &gauge},
+		{Name: "load", MetricType: entity.CounterType, Counter: &counter},
+		{Name: "requests", MetricType: entity.CounterType, Counter: &counter},
+	}
+
+	snap := snapshot.Snapshot{
+		Namespace: snapshot.Namespace{Metrics: conflicting},
+		Tenants: map[string]snapshot.Namespace{
+			"acme": {Metrics: conflicting},
+			"beta": {Metrics: conflicting[1:]},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "metrics-db.json")
+	data, err := snapshot.Encode(snap, snapshot.CompressionZstd)
+	require.NoError(t, err)
+	require.NoError(t, snapshot.WriteFile(path, data, 0))
+
+	conflicts, err := dumpConflicts(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"acme/load", "load"}, conflicts)
+}
@@ -18,15 +18,25 @@ var (
 	address        = flag.String("a", "localhost:8080", "server address")
 	pollInterval   = flag.Int("p", 2, "agent poll interval")
 	reportInterval = flag.Int("r", 10, "agent report interval")
-	hashKey        = flag.String("k", "", "key to hash all sending data")
+	hashKey        = flag.String("k", "", "key to hash all sending data, id:key signs with key id")
 	rateLimit      = flag.Int("l", 100, "agent rate limit")
-	cryptoKeyPath  = flag.String("crypto-key", "", "key to encrypt all sending data")
+	cryptoKeyPath  = flag.String("crypto-key", "", "directory with public.pem to encrypt all sending data, empty disables encryption")
+	fetchCryptoKey = flag.Bool("fetch-crypto-key", false, "fetch current public key from server to encrypt all sending data, requires tls or hash-verify-response")
 	configFilePath = flag.String("c", "", "config file path")
+	apiToken       = flag.String("api-token", "", "tenant api token")
+	authToken      = flag.String("auth-token", "", "bearer token or JWT to authenticate on server")
+	agentID        = flag.String("agent-id", "", "agent identifier, defaults to hostname")
+	tlsCA          = flag.String("tls-ca", "", "CA bundle PEM file path, the only CA trusted for server certificate, enables https")
+	tlsCert        = flag.String("tls-cert", "", "client certificate PEM file path for mutual TLS")
+	tlsKey         = flag.String("tls-key", "", "client private key PEM file path for mutual TLS")
+	verifyResponse = flag.Bool("hash-verify-response", false, "reject server responses without valid signature, requires hash key")
 )
 
 const (
 	PollIntervalEnv   = "POLL_INTERVAL"
 	ReportIntervalEnv = "REPORT_INTERVAL"
+	VerifyResponseEnv = "HASH_VERIFY_RESPONSE"
+	FetchCryptoKeyEnv = "FETCH_CRYPTO_KEY"
 )
 
 type Config struct {
@@ -36,6 +46,19 @@ type Config struct {
 	HashKey        string `env:"KEY" ,json:"hash_key"`
 	RateLimit      int    `env:"RATE_LIMIT" ,json:"rate_limit"`
 	CryptoKey      string `env:"CRYPTO_KEY" ,json:"crypto_key"`
+	APIToken       string `env:"API_TOKEN" json:"api_token"`
+	AuthToken      string `env:"AUTH_TOKEN" json:"auth_token"`
+	AgentID        string `env:"AGENT_ID" json:"agent_id"`
+	VerifyResponse bool
+	FetchCryptoKey bool
+	TLSCA          string `env:"TLS_CA" json:"tls_ca"`
+	TLSCert        string `env:"TLS_CERT" json:"tls_cert"`
+	TLSKey         string `env:"TLS_KEY" json:"tls_key"`
+}
+
+// TLS reports whether agent reports to server over https
+func (c *Config) TLS() bool {
+	return c.TLSCA != "" || c.TLSCert != ""
 }
 
 // NewAgentConfig creates new agent config
@@ -69,6 +92,33 @@ func NewAgentConfig() (*Config, error) {
 		config.CryptoKey = *cryptoKeyPath
 	}
 
+	if config.APIToken == "" {
+		config.APIToken = *apiToken
+	}
+
+	if config.AuthToken == "" {
+		config.AuthToken = *authToken
+	}
+
+	if config.AgentID == "" {
+		config.AgentID = *agentID
+	}
+	if config.AgentID == "" {
+		config.AgentID, _ = os.Hostname()
+	}
+
+	if config.TLSCA == "" {
+		config.TLSCA = *tlsCA
+	}
+
+	if config.TLSCert == "" {
+		config.TLSCert = *tlsCert
+	}
+
+	if config.TLSKey == "" {
+		config.TLSKey = *tlsKey
+	}
+
 	config.PollInterval = time.Duration(*pollInterval) * time.Second
 	pollIntervalString, pollExist := os.LookupEnv(PollIntervalEnv)
 	if pollExist {
@@ -89,6 +139,26 @@ func NewAgentConfig() (*Config, error) {
 		config.ReportInterval = time.Duration(reportIntervalInt) * time.Second
 	}
 
+	config.VerifyResponse = *verifyResponse
+	verifyResponseString, verifyExist := os.LookupEnv(VerifyResponseEnv)
+	if verifyExist {
+		verifyResponseBool, err := strconv.ParseBool(verifyResponseString)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse verify response due to error: %v", err)
+		}
+		config.VerifyResponse = verifyResponseBool
+	}
+
+	config.FetchCryptoKey = *fetchCryptoKey
+	fetchCryptoKeyString, fetchExist := os.LookupEnv(FetchCryptoKeyEnv)
+	if fetchExist {
+		fetchCryptoKeyBool, err := strconv.ParseBool(fetchCryptoKeyString)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse fetch crypto key due to error: %v", err)
+		}
+		config.FetchCryptoKey = fetchCryptoKeyBool
+	}
+
 	return &config, nil
 }
 
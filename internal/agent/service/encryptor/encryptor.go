@@ -1,17 +1,21 @@
 package encryptor
 
 import (
-	"bytes"
-	"crypto/rand"
-	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/arxon31/metrics-collector/internal/encrypting"
 )
 
+const publicKeyPath = "/api/v1/encryption-key"
+
 type encryptor struct {
-	key *rsa.PublicKey
+	key encrypting.PublicKey
 }
 
-// NewEncryptorService creates new encryptor service
-func NewEncryptorService(key *rsa.PublicKey) *encryptor {
+// NewEncryptorService creates new encryptor service, zero key disables encryption
+func NewEncryptorService(key encrypting.PublicKey) *encryptor {
 	return &encryptor{
 		key: key,
 	}
@@ -19,27 +23,53 @@ func NewEncryptorService(key *rsa.PublicKey) *encryptor {
 
 // Encrypt encrypts data by provided key
 func (e *encryptor) Encrypt(data []byte) ([]byte, error) {
-	var encryptedData bytes.Buffer
+	return encrypting.Encrypt(e.key.Key, data)
+}
+
+// KeyID returns ID of the key payloads are encrypted with, empty if encryption is disabled
+func (e *encryptor) KeyID() string {
+	return e.key.ID
+}
+
+type doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// FetchPublicKey requests current public key published by server at baseURL
+func FetchPublicKey(client doer, baseURL string) (encrypting.PublicKey, error) {
+	req, err := http.NewRequest(http.MethodGet, baseURL+publicKeyPath, nil)
+	if err != nil {
+		return encrypting.PublicKey{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return encrypting.PublicKey{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return encrypting.PublicKey{}, fmt.Errorf("can not fetch public key: unexpected status code %d", resp.StatusCode)
+	}
 
-	chunkSize := e.key.Size() - 11
+	var published struct {
+		KeyID     string `json:"key_id"`
+		PublicKey string `json:"public_key"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&published)
+	if err != nil {
+		return encrypting.PublicKey{}, fmt.Errorf("can not decode public key: %w", err)
+	}
 
-	chunks := len(data) / chunkSize
+	key, err := encrypting.ParsePublicKey([]byte(published.PublicKey))
+	if err != nil {
+		return encrypting.PublicKey{}, fmt.Errorf("can not parse public key: %w", err)
+	}
 
-	for i := 0; i <= chunks; i++ {
-		start := i * chunkSize
-		end := start + chunkSize
-		if end > len(data) {
-			end = len(data)
-		}
-		encryptedChunk, err := rsa.EncryptPKCS1v15(rand.Reader, e.key, data[start:end])
-		if err != nil {
-			return nil, err
-		}
-		_, err = encryptedData.Write(encryptedChunk)
-		if err != nil {
-			return nil, err
-		}
+	// key ID is a fingerprint so it is recomputed instead of trusted
+	if id := encrypting.KeyID(key); id != published.KeyID {
+		return encrypting.PublicKey{}, fmt.Errorf("public key id %s does not match published %s", id, published.KeyID)
 	}
 
-	return encryptedData.Bytes(), nil
+	return encrypting.PublicKey{ID: published.KeyID, Key: key}, nil
 }
@@ -4,22 +4,28 @@ package generator
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
 
 	"github.com/arxon31/metrics-collector/pkg/logger"
 
+	"github.com/arxon31/metrics-collector/internal/encrypting"
 	"github.com/arxon31/metrics-collector/internal/entity"
 )
 
 const (
-	metricURL = "update"
-	batchURL  = "updates"
+	metricURL   = "update"
+	batchURL    = "updates"
+	metadataURL = "api/v1/metadata"
 )
 
-const hashHeader = "HashSHA256"
+// idempotencyKeyHeader identifies batch, server applies a retried batch only once
+const idempotencyKeyHeader = "Idempotency-Key"
 
 type repo interface {
 	StoreCounter(ctx context.Context, name string, value int64) error
@@ -27,7 +33,7 @@ type repo interface {
 }
 
 type hasher interface {
-	Hash([]byte) (string, error)
+	Sign(req *http.Request, body []byte) error
 }
 
 type compressor interface {
@@ -36,10 +42,11 @@ type compressor interface {
 
 type encryptor interface {
 	Encrypt([]byte) ([]byte, error)
+	KeyID() string
 }
 
 type requestGenerator struct {
-	address    string
+	baseURL    string
 	rateLimit  int
 	repo       repo
 	hasher     hasher
@@ -47,9 +54,10 @@ type requestGenerator struct {
 	encryptor  encryptor
 }
 
-func New(address string, repo repo, hasher hasher, compressor compressor, encryptor encryptor) *requestGenerator {
+// New creates request generator for server at baseURL, e.g. https://localhost:8080
+func New(baseURL string, repo repo, hasher hasher, compressor compressor, encryptor encryptor) *requestGenerator {
 	g := &requestGenerator{
-		address:    address,
+		baseURL:    baseURL,
 		repo:       repo,
 		hasher:     hasher,
 		compressor: compressor,
@@ -59,17 +67,72 @@ func New(address string, repo repo, hasher hasher, compressor compressor, encryp
 	return g
 }
 
-// Generate func generating requests and sending them to generated channel
+// Generate func generating requests and sending them to generated channel, the channel is closed once all are sent
 // Below you can see all the methods that can be used
 func (g *requestGenerator) Generate(ctx context.Context) <-chan *http.Request {
 	requests := make(chan *http.Request)
 
-	go g.makeBatchMetricsRequest(ctx, requests)
-	//go g.makeCompressedMetricsRequest(ctx, requests)
+	go func() {
+		defer close(requests)
+		g.makeBatchMetricsRequest(ctx, requests)
+		//g.makeCompressedMetricsRequest(ctx, requests)
+	}()
 
 	return requests
 }
 
+// GenerateMetadata func generating requests registering metadata of agent runtime metrics, the channel is closed once all are sent
+func (g *requestGenerator) GenerateMetadata(ctx context.Context) <-chan *http.Request {
+	requests := make(chan *http.Request)
+
+	go g.makeMetadataRequests(ctx, requests)
+
+	return requests
+}
+
+func (g *requestGenerator) makeMetadataRequests(ctx context.Context, requests chan *http.Request) {
+	defer close(requests)
+
+	for _, md := range entity.RuntimeMetadata {
+		body, err := json.Marshal(md)
+		if err != nil {
+			logger.Logger.Error(err)
+			continue
+		}
+
+		path, err := url.JoinPath(g.baseURL, metadataURL, md.Name)
+		if err != nil {
+			logger.Logger.Error(err)
+			continue
+		}
+
+		body, keyID, err := g.encrypt(body)
+		if err != nil {
+			logger.Logger.Error(err)
+			continue
+		}
+
+		req, err := http.NewRequest(http.MethodPut, path, bytes.NewBuffer(body))
+		if err != nil {
+			logger.Logger.Error(err)
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+		setKeyID(req, keyID)
+
+		if err = g.hasher.Sign(req, body); err != nil {
+			logger.Logger.Error(err)
+			continue
+		}
+
+		select {
+		case requests <- req:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func (g *requestGenerator) makeGaugeURLRequest(ctx context.Context, requests chan *http.Request) {
 	metrics, err := g.repo.Metrics(ctx)
 	if err != nil {
@@ -172,22 +235,27 @@ func (g *requestGenerator) makeGZIPMetricsRequest(ctx context.Context, requests
 		return
 	}
 
+	body, keyID, err := g.encrypt(metricsBatchCompressed)
+	if err != nil {
+		logger.Logger.Error(err)
+		return
+	}
+
 	url := g.makeURL2(batchURL)
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(metricsBatchCompressed))
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
 	if err != nil {
 		logger.Logger.Error(err)
 		return
 	}
 	req.Header.Set("Content-Encoding", "gzip")
 	req.Header.Set("Content-Type", "application/json")
+	setKeyID(req, keyID)
 
-	hashSign, err := g.hasher.Hash(metricsBatchCompressed)
-
-	if err != nil {
-		requests <- req
+	if err = g.hasher.Sign(req, body); err != nil {
+		logger.Logger.Error(err)
 		return
 	}
-	req.Header.Set(hashHeader, hashSign)
+
 	requests <- req
 }
 
@@ -210,27 +278,69 @@ func (g *requestGenerator) makeBatchMetricsRequest(ctx context.Context, requests
 		return
 	}
 
+	body, keyID, err := g.encrypt(metricsBatchCompressed)
+	if err != nil {
+		logger.Logger.Error(err)
+		return
+	}
+
 	url := g.makeURL2(batchURL)
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(metricsBatchCompressed))
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
 	if err != nil {
 		logger.Logger.Error(err)
 		return
 	}
 	req.Header.Set("Content-Encoding", "gzip")
 	req.Header.Set("Content-Type", "application/json")
+	setKeyID(req, keyID)
 
-	hashSign, err := g.hasher.Hash(metricsBatchCompressed)
-
+	// key is generated once per batch, retries of the request keep it
+	key, err := newIdempotencyKey()
 	if err != nil {
-		requests <- req
+		logger.Logger.Error(err)
 		return
 	}
-	req.Header.Set(hashHeader, hashSign)
+	req.Header.Set(idempotencyKeyHeader, key)
+
+	if err = g.hasher.Sign(req, body); err != nil {
+		logger.Logger.Error(err)
+		return
+	}
+
 	requests <- req
 }
 
+func newIdempotencyKey() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("can not generate idempotency key: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// encrypt encrypts payload if encryption key is configured and returns ID of the key
+func (g *requestGenerator) encrypt(payload []byte) ([]byte, string, error) {
+	keyID := g.encryptor.KeyID()
+	if keyID == "" {
+		return payload, "", nil
+	}
+
+	encrypted, err := g.encryptor.Encrypt(payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return encrypted, keyID, nil
+}
+
+func setKeyID(req *http.Request, keyID string) {
+	if keyID != "" {
+		req.Header.Set(encrypting.KeyIDHeader, keyID)
+	}
+}
+
 func (g *requestGenerator) makeURL(metricType, name, val string) string {
-	path, err := url.JoinPath("http://", g.address, metricURL, metricType, name, val)
+	path, err := url.JoinPath(g.baseURL, metricURL, metricType, name, val)
 	if err != nil {
 		logger.Logger.Error(err)
 		return ""
@@ -239,7 +349,7 @@ func (g *requestGenerator) makeURL(metricType, name, val string) string {
 }
 
 func (g *requestGenerator) makeURL2(endpoint string) string {
-	path, err := url.JoinPath("http://", g.address, endpoint, "/")
+	path, err := url.JoinPath(g.baseURL, endpoint, "/")
 	if err != nil {
 		logger.Logger.Error(err)
 		return ""
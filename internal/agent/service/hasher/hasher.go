@@ -1,32 +1,99 @@
 package hasher
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
+	"bytes"
 	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/arxon31/metrics-collector/internal/signing"
 )
 
-var errNoHashKey = errors.New("no hash key provided")
+var (
+	errNoHashKey        = errors.New("no hash key provided")
+	errUnsignedResponse = errors.New("response is not signed")
+	errUnknownKeyID     = errors.New("response is signed with unknown key id")
+	errNonceMismatch    = errors.New("response nonce does not match request")
+	errBadSignature     = errors.New("response signature is not valid")
+)
 
 type hasher struct {
-	hashKey string
+	keys *signing.Keyring
+	now  func() time.Time
 }
 
-// NewHasherService creates new hasher service
-func NewHasherService(hashKey string) *hasher {
+// NewHasherService creates new hasher service signing with the first key of the keyring
+func NewHasherService(keys *signing.Keyring) *hasher {
 	return &hasher{
-		hashKey: hashKey,
+		keys: keys,
+		now:  time.Now,
+	}
+}
+
+// Sign sets signature, key ID, timestamp and nonce headers of request with body,
+// request is left unsigned if no keys are configured
+func (h *hasher) Sign(req *http.Request, body []byte) error {
+	if h.keys.Empty() {
+		return nil
+	}
+
+	key, ok := h.keys.Current()
+	if !ok {
+		return errNoHashKey
+	}
+
+	nonce, err := signing.NewNonce()
+	if err != nil {
+		return err
 	}
+	timestamp := strconv.FormatInt(h.now().Unix(), 10)
+
+	if key.ID != "" {
+		req.Header.Set(signing.KeyIDHeader, key.ID)
+	}
+	req.Header.Set(signing.TimestampHeader, timestamp)
+	req.Header.Set(signing.NonceHeader, nonce)
+	req.Header.Set(signing.HashHeader, signing.Sign(key.Secret, req.Method, req.URL.RequestURI(), timestamp, nonce, body))
+
+	return nil
 }
 
-// Hash hashes data by provided hash key
-func (h *hasher) Hash(data []byte) (sign string, err error) {
-	if h.hashKey == "" {
-		return "", errNoHashKey
+// Verify checks that response is signed by server for the request it answers
+func (h *hasher) Verify(resp *http.Response) error {
+	if h.keys.Empty() {
+		return nil
 	}
-	hash := hmac.New(sha256.New, []byte(h.hashKey))
-	hash.Write(data)
-	s := hash.Sum(nil)
-	return base64.StdEncoding.EncodeToString(s), nil
+
+	signFromResp := resp.Header.Get(signing.HashHeader)
+	if signFromResp == "" {
+		return errUnsignedResponse
+	}
+
+	key, ok := h.keys.Key(resp.Header.Get(signing.KeyIDHeader))
+	if !ok {
+		return errUnknownKeyID
+	}
+
+	req := resp.Request
+	nonce := resp.Header.Get(signing.NonceHeader)
+	if nonce != req.Header.Get(signing.NonceHeader) {
+		return errNonceMismatch
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	timestamp := resp.Header.Get(signing.TimestampHeader)
+	sign := signing.SignResponse(key.Secret, resp.StatusCode, req.Method, req.URL.RequestURI(), timestamp, nonce, body)
+	if !signing.Equal(sign, signFromResp) {
+		return errBadSignature
+	}
+
+	return nil
 }
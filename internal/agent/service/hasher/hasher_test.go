@@ -0,0 +1,104 @@
+package hasher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/middlewares"
+	"github.com/arxon31/metrics-collector/internal/signing"
+	"github.com/arxon31/metrics-collector/pkg/httpclient"
+)
+
+func TestVerifyGzippedResponse(t *testing.T) {
+	keys, err := signing.ParseKeyring("v1:secret")
+	require.NoError(t, err)
+
+	const answer = `[{"id":"PollCount","type":"counter","delta":1}]`
+
+	hashing := middlewares.NewHashingMiddleware(keys, time.Minute, false, false)
+	compressing := middlewares.NewCompressingMiddleware(1 << 20)
+	server := httptest.NewServer(hashing.WithHash(compressing.WithCompress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, answer, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))))
+	defer server.Close()
+
+	h := NewHasherService(keys)
+	client := httpclient.NewClient(httpclient.WithResponseVerifier(h))
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(answer))
+	require.NoError(t, zw.Close())
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/updates/", bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	require.NoError(t, h.Sign(req, buf.Bytes()))
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.True(t, resp.Uncompressed, "response was sent gzipped")
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, answer, string(body))
+}
+
+func TestSignedQuery(t *testing.T) {
+	keys, err := signing.ParseKeyring("v1:secret")
+	require.NoError(t, err)
+
+	hashing := middlewares.NewHashingMiddleware(keys, time.Minute, false, true)
+	server := httptest.NewServer(hashing.WithHash(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Query().Get("id")))
+	})))
+	defer server.Close()
+
+	h := NewHasherService(keys)
+	client := httpclient.NewClient(httpclient.WithResponseVerifier(h))
+
+	newRequest := func() *http.Request {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/value/?id=Alloc", nil)
+		require.NoError(t, err)
+		require.NoError(t, h.Sign(req, nil))
+		return req
+	}
+
+	t.Run("signed_query_is_accepted", func(t *testing.T) {
+		resp, err := client.Do(newRequest())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "Alloc", string(body))
+	})
+
+	t.Run("tampered_query_is_rejected", func(t *testing.T) {
+		req := newRequest()
+		req.URL.RawQuery = "id=PollCount"
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
@@ -2,7 +2,8 @@
 package reporter
 
 import (
-	"context"
+	"bytes"
+	"io"
 	"net/http"
 	"time"
 
@@ -11,59 +12,106 @@ import (
 	"go.uber.org/zap"
 )
 
-const workerTimeout = 2 * time.Second
+// retryDelays are pauses before repeated attempts of requests failed by network or server errors
+var retryDelays = []time.Duration{time.Second, 3 * time.Second, 5 * time.Second}
 
 type reporter interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+type signer interface {
+	Sign(req *http.Request, body []byte) error
+}
+
 type metricReporter struct {
 	rateLimit      int
 	reportInterval time.Duration
 	reporter       reporter
+	signer         signer
+	retryDelays    []time.Duration
 }
 
-// NewReporter creates new reporter
-func NewReporter(rateLimit int, reporter reporter) *metricReporter {
+// NewReporter creates new reporter, retried requests are signed again by signer
+func NewReporter(rateLimit int, reporter reporter, signer signer) *metricReporter {
 
 	rep := &metricReporter{
-		reporter:  reporter,
-		rateLimit: rateLimit,
+		reporter:    reporter,
+		rateLimit:   rateLimit,
+		signer:      signer,
+		retryDelays: retryDelays,
 	}
 
 	return rep
 }
 
-// Report func sends rest requests to server
+// Report func sends rest requests to server, workers send requests until reqChan is closed
 func (r *metricReporter) Report(reqChan <-chan *http.Request) {
 	for i := 0; i < r.rateLimit; i++ {
 		go r.runWorker(reqChan)
 	}
 }
 
-func (r *metricReporter) runWorker(reqChan <-chan *http.Request) error {
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), workerTimeout)
-	defer cancel()
-
-	for {
-		select {
-		case <-timeoutCtx.Done():
-			return nil
-		case req, ok := <-reqChan:
-			if !ok {
-				return nil
-			}
-			resp, err := r.reporter.Do(req)
-			if err != nil {
-				return err
-			}
-			defer resp.Body.Close()
-			if resp.StatusCode != http.StatusOK {
+func (r *metricReporter) runWorker(reqChan <-chan *http.Request) {
+	for req := range reqChan {
+		r.send(req)
+	}
+}
+
+// send sends request, it is repeated after network errors and server errors until retry delays are exhausted
+func (r *metricReporter) send(req *http.Request) {
+	for attempt := 0; ; attempt++ {
+		resp, err := r.reporter.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		if !retryable(resp, err) || attempt == len(r.retryDelays) {
+			switch {
+			case err != nil:
+				logger.Logger.Error("can not send request", zap.String("url", req.URL.String()), zap.Error(err))
+			case resp.StatusCode != http.StatusOK:
 				logger.Logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
+			default:
+				logger.Logger.Info("request processed")
 			}
-			logger.Logger.Info("request processed")
-			return nil
+			return
 		}
+
+		time.Sleep(r.retryDelays[attempt])
+
+		req, err = r.renew(req)
+		if err != nil {
+			logger.Logger.Error("can not repeat request", zap.Error(err))
+			return
+		}
+	}
+}
+
+// renew copies request for another attempt, it keeps headers including idempotency key
+// and is signed again since server rejects reused nonces
+func (r *metricReporter) renew(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+
+	var body []byte
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		body, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = io.NopCloser(bytes.NewReader(body))
 	}
 
+	return retry, r.signer.Sign(retry, body)
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
 }
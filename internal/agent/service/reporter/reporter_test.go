@@ -0,0 +1,115 @@
+package reporter
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type reporterStub struct {
+	mu       sync.Mutex
+	sent     []*http.Request
+	bodies   []string
+	statuses []int
+}
+
+func (r *reporterStub) Do(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r.sent = append(r.sent, req)
+	r.bodies = append(r.bodies, string(body))
+
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+type signerStub struct {
+	signed int
+}
+
+func (s *signerStub) Sign(req *http.Request, body []byte) error {
+	s.signed++
+	req.Header.Set("Nonce", strconv.Itoa(s.signed))
+	return nil
+}
+
+func (r *reporterStub) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sent)
+}
+
+func TestReport_DrainsChannel(t *testing.T) {
+	const total = 20
+
+	stub := &reporterStub{}
+	requests := make(chan *http.Request)
+	NewReporter(2, stub, &signerStub{}).Report(requests)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(requests)
+		for i := 0; i < total; i++ {
+			req, err := http.NewRequest(http.MethodPut, "http://localhost/api/v1/metadata/m", nil)
+			require.NoError(t, err)
+			requests <- req
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("generator is blocked on sending requests")
+	}
+	require.Eventually(t, func() bool { return stub.count() == total }, time.Second, 10*time.Millisecond)
+}
+
+func TestReport_RetriesWithSameIdempotencyKey(t *testing.T) {
+	stub := &reporterStub{statuses: []int{http.StatusServiceUnavailable, http.StatusInternalServerError}}
+	signer := &signerStub{}
+	r := NewReporter(1, stub, signer)
+	r.retryDelays = []time.Duration{0, 0, 0}
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/updates/", strings.NewReader("batch"))
+	require.NoError(t, err)
+	req.Header.Set("Idempotency-Key", "k1")
+	r.send(req)
+
+	require.Len(t, stub.sent, 3)
+	require.Equal(t, 2, signer.signed, "retries are signed again")
+	for i, sent := range stub.sent {
+		require.Equal(t, "k1", sent.Header.Get("Idempotency-Key"))
+		require.Equal(t, "batch", stub.bodies[i])
+	}
+	require.NotEqual(t, stub.sent[1].Header.Get("Nonce"), stub.sent[2].Header.Get("Nonce"))
+
+	t.Run("client_error_is_not_retried", func(t *testing.T) {
+		stub := &reporterStub{statuses: []int{http.StatusBadRequest}}
+		r := NewReporter(1, stub, &signerStub{})
+		r.retryDelays = []time.Duration{0}
+
+		req, err := http.NewRequest(http.MethodPost, "http://localhost/updates/", strings.NewReader("batch"))
+		require.NoError(t, err)
+		r.send(req)
+		require.Len(t, stub.sent, 1)
+	})
+}
@@ -0,0 +1,45 @@
+// Package auth keeps identity, role and tenant of the request initiator in context
+package auth
+
+import "context"
+
+type subjectKey struct{}
+
+const anonymous = "anonymous"
+
+// WithSubject returns context carrying subject identity
+func WithSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, subjectKey{}, subject)
+}
+
+// Subject returns subject identity from context
+func Subject(ctx context.Context) string {
+	if subject, ok := ctx.Value(subjectKey{}).(string); ok && subject != "" {
+		return subject
+	}
+	return anonymous
+}
+
+type tenantKey struct{}
+
+// DefaultTenant is a namespace of requests without API token
+const DefaultTenant = ""
+
+// WithTenant returns context carrying tenant namespace
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// Tenant returns tenant namespace from context
+func Tenant(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		return tenant
+	}
+	return DefaultTenant
+}
+
+// APITokenHeader carries tenant api token, bearer token is used if header is absent
+const APITokenHeader = "X-API-Token"
+
+// AgentIDHeader carries identifier of the agent instance
+const AgentIDHeader = "X-Agent-ID"
@@ -0,0 +1,48 @@
+package auth
+
+import "context"
+
+// Roles are ordered, every role grants permissions of the previous ones
+const (
+	RoleReader = "reader"
+	RoleWriter = "writer"
+	RoleAdmin  = "admin"
+)
+
+var roleLevels = map[string]int{
+	RoleReader: 1,
+	RoleWriter: 2,
+	RoleAdmin:  3,
+}
+
+// ValidRole reports whether role is known
+func ValidRole(role string) bool {
+	_, ok := roleLevels[role]
+	return ok
+}
+
+// Allows reports whether role grants permissions of required role
+func Allows(role, required string) bool {
+	level, ok := roleLevels[role]
+	return ok && level >= roleLevels[required]
+}
+
+// Identity is an authenticated subject
+type Identity struct {
+	Subject string
+	Role    string
+	Tenant  string
+}
+
+type roleKey struct{}
+
+// WithRole returns context carrying role of authenticated subject
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleKey{}, role)
+}
+
+// Role returns role of authenticated subject, empty if request was not authenticated
+func Role(ctx context.Context) string {
+	role, _ := ctx.Value(roleKey{}).(string)
+	return role
+}
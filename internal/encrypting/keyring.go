@@ -0,0 +1,85 @@
+package encrypting
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+)
+
+// KeyIDHeader tags encrypted payload with ID of the public key it was encrypted with
+const KeyIDHeader = "X-Encryption-Key-ID"
+
+// PublicKey is a public key with its ID
+type PublicKey struct {
+	ID  string
+	Key *rsa.PublicKey
+}
+
+// Keyring keeps current private key and retired keys by ID
+type Keyring struct {
+	current *rsa.PrivateKey
+	keys    map[string]*rsa.PrivateKey
+}
+
+func newKeyring(current *rsa.PrivateKey) *Keyring {
+	k := &Keyring{
+		current: current,
+		keys:    make(map[string]*rsa.PrivateKey),
+	}
+	k.add(current)
+	return k
+}
+
+func (k *Keyring) add(key *rsa.PrivateKey) {
+	k.keys[KeyID(&key.PublicKey)] = key
+}
+
+// Current returns private key agents should encrypt for
+func (k *Keyring) Current() *rsa.PrivateKey {
+	return k.current
+}
+
+// PublicKey returns public part of current key
+func (k *Keyring) PublicKey() PublicKey {
+	return PublicKey{ID: KeyID(&k.current.PublicKey), Key: &k.current.PublicKey}
+}
+
+// Key returns private key by ID
+func (k *Keyring) Key(id string) (*rsa.PrivateKey, bool) {
+	key, ok := k.keys[id]
+	return key, ok
+}
+
+// Encrypt encrypts data by chunks fitting PKCS1 v1.5 padding
+func Encrypt(key *rsa.PublicKey, data []byte) ([]byte, error) {
+	var encrypted bytes.Buffer
+
+	chunkSize := key.Size() - 11
+	for start := 0; start < len(data); start += chunkSize {
+		end := min(start+chunkSize, len(data))
+		chunk, err := rsa.EncryptPKCS1v15(rand.Reader, key, data[start:end])
+		if err != nil {
+			return nil, err
+		}
+		encrypted.Write(chunk)
+	}
+
+	return encrypted.Bytes(), nil
+}
+
+// Decrypt decrypts data encrypted by Encrypt, every encrypted chunk is of key size
+func Decrypt(key *rsa.PrivateKey, data []byte) ([]byte, error) {
+	var decrypted bytes.Buffer
+
+	chunkSize := key.Size()
+	for start := 0; start < len(data); start += chunkSize {
+		end := min(start+chunkSize, len(data))
+		chunk, err := rsa.DecryptPKCS1v15(nil, key, data[start:end])
+		if err != nil {
+			return nil, err
+		}
+		decrypted.Write(chunk)
+	}
+
+	return decrypted.Bytes(), nil
+}
@@ -1,20 +1,22 @@
 package encrypting
 
 import (
-	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
-	"io"
+	"errors"
+	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 )
 
 const (
 	privateKeyName = "private.pem"
 	publicKeyName  = "public.pem"
-	KeyLengthBits  = 4096
+	// retired keys are kept as private.<key id>.pem to decrypt payloads encrypted before rotation
+	retiredKeyPrefix = "private."
+	retiredKeySuffix = ".pem"
+	KeyLengthBits    = 4096
 )
 
 type keypair struct {
@@ -27,102 +29,131 @@ func NewService(keyPath string) *keypair {
 	}
 }
 
+// GenerateIfNotExist generates key pair unless private key file exists, existing key is never overwritten
 func (k *keypair) GenerateIfNotExist() error {
-	privateKeyPath := path.Join(k.keysPath, privateKeyName)
-	publicKeyPath := path.Join(k.keysPath, publicKeyName)
+	_, err := os.Stat(path.Join(k.keysPath, privateKeyName))
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return k.generate()
+}
 
-	privateKeyFile, err := os.OpenFile(privateKeyPath, os.O_RDWR|os.O_CREATE, 0600)
+// Rotate retires current private key and generates a new key pair, returns ID of the new key
+func (k *keypair) Rotate() (string, error) {
+	current, err := k.GetPrivateKey()
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer privateKeyFile.Close()
 
-	publicKeyFile, err := os.OpenFile(publicKeyPath, os.O_RDWR|os.O_CREATE, 0600)
+	retiredPath := path.Join(k.keysPath, retiredKeyPrefix+KeyID(&current.PublicKey)+retiredKeySuffix)
+	err = os.Rename(path.Join(k.keysPath, privateKeyName), retiredPath)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("can not retire private key: %w", err)
+	}
+
+	err = k.generate()
+	if err != nil {
+		return "", err
+	}
+
+	publicKey, err := k.GetPublicKey()
+	if err != nil {
+		return "", err
 	}
-	defer publicKeyFile.Close()
 
+	return KeyID(publicKey), nil
+}
+
+func (k *keypair) generate() error {
 	privateKey, err := rsa.GenerateKey(rand.Reader, KeyLengthBits)
 	if err != nil {
 		return err
 	}
 
-	var privateKeyPEM, publicKeyPEM bytes.Buffer
-
-	err = pem.Encode(&privateKeyPEM, &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	})
+	privateKeyPEM, err := EncodePrivateKey(privateKey)
 	if err != nil {
 		return err
 	}
 
-	err = pem.Encode(&publicKeyPEM, &pem.Block{
-		Type:  "RSA PUBLIC KEY",
-		Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey),
-	})
+	publicKeyPEM, err := EncodePublicKey(&privateKey.PublicKey)
 	if err != nil {
 		return err
 	}
 
-	_, err = privateKeyFile.Write(privateKeyPEM.Bytes())
+	// O_EXCL guards against replacing a key created concurrently
+	privateKeyFile, err := os.OpenFile(path.Join(k.keysPath, privateKeyName), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
 	if err != nil {
 		return err
 	}
+	defer privateKeyFile.Close()
 
-	_, err = publicKeyFile.Write(publicKeyPEM.Bytes())
+	_, err = privateKeyFile.Write(privateKeyPEM)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return os.WriteFile(path.Join(k.keysPath, publicKeyName), publicKeyPEM, 0644)
 }
 
 func (k *keypair) GetPrivateKey() (*rsa.PrivateKey, error) {
-	privateKeyPath := path.Join(k.keysPath, privateKeyName)
+	return readPrivateKey(path.Join(k.keysPath, privateKeyName))
+}
 
-	privateKeyFile, err := os.Open(privateKeyPath)
+func (k *keypair) GetPublicKey() (*rsa.PublicKey, error) {
+	publicKeyPath := path.Join(k.keysPath, publicKeyName)
+
+	publicKeyBytes, err := os.ReadFile(publicKeyPath)
 	if err != nil {
 		return nil, err
 	}
-	defer privateKeyFile.Close()
 
-	privateKeyBytes, err := io.ReadAll(privateKeyFile)
+	publicKey, err := ParsePublicKey(publicKeyBytes)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%s: %w", publicKeyPath, err)
 	}
 
-	privateKeyBlock, _ := pem.Decode(privateKeyBytes)
+	return publicKey, nil
+}
 
-	privateKey, err := x509.ParsePKCS1PrivateKey(privateKeyBlock.Bytes)
+// LoadKeyring loads current private key and all retired ones, a corrupt key file is an error
+func (k *keypair) LoadKeyring() (*Keyring, error) {
+	current, err := k.GetPrivateKey()
 	if err != nil {
 		return nil, err
 	}
 
-	return privateKey, nil
-}
-
-func (k *keypair) GetPublicKey() (*rsa.PublicKey, error) {
-	publicKeyPath := path.Join(k.keysPath, publicKeyName)
+	keyring := newKeyring(current)
 
-	publicKeyFile, err := os.Open(publicKeyPath)
+	retired, err := filepath.Glob(path.Join(k.keysPath, retiredKeyPrefix+"*"+retiredKeySuffix))
 	if err != nil {
 		return nil, err
 	}
-	defer publicKeyFile.Close()
 
-	publicKeyBytes, err := io.ReadAll(publicKeyFile)
-	if err != nil {
-		return nil, err
+	for _, keyPath := range retired {
+		key, err := readPrivateKey(keyPath)
+		if err != nil {
+			return nil, err
+		}
+		keyring.add(key)
 	}
 
-	publicKeyBlock, _ := pem.Decode(publicKeyBytes)
+	return keyring, nil
+}
 
-	publicKey, err := x509.ParsePKCS1PublicKey(publicKeyBlock.Bytes)
+func readPrivateKey(keyPath string) (*rsa.PrivateKey, error) {
+	privateKeyBytes, err := os.ReadFile(keyPath)
 	if err != nil {
 		return nil, err
 	}
 
-	return publicKey, nil
+	privateKey, err := ParsePrivateKey(privateKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", keyPath, err)
+	}
+
+	return privateKey, nil
 }
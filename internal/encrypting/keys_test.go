@@ -0,0 +1,99 @@
+package encrypting
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	for _, size := range []int{0, 1, key.Size() - 11, key.Size() - 10, 3 * key.Size()} {
+		data := make([]byte, size)
+		_, err = rand.Read(data)
+		require.NoError(t, err)
+
+		encrypted, err := Encrypt(&key.PublicKey, data)
+		require.NoError(t, err)
+
+		decrypted, err := Decrypt(key, encrypted)
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(data, decrypted))
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	pkcs8, err := EncodePrivateKey(key)
+	require.NoError(t, err)
+
+	for _, raw := range [][]byte{pkcs1, pkcs8} {
+		parsed, err := ParsePrivateKey(raw)
+		require.NoError(t, err)
+		require.True(t, parsed.Equal(key))
+	}
+
+	pkcs1Public := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey)})
+	pkix, err := EncodePublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	for _, raw := range [][]byte{pkcs1Public, pkix} {
+		parsed, err := ParsePublicKey(raw)
+		require.NoError(t, err)
+		require.True(t, parsed.Equal(&key.PublicKey))
+	}
+
+	_, err = ParsePrivateKey([]byte("garbage"))
+	require.ErrorIs(t, err, ErrNoPEM)
+}
+
+func TestKeypair(t *testing.T) {
+	dir := t.TempDir()
+	keys := NewService(dir)
+
+	require.NoError(t, keys.GenerateIfNotExist())
+	first, err := keys.GetPrivateKey()
+	require.NoError(t, err)
+
+	require.NoError(t, keys.GenerateIfNotExist())
+	again, err := keys.GetPrivateKey()
+	require.NoError(t, err)
+	require.True(t, first.Equal(again), "existing key is kept")
+
+	id, err := keys.Rotate()
+	require.NoError(t, err)
+	require.NotEqual(t, KeyID(&first.PublicKey), id)
+
+	keyring, err := keys.LoadKeyring()
+	require.NoError(t, err)
+	require.Equal(t, id, keyring.PublicKey().ID)
+
+	retired, ok := keyring.Key(KeyID(&first.PublicKey))
+	require.True(t, ok, "retired key decrypts payloads encrypted before rotation")
+	require.True(t, first.Equal(retired))
+
+	t.Run("corrupt_key_is_not_regenerated", func(t *testing.T) {
+		privatePath := path.Join(dir, privateKeyName)
+		require.NoError(t, os.WriteFile(privatePath, []byte("corrupt"), 0600))
+
+		require.NoError(t, keys.GenerateIfNotExist())
+		_, err := keys.LoadKeyring()
+		require.ErrorIs(t, err, ErrNoPEM)
+
+		raw, err := os.ReadFile(privatePath)
+		require.NoError(t, err)
+		require.Equal(t, "corrupt", string(raw))
+	})
+}
@@ -0,0 +1,91 @@
+package encrypting
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+)
+
+const keyIDBytes = 8
+
+var (
+	ErrNoPEM   = errors.New("no PEM block found")
+	ErrKeyType = errors.New("key is not RSA")
+)
+
+// KeyID returns fingerprint of public key, the first bytes of SHA-256 of its PKIX encoding
+func KeyID(publicKey *rsa.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:keyIDBytes])
+}
+
+// ParsePrivateKey parses PEM encoded PKCS1 "RSA PRIVATE KEY" or PKCS8 "PRIVATE KEY"
+func ParsePrivateKey(raw []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, ErrNoPEM
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrKeyType
+	}
+
+	return privateKey, nil
+}
+
+// ParsePublicKey parses PEM encoded PKCS1 "RSA PUBLIC KEY" or PKIX "PUBLIC KEY"
+func ParsePublicKey(raw []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, ErrNoPEM
+	}
+
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrKeyType
+	}
+
+	return publicKey, nil
+}
+
+// EncodePrivateKey returns PKCS8 "PRIVATE KEY" PEM
+func EncodePrivateKey(privateKey *rsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// EncodePublicKey returns PKIX "PUBLIC KEY" PEM
+func EncodePublicKey(publicKey *rsa.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+const (
+	AuditDeleteMetric  = "delete_metric"
+	AuditDeleteMetrics = "delete_metrics"
+	AuditResetCounter  = "reset_counter"
+	AuditCreateToken   = "create_token"
+	AuditRevokeToken   = "revoke_token"
+	AuditDumpSnapshot  = "dump_snapshot"
+	AuditRestore       = "restore_snapshot"
+)
+
+// AuditRecord describes a single administrative action
+type AuditRecord struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	Action string    `json:"action"`
+	Target string    `json:"target"`
+	Tenant string    `json:"tenant,omitempty"`
+}
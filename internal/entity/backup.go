@@ -0,0 +1,23 @@
+package entity
+
+import "errors"
+
+// Restore modes of uploaded snapshot
+const (
+	// RestoreMerge overwrites metrics present in snapshot and keeps the others
+	RestoreMerge = "merge"
+	// RestoreReplace removes all metrics before loading snapshot
+	RestoreReplace = "replace"
+)
+
+var ErrRestoreMode = errors.New("restore mode must be merge or replace")
+
+// RestoreSummary describes loaded snapshot
+type RestoreSummary struct {
+	Mode     string `json:"mode"`
+	Tenants  int    `json:"tenants"`
+	Metrics  int    `json:"metrics"`
+	Metadata int    `json:"metadata"`
+	Tokens   int    `json:"tokens"`
+	Removed  int    `json:"removed"`
+}
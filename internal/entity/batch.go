@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Statuses of batch items
+const (
+	// BatchAccepted item is stored
+	BatchAccepted = "accepted"
+	// BatchRejected item is invalid or conflicts with stored metrics
+	BatchRejected = "rejected"
+	// BatchSkipped item is valid but not stored because another item rejected all-or-nothing batch
+	BatchSkipped = "skipped"
+)
+
+// ErrBatchRejected is returned when all-or-nothing batch has rejected items
+var ErrBatchRejected = errors.New("batch rejected")
+
+// BatchResult is outcome of a single batch item, Err is set for rejected items
+type BatchResult struct {
+	Name       string
+	MetricType string
+	Status     string
+	Err        error
+}
+
+// BatchError reports all-or-nothing batch where nothing was stored because of rejected items
+type BatchError struct {
+	Results []BatchResult
+}
+
+func (e *BatchError) Error() string {
+	rejected := 0
+	for _, r := range e.Results {
+		if r.Status == BatchRejected {
+			rejected++
+		}
+	}
+	return fmt.Sprintf("%s: %d of %d items rejected: %v", ErrBatchRejected, rejected, len(e.Results), e.Unwrap())
+}
+
+// Unwrap returns reason of the first rejected item so callers can classify the failure
+func (e *BatchError) Unwrap() error {
+	for _, r := range e.Results {
+		if r.Err != nil {
+			return r.Err
+		}
+	}
+	return ErrBatchRejected
+}
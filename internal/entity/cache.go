@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// CacheStats describes state of write-back cache in front of storage
+type CacheStats struct {
+	// Pending is the number of metrics written to cache and not flushed yet
+	Pending int
+	// Lag is the age of the oldest write not flushed yet
+	Lag time.Duration
+	// LastFlush is the time of the last successful flush
+	LastFlush time.Time
+	// LastFlushLag is the age of the oldest write of the last successful flush when it was stored
+	LastFlushLag time.Duration
+	Flushes      int64
+	FlushErrors  int64
+	LastError    string
+}
@@ -1,6 +1,10 @@
 package entity
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 //easyjson:json
 type MetricDTOs []MetricDTO
@@ -10,6 +14,70 @@ type MetricDTO struct {
 	MetricType string   `json:"type"`
 	Counter    *int64   `json:"delta,omitempty"`
 	Gauge      *float64 `json:"value,omitempty"`
+	// UpdatedAt is the time of the last metric update, filled by repository.
+	// It is kept by StoreBatch when set, so restored metrics are not considered just updated.
+	UpdatedAt time.Time `json:"-"`
+}
+
+// UpdateTime returns UpdatedAt if it is set, otherwise now
+func (m *MetricDTO) UpdateTime(now time.Time) time.Time {
+	if m.UpdatedAt.IsZero() {
+		return now
+	}
+	return m.UpdatedAt
+}
+
+// StoredMetrics are metrics persisted by snapshots and write-ahead log,
+// unlike API representation they keep update time
+type StoredMetrics []MetricDTO
+
+// storedMetric lists fields of MetricDTO as its generated marshaller would be promoted if it was embedded
+type storedMetric struct {
+	Name       string     `json:"id"`
+	MetricType string     `json:"type"`
+	Counter    *int64     `json:"delta,omitempty"`
+	Gauge      *float64   `json:"value,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+func (s StoredMetrics) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return []byte("null"), nil
+	}
+
+	stored := make([]storedMetric, 0, len(s))
+	for _, m := range s {
+		sm := storedMetric{Name: m.Name, MetricType: m.MetricType, Counter: m.Counter, Gauge: m.Gauge}
+		if !m.UpdatedAt.IsZero() {
+			updatedAt := m.UpdatedAt
+			sm.UpdatedAt = &updatedAt
+		}
+		stored = append(stored, sm)
+	}
+	return json.Marshal(stored)
+}
+
+func (s *StoredMetrics) UnmarshalJSON(data []byte) error {
+	var stored []storedMetric
+	err := json.Unmarshal(data, &stored)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		*s = nil
+		return nil
+	}
+
+	metrics := make(StoredMetrics, 0, len(stored))
+	for _, sm := range stored {
+		m := MetricDTO{Name: sm.Name, MetricType: sm.MetricType, Counter: sm.Counter, Gauge: sm.Gauge}
+		if sm.UpdatedAt != nil {
+			m.UpdatedAt = *sm.UpdatedAt
+		}
+		metrics = append(metrics, m)
+	}
+	*s = metrics
+	return nil
 }
 
 func (m *MetricDTO) Validate() error {
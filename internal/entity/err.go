@@ -1,6 +1,10 @@
 package entity
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
 
 var (
 	ErrMetricName     = errors.New("metric name is empty")
@@ -10,3 +14,42 @@ var (
 	ErrNoValue        = errors.New("no value provided")
 	ErrMultipleValues = errors.New("multiple values provided")
 )
+
+var ErrTypeConflict = errors.New("metric type conflict")
+
+// TypeConflictError reports that metric name is already registered with another type
+type TypeConflictError struct {
+	Name      string `json:"id"`
+	Existing  string `json:"existing_type"`
+	Requested string `json:"requested_type"`
+}
+
+func (e *TypeConflictError) Error() string {
+	return fmt.Sprintf("%s:%s: exists as %s, requested %s", e.Name, ErrTypeConflict, e.Existing, e.Requested)
+}
+
+func (e *TypeConflictError) Unwrap() error {
+	return ErrTypeConflict
+}
+
+// FindTypeConflicts returns names which exist both as gauge and counter
+func FindTypeConflicts(metrics []MetricDTO) []string {
+	types := make(map[string]string, len(metrics))
+	conflicts := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, m := range metrics {
+		existing, ok := types[m.Name]
+		if !ok {
+			types[m.Name] = m.MetricType
+			continue
+		}
+		if existing != m.MetricType && !seen[m.Name] {
+			seen[m.Name] = true
+			conflicts = append(conflicts, m.Name)
+		}
+	}
+
+	sort.Strings(conflicts)
+	return conflicts
+}
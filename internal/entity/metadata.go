@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	UnitBytes       = "bytes"
+	UnitCount       = "count"
+	UnitRatio       = "ratio"
+	UnitPercent     = "percent"
+	UnitNanoseconds = "nanoseconds"
+
+	OwnerAgent = "agent"
+)
+
+var ErrMetadataType = errors.New("metadata type must be gauge or counter")
+
+// Metadata describes metric name
+type Metadata struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Unit        string `json:"unit,omitempty"`
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+}
+
+func (m *Metadata) Validate() error {
+	if m.Name == "" {
+		return ErrMetricName
+	}
+	if m.Type != "" && m.Type != GaugeType && m.Type != CounterType {
+		return fmt.Errorf("%s:%w", m.Name, ErrMetadataType)
+	}
+	return nil
+}
+
+// RuntimeMetadata describes metrics collected by agent
+var RuntimeMetadata = []Metadata{
+	{Name: Alloc, Type: GaugeType, Unit: UnitBytes, Description: "Bytes of allocated heap objects", Owner: OwnerAgent},
+	{Name: BuckHashSys, Type: GaugeType, Unit: UnitBytes, Description: "Bytes of memory in profiling bucket hash tables", Owner: OwnerAgent},
+	{Name: Frees, Type: GaugeType, Unit: UnitCount, Description: "Cumulative count of heap objects freed", Owner: OwnerAgent},
+	{Name: GCCPUFraction, Type: GaugeType, Unit: UnitRatio, Description: "Fraction of available CPU time used by the GC", Owner: OwnerAgent},
+	{Name: GCSys, Type: GaugeType, Unit: UnitBytes, Description: "Bytes of memory in garbage collection metadata", Owner: OwnerAgent},
+	{Name: HeapAlloc, Type: GaugeType, Unit: UnitBytes, Description: "Bytes of allocated heap objects", Owner: OwnerAgent},
+	{Name: HeapIdle, Type: GaugeType, Unit: UnitBytes, Description: "Bytes in idle heap spans", Owner: OwnerAgent},
+	{Name: HeapInuse, Type: GaugeType, Unit: UnitBytes, Description: "Bytes in in-use heap spans", Owner: OwnerAgent},
+	{Name: HeapObjects, Type: GaugeType, Unit: UnitCount, Description: "Number of allocated heap objects", Owner: OwnerAgent},
+	{Name: HeapReleased, Type: GaugeType, Unit: UnitBytes, Description: "Bytes of physical memory returned to the OS", Owner: OwnerAgent},
+	{Name: HeapSys, Type: GaugeType, Unit: UnitBytes, Description: "Bytes of heap memory obtained from the OS", Owner: OwnerAgent},
+	{Name: LastGC, Type: GaugeType, Unit: UnitNanoseconds, Description: "Time the last garbage collection finished since the Unix epoch", Owner: OwnerAgent},
+	{Name: Lookups, Type: GaugeType, Unit: UnitCount, Description: "Number of pointer lookups performed by the runtime", Owner: OwnerAgent},
+	{Name: MCacheInuse, Type: GaugeType, Unit: UnitBytes, Description: "Bytes of allocated mcache structures", Owner: OwnerAgent},
+	{Name: MCacheSys, Type: GaugeType, Unit: UnitBytes, Description: "Bytes of memory obtained from the OS for mcache structures", Owner: OwnerAgent},
+	{Name: MSpanInuse, Type: GaugeType, Unit: UnitBytes, Description: "Bytes of allocated mspan structures", Owner: OwnerAgent},
+	{Name: MSpanSys, Type: GaugeType, Unit: UnitBytes, Description: "Bytes of memory obtained from the OS for mspan structures", Owner: OwnerAgent},
+	{Name: Mallocs, Type: GaugeType, Unit: UnitCount, Description: "Cumulative count of heap objects allocated", Owner: OwnerAgent},
+	{Name: NextGC, Type: GaugeType, Unit: UnitBytes, Description: "Target heap size of the next GC cycle", Owner: OwnerAgent},
+	{Name: NumForcedGC, Type: GaugeType, Unit: UnitCount, Description: "Number of GC cycles forced by the application", Owner: OwnerAgent},
+	{Name: NumGC, Type: GaugeType, Unit: UnitCount, Description: "Number of completed GC cycles", Owner: OwnerAgent},
+	{Name: OtherSys, Type: GaugeType, Unit: UnitBytes, Description: "Bytes of memory in miscellaneous off-heap runtime allocations", Owner: OwnerAgent},
+	{Name: PauseTotalNs, Type: GaugeType, Unit: UnitNanoseconds, Description: "Cumulative time spent in GC stop-the-world pauses", Owner: OwnerAgent},
+	{Name: StackInuse, Type: GaugeType, Unit: UnitBytes, Description: "Bytes in stack spans", Owner: OwnerAgent},
+	{Name: StackSys, Type: GaugeType, Unit: UnitBytes, Description: "Bytes of stack memory obtained from the OS", Owner: OwnerAgent},
+	{Name: Sys, Type: GaugeType, Unit: UnitBytes, Description: "Total bytes of memory obtained from the OS", Owner: OwnerAgent},
+	{Name: TotalAlloc, Type: GaugeType, Unit: UnitBytes, Description: "Cumulative bytes allocated for heap objects", Owner: OwnerAgent},
+	{Name: RandomValue, Type: GaugeType, Description: "Random value updated on every poll", Owner: OwnerAgent},
+	{Name: PollCount, Type: CounterType, Unit: UnitCount, Description: "Number of metric polls made by agent", Owner: OwnerAgent},
+	{Name: TotalMemory, Type: GaugeType, Unit: UnitBytes, Description: "Total amount of RAM on the host", Owner: OwnerAgent},
+	{Name: FreeMemory, Type: GaugeType, Unit: UnitBytes, Description: "Amount of free RAM on the host", Owner: OwnerAgent},
+	{Name: CPUUtilization1, Type: GaugeType, Unit: UnitPercent, Description: "CPU utilization of the host", Owner: OwnerAgent},
+}
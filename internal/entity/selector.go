@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+)
+
+var (
+	ErrSelectorEmpty     = errors.New("glob or regex must be provided")
+	ErrSelectorAmbiguous = errors.New("only one of glob or regex must be provided")
+	ErrSelectorPattern   = errors.New("bad pattern")
+)
+
+// MetricSelector selects metrics by optional type and name glob or regular expression
+type MetricSelector struct {
+	Type  string
+	Glob  string
+	Regex string
+}
+
+// Matcher validates selector and returns function matching metrics
+func (s MetricSelector) Matcher() (func(MetricDTO) bool, error) {
+	if s.Type != "" && s.Type != GaugeType && s.Type != CounterType {
+		return nil, fmt.Errorf("%s:%w", s.Type, ErrMetricType)
+	}
+
+	var matchName func(string) bool
+
+	switch {
+	case s.Glob == "" && s.Regex == "":
+		return nil, ErrSelectorEmpty
+	case s.Glob != "" && s.Regex != "":
+		return nil, ErrSelectorAmbiguous
+	case s.Glob != "":
+		if _, err := path.Match(s.Glob, ""); err != nil {
+			return nil, fmt.Errorf("%s:%w: %v", s.Glob, ErrSelectorPattern, err)
+		}
+		matchName = func(name string) bool {
+			ok, _ := path.Match(s.Glob, name)
+			return ok
+		}
+	default:
+		re, err := regexp.Compile(s.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w: %v", s.Regex, ErrSelectorPattern, err)
+		}
+		matchName = re.MatchString
+	}
+
+	return func(m MetricDTO) bool {
+		if s.Type != "" && m.MetricType != s.Type {
+			return false
+		}
+		return matchName(m.Name)
+	}, nil
+}
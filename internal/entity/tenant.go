@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var (
+	ErrTenantName    = errors.New("tenant name must be 1-64 letters, digits, '-' or '_'")
+	ErrUnknownToken  = errors.New("unknown or revoked api token")
+	ErrTokenNotFound = errors.New("api token not found")
+	ErrQuotaExceeded = errors.New("tenant metric quota exceeded")
+)
+
+var tenantNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// APIToken identifies tenant, only hash of the secret is stored
+type APIToken struct {
+	ID        string     `json:"id"`
+	Tenant    string     `json:"tenant"`
+	Hash      string     `json:"hash,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active reports whether token was not revoked
+func (t *APIToken) Active() bool {
+	return t.RevokedAt == nil
+}
+
+func ValidateTenant(tenant string) error {
+	if !tenantNameRe.MatchString(tenant) {
+		return fmt.Errorf("%q:%w", tenant, ErrTenantName)
+	}
+	return nil
+}
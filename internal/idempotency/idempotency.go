@@ -0,0 +1,32 @@
+// Package idempotency remembers responses of processed requests by client provided key,
+// so retried requests are answered with the original response instead of being applied twice.
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// Lease is how long a key stays reserved by a request being processed,
+// a reservation of crashed process is taken over after it
+const Lease = time.Minute
+
+var (
+	ErrInProgress = errors.New("request with the same idempotency key is in progress")
+	ErrMismatch   = errors.New("idempotency key was already used with another payload")
+)
+
+// Response is remembered response of a completed request
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Fingerprint returns digest of request payload, a key can only be reused with the same payload
+func Fingerprint(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,129 @@
+package idempotency
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	id          string
+	fingerprint string
+	response    *Response
+	expiresAt   time.Time
+}
+
+// MemoryStore keeps keys in memory, when maxKeys is reached the oldest keys are dropped first
+type MemoryStore struct {
+	ttl     time.Duration
+	maxKeys int
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// NewMemoryStore creates store remembering responses for ttl, zero maxKeys means unlimited
+func NewMemoryStore(ttl time.Duration, maxKeys int) *MemoryStore {
+	return &MemoryStore{
+		ttl:     ttl,
+		maxKeys: maxKeys,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Begin reserves key for request with fingerprint. It returns response of completed request,
+// ErrInProgress if key is reserved by another request and ErrMismatch if key was used with another payload.
+func (s *MemoryStore) Begin(_ context.Context, tenant, key, fingerprint string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	id := entryID(tenant, key)
+
+	if elem, ok := s.entries[id]; ok {
+		entry := elem.Value.(*memoryEntry)
+		if now.Before(entry.expiresAt) {
+			if entry.fingerprint != fingerprint {
+				return nil, ErrMismatch
+			}
+			if entry.response == nil {
+				return nil, ErrInProgress
+			}
+			return entry.response, nil
+		}
+		s.remove(elem)
+	}
+
+	s.evict(now)
+
+	entry := &memoryEntry{id: id, fingerprint: fingerprint, expiresAt: now.Add(Lease)}
+	s.entries[id] = s.order.PushBack(entry)
+
+	return nil, nil
+}
+
+// Complete remembers response of reserved key
+func (s *MemoryStore) Complete(_ context.Context, tenant, key string, resp Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[entryID(tenant, key)]
+	if !ok {
+		return nil
+	}
+
+	entry := elem.Value.(*memoryEntry)
+	entry.response = &resp
+	entry.expiresAt = s.now().Add(s.ttl)
+	// completed keys live longer than reservations, keep order by expiration
+	s.order.MoveToBack(elem)
+
+	return nil
+}
+
+// Release drops reservation of key so the request can be retried
+func (s *MemoryStore) Release(_ context.Context, tenant, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[entryID(tenant, key)]
+	if ok && elem.Value.(*memoryEntry).response == nil {
+		s.remove(elem)
+	}
+
+	return nil
+}
+
+// evict drops expired keys and the oldest completed ones over maxKeys to make room for a new key.
+// Reservations in progress are never dropped before their lease expires, so maxKeys may be exceeded by them.
+func (s *MemoryStore) evict(now time.Time) {
+	for elem := s.order.Front(); elem != nil; {
+		entry := elem.Value.(*memoryEntry)
+		next := elem.Next()
+
+		expired := !now.Before(entry.expiresAt)
+		full := s.maxKeys > 0 && s.order.Len() >= s.maxKeys
+		switch {
+		case expired, full && entry.response != nil:
+			s.remove(elem)
+		case !full:
+			return
+		}
+
+		elem = next
+	}
+}
+
+func (s *MemoryStore) remove(elem *list.Element) {
+	delete(s.entries, elem.Value.(*memoryEntry).id)
+	s.order.Remove(elem)
+}
+
+func entryID(tenant, key string) string {
+	return tenant + "\x00" + key
+}
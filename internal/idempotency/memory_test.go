@@ -0,0 +1,105 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	resp := Response{Status: 200, ContentType: "application/json", Body: []byte(`{}`)}
+
+	t.Run("replay_completed", func(t *testing.T) {
+		s := NewMemoryStore(time.Hour, 0)
+
+		stored, err := s.Begin(ctx, "acme", "k1", "f1")
+		require.NoError(t, err)
+		require.Nil(t, stored)
+
+		_, err = s.Begin(ctx, "acme", "k1", "f1")
+		require.ErrorIs(t, err, ErrInProgress)
+
+		require.NoError(t, s.Complete(ctx, "acme", "k1", resp))
+
+		stored, err = s.Begin(ctx, "acme", "k1", "f1")
+		require.NoError(t, err)
+		require.Equal(t, &resp, stored)
+
+		_, err = s.Begin(ctx, "acme", "k1", "f2")
+		require.ErrorIs(t, err, ErrMismatch)
+
+		stored, err = s.Begin(ctx, "beta", "k1", "f2")
+		require.NoError(t, err, "keys are scoped by tenant")
+		require.Nil(t, stored)
+	})
+
+	t.Run("release_allows_retry", func(t *testing.T) {
+		s := NewMemoryStore(time.Hour, 0)
+
+		_, err := s.Begin(ctx, "", "k1", "f1")
+		require.NoError(t, err)
+		require.NoError(t, s.Release(ctx, "", "k1"))
+
+		stored, err := s.Begin(ctx, "", "k1", "f1")
+		require.NoError(t, err)
+		require.Nil(t, stored)
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		now := time.Now()
+		s := NewMemoryStore(time.Hour, 0)
+		s.now = func() time.Time { return now }
+
+		_, err := s.Begin(ctx, "", "k1", "f1")
+		require.NoError(t, err)
+
+		// reservation of crashed request is taken over after lease
+		now = now.Add(Lease)
+		_, err = s.Begin(ctx, "", "k1", "f1")
+		require.NoError(t, err)
+		require.NoError(t, s.Complete(ctx, "", "k1", resp))
+
+		now = now.Add(time.Hour)
+		stored, err := s.Begin(ctx, "", "k1", "f2")
+		require.NoError(t, err)
+		require.Nil(t, stored)
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		s := NewMemoryStore(time.Hour, 2)
+
+		for _, key := range []string{"k1", "k2", "k3"} {
+			_, err := s.Begin(ctx, "", key, "f")
+			require.NoError(t, err)
+			require.NoError(t, s.Complete(ctx, "", key, resp))
+		}
+
+		require.Equal(t, 2, s.order.Len())
+		stored, err := s.Begin(ctx, "", "k1", "f")
+		require.NoError(t, err)
+		require.Nil(t, stored, "the oldest key is dropped")
+	})
+
+	t.Run("in_progress_is_not_evicted", func(t *testing.T) {
+		s := NewMemoryStore(time.Hour, 2)
+
+		_, err := s.Begin(ctx, "", "k1", "f")
+		require.NoError(t, err)
+
+		for _, key := range []string{"k2", "k3"} {
+			_, err = s.Begin(ctx, "", key, "f")
+			require.NoError(t, err)
+			require.NoError(t, s.Complete(ctx, "", key, resp))
+		}
+
+		_, err = s.Begin(ctx, "", "k1", "f")
+		require.ErrorIs(t, err, ErrInProgress, "reservation is kept over maxKeys")
+
+		stored, err := s.Begin(ctx, "", "k2", "f")
+		require.NoError(t, err)
+		require.Nil(t, stored, "the oldest completed key is dropped instead")
+	})
+}
@@ -0,0 +1,138 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	_ "github.com/jackc/pgx/stdlib"
+)
+
+const (
+	// reservation takes over a key only if it is new or expired, so concurrent requests get exactly one winner
+	reserveQuery = `INSERT INTO idempotency_keys (tenant, key, fingerprint, expires_at) VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+ON CONFLICT (tenant, key) DO UPDATE SET fingerprint=EXCLUDED.fingerprint, status=NULL, content_type='', body=NULL, expires_at=EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at < now()`
+	selectQuery   = `SELECT fingerprint, status, content_type, body FROM idempotency_keys WHERE tenant=$1 AND key=$2`
+	completeQuery = `UPDATE idempotency_keys SET status=$3, content_type=$4, body=$5, expires_at=now() + make_interval(secs => $6) WHERE tenant=$1 AND key=$2`
+	releaseQuery  = `DELETE FROM idempotency_keys WHERE tenant=$1 AND key=$2 AND status IS NULL`
+	sweepQuery    = `DELETE FROM idempotency_keys WHERE expires_at < now()`
+
+	sweepEvery = time.Minute
+	// reserveAttempts bounds retries of reservation when the key is released between insert and select
+	reserveAttempts = 3
+)
+
+// PostgresStore keeps keys in idempotency_keys table so they are shared by server replicas
+type PostgresStore struct {
+	db  *sql.DB
+	ttl time.Duration
+
+	mu        sync.Mutex
+	nextSweep time.Time
+}
+
+// NewPostgresStore connects to database, the table is created by repository migrations
+func NewPostgresStore(url string, ttl time.Duration) (*PostgresStore, error) {
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &PostgresStore{
+		db:  db,
+		ttl: ttl,
+	}, nil
+}
+
+// Begin reserves key for request with fingerprint. It returns response of completed request,
+// ErrInProgress if key is reserved by another request and ErrMismatch if key was used with another payload.
+func (s *PostgresStore) Begin(ctx context.Context, tenant, key, fingerprint string) (*Response, error) {
+	s.sweep(ctx)
+
+	for attempt := 1; ; attempt++ {
+		resp, err := s.begin(ctx, tenant, key, fingerprint)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return resp, err
+		}
+		// key was released or swept after it failed to be reserved, it can be taken now
+		if attempt == reserveAttempts {
+			return nil, ErrInProgress
+		}
+	}
+}
+
+// begin makes a single reservation attempt, sql.ErrNoRows means the key disappeared after the attempt
+func (s *PostgresStore) begin(ctx context.Context, tenant, key, fingerprint string) (*Response, error) {
+	res, err := s.db.ExecContext(ctx, reserveQuery, tenant, key, fingerprint, Lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	reserved, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if reserved == 1 {
+		return nil, nil
+	}
+
+	var (
+		stored      string
+		status      sql.NullInt64
+		contentType string
+		body        []byte
+	)
+	err = s.db.QueryRowContext(ctx, selectQuery, tenant, key).Scan(&stored, &status, &contentType, &body)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored != fingerprint {
+		return nil, ErrMismatch
+	}
+	if !status.Valid {
+		return nil, ErrInProgress
+	}
+
+	return &Response{Status: int(status.Int64), ContentType: contentType, Body: body}, nil
+}
+
+// Complete remembers response of reserved key
+func (s *PostgresStore) Complete(ctx context.Context, tenant, key string, resp Response) error {
+	_, err := s.db.ExecContext(ctx, completeQuery, tenant, key, resp.Status, resp.ContentType, resp.Body, s.ttl.Seconds())
+	return err
+}
+
+// Release drops reservation of key so the request can be retried
+func (s *PostgresStore) Release(ctx context.Context, tenant, key string) error {
+	_, err := s.db.ExecContext(ctx, releaseQuery, tenant, key)
+	return err
+}
+
+// Close closes database connection
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
+
+// sweep deletes expired keys at most once per sweepEvery, expired keys are reclaimed by Begin anyway
+func (s *PostgresStore) sweep(ctx context.Context) {
+	s.mu.Lock()
+	now := time.Now()
+	if now.Before(s.nextSweep) {
+		s.mu.Unlock()
+		return
+	}
+	s.nextSweep = now.Add(sweepEvery)
+	s.mu.Unlock()
+
+	s.db.ExecContext(ctx, sweepQuery)
+}
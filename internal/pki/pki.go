@@ -0,0 +1,236 @@
+// Package pki issues a local certificate authority, server and agent certificates for TLS and mutual TLS
+package pki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+// Key types supported for certificates
+const (
+	KeyRSA     = "rsa"
+	KeyECDSA   = "ecdsa"
+	KeyEd25519 = "ed25519"
+)
+
+const (
+	rsaBits    = 2048
+	serialBits = 128
+
+	certPerm = 0o644
+	keyPerm  = 0o600
+)
+
+var (
+	ErrKeyType    = errors.New("unsupported key type, use rsa, ecdsa or ed25519")
+	ErrFileExists = errors.New("file already exists")
+	errPEM        = errors.New("no PEM block found")
+)
+
+// Certificate is a parsed certificate with its private key
+type Certificate struct {
+	Cert *x509.Certificate
+	Key  crypto.Signer
+}
+
+// GenerateKey generates private key of keyType
+func GenerateKey(keyType string) (crypto.Signer, error) {
+	switch keyType {
+	case KeyRSA:
+		return rsa.GenerateKey(rand.Reader, rsaBits)
+	case KeyECDSA:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("%q: %w", keyType, ErrKeyType)
+	}
+}
+
+// NewCA creates self-signed certificate authority
+func NewCA(commonName, keyType string, validity time.Duration) (*Certificate, error) {
+	key, err := GenerateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := newTemplate(commonName, validity)
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.MaxPathLenZero = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+
+	return create(template, template, key.Public(), key, key)
+}
+
+// IssueServer issues server certificate for hosts, IP addresses are put to IP SANs and names to DNS SANs
+func (ca *Certificate) IssueServer(commonName string, hosts []string, keyType string, validity time.Duration) (*Certificate, error) {
+	return ca.issue(commonName, hosts, keyType, validity, x509.ExtKeyUsageServerAuth)
+}
+
+// IssueClient issues agent certificate, server maps its common name to agent identity
+func (ca *Certificate) IssueClient(commonName, keyType string, validity time.Duration) (*Certificate, error) {
+	return ca.issue(commonName, []string{commonName}, keyType, validity, x509.ExtKeyUsageClientAuth)
+}
+
+func (ca *Certificate) issue(commonName string, hosts []string, keyType string, validity time.Duration, usage x509.ExtKeyUsage) (*Certificate, error) {
+	key, err := GenerateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := newTemplate(commonName, validity)
+	if err != nil {
+		return nil, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	return create(template, ca.Cert, key.Public(), key, ca.Key)
+}
+
+func newTemplate(commonName string, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), serialBits))
+	if err != nil {
+		return nil, fmt.Errorf("can not generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		// backdated to tolerate clock difference between hosts
+		NotBefore: now.Add(-time.Hour),
+		NotAfter:  now.Add(validity),
+	}, nil
+}
+
+func create(template, parent *x509.Certificate, pub crypto.PublicKey, key, signer crypto.Signer) (*Certificate, error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		return nil, fmt.Errorf("can not create certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse certificate: %w", err)
+	}
+
+	return &Certificate{Cert: cert, Key: key}, nil
+}
+
+// Write writes PEM certificate readable by everyone and PKCS8 private key readable by owner only.
+// Existing files are not overwritten unless force is set.
+func (c *Certificate) Write(certPath, keyPath string, force bool) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(c.Key)
+	if err != nil {
+		return fmt.Errorf("can not marshal private key: %w", err)
+	}
+
+	// both files are checked first so certificate and key are never left from different pairs
+	if !force {
+		for _, path := range []string{certPath, keyPath} {
+			if _, err = os.Stat(path); err == nil {
+				return fmt.Errorf("%s: %w", path, ErrFileExists)
+			}
+		}
+	}
+
+	err = writePEM(keyPath, "PRIVATE KEY", keyDER, keyPerm, force)
+	if err != nil {
+		return err
+	}
+
+	return writePEM(certPath, "CERTIFICATE", c.Cert.Raw, certPerm, force)
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, perm)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s: %w", path, ErrFileExists)
+		}
+		return err
+	}
+	defer file.Close()
+
+	// permissions of a truncated file are not changed by OpenFile
+	err = file.Chmod(perm)
+	if err != nil {
+		return err
+	}
+
+	return pem.Encode(file, &pem.Block{Type: blockType, Bytes: der})
+}
+
+// Load reads certificate and PKCS8 private key written by Write
+func Load(certPath, keyPath string) (*Certificate, error) {
+	certDER, err := readPEM(certPath)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse certificate: %w", err)
+	}
+
+	keyDER, err := readPEM(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyDER)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", keyPath, ErrKeyType)
+	}
+
+	return &Certificate{Cert: cert, Key: signer}, nil
+}
+
+func readPEM(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("%s: %w", path, errPEM)
+	}
+	return block.Bytes, nil
+}
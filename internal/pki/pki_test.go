@@ -0,0 +1,81 @@
+package pki
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssue(t *testing.T) {
+	for _, keyType := range []string{KeyRSA, KeyECDSA, KeyEd25519} {
+		t.Run(keyType, func(t *testing.T) {
+			ca, err := NewCA("test CA", keyType, time.Hour)
+			require.NoError(t, err)
+			require.True(t, ca.Cert.IsCA)
+
+			roots := x509.NewCertPool()
+			roots.AddCert(ca.Cert)
+
+			server, err := ca.IssueServer("localhost", []string{"localhost", "127.0.0.1"}, keyType, time.Hour)
+			require.NoError(t, err)
+			_, err = server.Cert.Verify(x509.VerifyOptions{
+				Roots:     roots,
+				DNSName:   "127.0.0.1",
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			})
+			require.NoError(t, err)
+
+			agent, err := ca.IssueClient("agent-1", keyType, time.Hour)
+			require.NoError(t, err)
+			require.Equal(t, "agent-1", agent.Cert.Subject.CommonName)
+			_, err = agent.Cert.Verify(x509.VerifyOptions{
+				Roots:     roots,
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			})
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("unknown_key_type", func(t *testing.T) {
+		_, err := NewCA("test CA", "dsa", time.Hour)
+		require.ErrorIs(t, err, ErrKeyType)
+	})
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca.key")
+
+	ca, err := NewCA("test CA", KeyECDSA, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, ca.Write(certPath, keyPath, false))
+
+	info, err := os.Stat(keyPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(keyPerm), info.Mode().Perm())
+
+	info, err = os.Stat(certPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(certPerm), info.Mode().Perm())
+
+	_, err = tls.LoadX509KeyPair(certPath, keyPath)
+	require.NoError(t, err, "files are usable by crypto/tls")
+
+	loaded, err := Load(certPath, keyPath)
+	require.NoError(t, err)
+	require.Equal(t, ca.Cert.Raw, loaded.Cert.Raw)
+
+	other, err := NewCA("other CA", KeyECDSA, time.Hour)
+	require.NoError(t, err)
+	require.ErrorIs(t, other.Write(certPath, keyPath, false), ErrFileExists)
+	require.NoError(t, other.Write(certPath, keyPath, true))
+
+	loaded, err = Load(certPath, keyPath)
+	require.NoError(t, err)
+	require.Equal(t, other.Cert.Raw, loaded.Cert.Raw)
+}
@@ -0,0 +1,438 @@
+// Package bolt provides repository stored in an embedded bbolt database file,
+// it gives small deployments durable storage without running a database server.
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+)
+
+// openTimeout limits waiting for file lock held by another process
+const openTimeout = time.Second
+
+// Metrics of a tenant are kept in tenants/<tenantPrefix><tenant>/{gauges,counters,metadata} buckets,
+// tokens are shared by all tenants
+var (
+	tenantsBucket  = []byte("tenants")
+	gaugesBucket   = []byte("gauges")
+	countersBucket = []byte("counters")
+	metadataBucket = []byte("metadata")
+	tokensBucket   = []byte("tokens")
+)
+
+// tenantPrefix makes bucket name of default tenant not empty
+const tenantPrefix = "/"
+
+var errInvalidValue = errors.New("invalid stored value")
+
+type Bolt struct {
+	db *bbolt.DB
+}
+
+// NewBolt opens database file creating it if needed, the file is locked until Close
+func NewBolt(path string) (*Bolt, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0o750)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("can not open %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{tenantsBucket, tokensBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+func (s *Bolt) StoreGauge(ctx context.Context, name string, value float64) error {
+	return s.update(ctx, func(ns *namespace) error {
+		return ns.putGauge(name, value, time.Now())
+	})
+}
+
+func (s *Bolt) StoreCounter(ctx context.Context, name string, value int64) error {
+	return s.update(ctx, func(ns *namespace) error {
+		return ns.addCounter(name, value, time.Now())
+	})
+}
+
+func (s *Bolt) Gauge(ctx context.Context, name string) (float64, error) {
+	var value float64
+	err := s.view(ctx, func(ns *namespace) error {
+		v, _, err := decodeValue(ns.gauges.Get([]byte(name)))
+		value = math.Float64frombits(v)
+		return err
+	})
+	return value, err
+}
+
+func (s *Bolt) Counter(ctx context.Context, name string) (int64, error) {
+	var value int64
+	err := s.view(ctx, func(ns *namespace) error {
+		v, _, err := decodeValue(ns.counters.Get([]byte(name)))
+		value = int64(v)
+		return err
+	})
+	return value, err
+}
+
+func (s *Bolt) Metrics(ctx context.Context) ([]entity.MetricDTO, error) {
+	metrics := make([]entity.MetricDTO, 0)
+	err := s.view(ctx, func(ns *namespace) error {
+		err := ns.gauges.ForEach(func(k, v []byte) error {
+			bits, updatedAt, err := decodeValue(v)
+			if err != nil {
+				return err
+			}
+			value := math.Float64frombits(bits)
+			metrics = append(metrics, entity.MetricDTO{Name: string(k), MetricType: entity.GaugeType, Gauge: &value, UpdatedAt: updatedAt})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return ns.counters.ForEach(func(k, v []byte) error {
+			bits, updatedAt, err := decodeValue(v)
+			if err != nil {
+				return err
+			}
+			value := int64(bits)
+			metrics = append(metrics, entity.MetricDTO{Name: string(k), MetricType: entity.CounterType, Counter: &value, UpdatedAt: updatedAt})
+			return nil
+		})
+	})
+	if errors.Is(err, repoerr.ErrMetricNotFound) {
+		return metrics, nil
+	}
+	return metrics, err
+}
+
+// StoreBatch stores batch of metrics in a single transaction, a failed batch leaves nothing stored
+func (s *Bolt) StoreBatch(ctx context.Context, metrics []entity.MetricDTO) error {
+	return s.update(ctx, func(ns *namespace) error {
+		now := time.Now()
+		for _, m := range metrics {
+			var err error
+			switch m.MetricType {
+			case entity.GaugeType:
+				err = ns.putGauge(m.Name, *m.Gauge, m.UpdateTime(now))
+			case entity.CounterType:
+				err = ns.addCounter(m.Name, *m.Counter, m.UpdateTime(now))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Evict removes provided metrics unless they were updated after metric.UpdatedAt
+func (s *Bolt) Evict(ctx context.Context, metrics []entity.MetricDTO) error {
+	err := s.modify(ctx, func(ns *namespace) error {
+		for _, m := range metrics {
+			bucket := ns.metrics(m.MetricType)
+			if bucket == nil {
+				continue
+			}
+			v := bucket.Get([]byte(m.Name))
+			if v == nil {
+				continue
+			}
+			_, updatedAt, err := decodeValue(v)
+			if err != nil {
+				return err
+			}
+			if updatedAt.After(m.UpdatedAt) {
+				continue
+			}
+			err = bucket.Delete([]byte(m.Name))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, repoerr.ErrMetricNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Delete removes metric
+func (s *Bolt) Delete(ctx context.Context, metricType, name string) error {
+	return s.modify(ctx, func(ns *namespace) error {
+		bucket := ns.metrics(metricType)
+		if bucket == nil || bucket.Get([]byte(name)) == nil {
+			return repoerr.ErrMetricNotFound
+		}
+		return bucket.Delete([]byte(name))
+	})
+}
+
+// ResetCounter sets counter value to zero
+func (s *Bolt) ResetCounter(ctx context.Context, name string) error {
+	return s.modify(ctx, func(ns *namespace) error {
+		if ns.counters.Get([]byte(name)) == nil {
+			return repoerr.ErrMetricNotFound
+		}
+		return ns.counters.Put([]byte(name), encodeValue(0, time.Now()))
+	})
+}
+
+// StoreMetadata replaces metric metadata
+func (s *Bolt) StoreMetadata(ctx context.Context, metadata entity.Metadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	return s.update(ctx, func(ns *namespace) error {
+		return ns.metadata.Put([]byte(metadata.Name), data)
+	})
+}
+
+// Metadata returns metadata of all metrics
+func (s *Bolt) Metadata(ctx context.Context) ([]entity.Metadata, error) {
+	metadata := make([]entity.Metadata, 0)
+	err := s.view(ctx, func(ns *namespace) error {
+		return ns.metadata.ForEach(func(_, v []byte) error {
+			var md entity.Metadata
+			err := json.Unmarshal(v, &md)
+			if err != nil {
+				return err
+			}
+			metadata = append(metadata, md)
+			return nil
+		})
+	})
+	if errors.Is(err, repoerr.ErrMetricNotFound) {
+		return metadata, nil
+	}
+	return metadata, err
+}
+
+// Tenants returns names of all tenants having a namespace
+func (s *Bolt) Tenants(_ context.Context) ([]string, error) {
+	tenants := make([]string, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(tenantsBucket).ForEachBucket(func(k []byte) error {
+			tenants = append(tenants, strings.TrimPrefix(string(k), tenantPrefix))
+			return nil
+		})
+	})
+	return tenants, err
+}
+
+// StoreToken saves api token
+func (s *Bolt) StoreToken(_ context.Context, token entity.APIToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(tokensBucket).Put([]byte(token.ID), data)
+	})
+}
+
+// Tokens returns all api tokens including revoked ones
+func (s *Bolt) Tokens(_ context.Context) ([]entity.APIToken, error) {
+	tokens := make([]entity.APIToken, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(tokensBucket).ForEach(func(_, v []byte) error {
+			var token entity.APIToken
+			err := json.Unmarshal(v, &token)
+			if err != nil {
+				return err
+			}
+			tokens = append(tokens, token)
+			return nil
+		})
+	})
+	return tokens, err
+}
+
+// RevokeToken marks api token as revoked
+func (s *Bolt) RevokeToken(_ context.Context, id string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(tokensBucket)
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return entity.ErrTokenNotFound
+		}
+
+		var token entity.APIToken
+		err := json.Unmarshal(data, &token)
+		if err != nil {
+			return err
+		}
+		if token.RevokedAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		token.RevokedAt = &now
+		data, err = json.Marshal(token)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(id), data)
+	})
+}
+
+// Ping checks database file is open
+func (s *Bolt) Ping() error {
+	return s.db.View(func(*bbolt.Tx) error { return nil })
+}
+
+// Close flushes and unlocks database file
+func (s *Bolt) Close() error {
+	return s.db.Close()
+}
+
+// update runs fn in a write transaction on namespace of the tenant from context, creating it if needed
+func (s *Bolt) update(ctx context.Context, fn func(ns *namespace) error) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		tenant, err := tx.Bucket(tenantsBucket).CreateBucketIfNotExists(tenantKey(auth.Tenant(ctx)))
+		if err != nil {
+			return err
+		}
+
+		ns := &namespace{}
+		for _, b := range []struct {
+			bucket **bbolt.Bucket
+			name   []byte
+		}{{&ns.gauges, gaugesBucket}, {&ns.counters, countersBucket}, {&ns.metadata, metadataBucket}} {
+			*b.bucket, err = tenant.CreateBucketIfNotExists(b.name)
+			if err != nil {
+				return err
+			}
+		}
+
+		return fn(ns)
+	})
+}
+
+// modify runs fn in a write transaction on existing namespace of the tenant from context,
+// repoerr.ErrMetricNotFound is returned if tenant has no namespace
+func (s *Bolt) modify(ctx context.Context, fn func(ns *namespace) error) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		ns := tenantNamespace(tx, auth.Tenant(ctx))
+		if ns == nil {
+			return repoerr.ErrMetricNotFound
+		}
+		return fn(ns)
+	})
+}
+
+// view runs fn in a read transaction on namespace of the tenant from context,
+// repoerr.ErrMetricNotFound is returned if tenant has no namespace
+func (s *Bolt) view(ctx context.Context, fn func(ns *namespace) error) error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		ns := tenantNamespace(tx, auth.Tenant(ctx))
+		if ns == nil {
+			return repoerr.ErrMetricNotFound
+		}
+		return fn(ns)
+	})
+}
+
+// tenantNamespace returns buckets of existing tenant or nil
+func tenantNamespace(tx *bbolt.Tx, name string) *namespace {
+	tenant := tx.Bucket(tenantsBucket).Bucket(tenantKey(name))
+	if tenant == nil {
+		return nil
+	}
+
+	return &namespace{
+		gauges:   tenant.Bucket(gaugesBucket),
+		counters: tenant.Bucket(countersBucket),
+		metadata: tenant.Bucket(metadataBucket),
+	}
+}
+
+func tenantKey(tenant string) []byte {
+	return []byte(tenantPrefix + tenant)
+}
+
+// namespace keeps buckets of a single tenant
+type namespace struct {
+	gauges   *bbolt.Bucket
+	counters *bbolt.Bucket
+	metadata *bbolt.Bucket
+}
+
+func (ns *namespace) metrics(metricType string) *bbolt.Bucket {
+	switch metricType {
+	case entity.GaugeType:
+		return ns.gauges
+	case entity.CounterType:
+		return ns.counters
+	}
+	return nil
+}
+
+func (ns *namespace) putGauge(name string, value float64, now time.Time) error {
+	return ns.gauges.Put([]byte(name), encodeValue(math.Float64bits(value), now))
+}
+
+func (ns *namespace) addCounter(name string, delta int64, now time.Time) error {
+	var value int64
+	if v := ns.counters.Get([]byte(name)); v != nil {
+		stored, _, err := decodeValue(v)
+		if err != nil {
+			return err
+		}
+		value = int64(stored)
+	}
+
+	return ns.counters.Put([]byte(name), encodeValue(uint64(value+delta), now))
+}
+
+// encodeValue packs value bits and update time into 16 bytes
+func encodeValue(bits uint64, updatedAt time.Time) []byte {
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint64(buf, bits)
+	binary.BigEndian.PutUint64(buf[8:], uint64(updatedAt.UnixNano()))
+	return buf
+}
+
+// decodeValue unpacks value stored by encodeValue, nil value means metric is not stored
+func decodeValue(v []byte) (uint64, time.Time, error) {
+	if v == nil {
+		return 0, time.Time{}, repoerr.ErrMetricNotFound
+	}
+	if len(v) != 16 {
+		return 0, time.Time{}, errInvalidValue
+	}
+	return binary.BigEndian.Uint64(v), time.Unix(0, int64(binary.BigEndian.Uint64(v[8:]))), nil
+}
@@ -0,0 +1,134 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+)
+
+func TestBolt(t *testing.T) {
+	ctx := context.Background()
+	acme := auth.WithTenant(ctx, "acme")
+
+	t.Run("data_survives_reopen", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "metrics.db")
+
+		repo, err := NewBolt(path)
+		require.NoError(t, err)
+		require.NoError(t, repo.StoreGauge(ctx, "load", 0.5))
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 2))
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 3))
+		require.NoError(t, repo.StoreGauge(acme, "temp", 36.6))
+		require.NoError(t, repo.StoreMetadata(ctx, entity.Metadata{Name: "load", Unit: "percent"}))
+		require.NoError(t, repo.StoreToken(ctx, entity.APIToken{ID: "t1", Tenant: "acme", Hash: "h"}))
+		require.NoError(t, repo.RevokeToken(ctx, "t1"))
+		require.NoError(t, repo.Close())
+
+		repo, err = NewBolt(path)
+		require.NoError(t, err)
+		defer repo.Close()
+
+		gauge, err := repo.Gauge(ctx, "load")
+		require.NoError(t, err)
+		require.Equal(t, 0.5, gauge)
+
+		counter, err := repo.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Equal(t, int64(5), counter)
+
+		_, err = repo.Gauge(ctx, "temp")
+		require.ErrorIs(t, err, repoerr.ErrMetricNotFound)
+		gauge, err = repo.Gauge(acme, "temp")
+		require.NoError(t, err)
+		require.Equal(t, 36.6, gauge)
+
+		tenants, err := repo.Tenants(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{auth.DefaultTenant, "acme"}, tenants)
+
+		metadata, err := repo.Metadata(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []entity.Metadata{{Name: "load", Unit: "percent"}}, metadata)
+
+		tokens, err := repo.Tokens(ctx)
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		require.Equal(t, "h", tokens[0].Hash)
+		require.NotNil(t, tokens[0].RevokedAt)
+	})
+
+	t.Run("failed_batch_stores_nothing", func(t *testing.T) {
+		repo, err := NewBolt(filepath.Join(t.TempDir(), "metrics.db"))
+		require.NoError(t, err)
+		defer repo.Close()
+
+		delta := int64(1)
+		value := 1.5
+		err = repo.StoreBatch(ctx, []entity.MetricDTO{
+			{Name: "requests", MetricType: entity.CounterType, Counter: &delta},
+			{Name: "", MetricType: entity.GaugeType, Gauge: &value},
+		})
+		require.Error(t, err)
+
+		_, err = repo.Counter(ctx, "requests")
+		require.ErrorIs(t, err, repoerr.ErrMetricNotFound)
+
+		require.NoError(t, repo.StoreBatch(ctx, []entity.MetricDTO{
+			{Name: "requests", MetricType: entity.CounterType, Counter: &delta},
+			{Name: "requests", MetricType: entity.CounterType, Counter: &delta},
+			{Name: "load", MetricType: entity.GaugeType, Gauge: &value},
+		}))
+
+		metrics, err := repo.Metrics(ctx)
+		require.NoError(t, err)
+		require.Len(t, metrics, 2)
+		for _, m := range metrics {
+			require.False(t, m.UpdatedAt.IsZero())
+			if m.MetricType == entity.CounterType {
+				require.Equal(t, int64(2), *m.Counter)
+			}
+		}
+	})
+
+	t.Run("delete_reset_and_evict", func(t *testing.T) {
+		repo, err := NewBolt(filepath.Join(t.TempDir(), "metrics.db"))
+		require.NoError(t, err)
+		defer repo.Close()
+
+		require.ErrorIs(t, repo.Delete(acme, entity.GaugeType, "missing"), repoerr.ErrMetricNotFound)
+		require.ErrorIs(t, repo.ResetCounter(acme, "missing"), repoerr.ErrMetricNotFound)
+		tenants, err := repo.Tenants(ctx)
+		require.NoError(t, err)
+		require.Empty(t, tenants)
+
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 7))
+		require.NoError(t, repo.ResetCounter(ctx, "requests"))
+		counter, err := repo.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Zero(t, counter)
+
+		require.NoError(t, repo.StoreGauge(ctx, "stale", 1))
+		require.NoError(t, repo.StoreGauge(ctx, "fresh", 1))
+		before := time.Now()
+		require.NoError(t, repo.StoreGauge(ctx, "fresh", 2))
+		require.NoError(t, repo.Evict(ctx, []entity.MetricDTO{
+			{Name: "stale", MetricType: entity.GaugeType, UpdatedAt: before},
+			{Name: "fresh", MetricType: entity.GaugeType, UpdatedAt: before.Add(-time.Hour)},
+		}))
+		_, err = repo.Gauge(ctx, "stale")
+		require.ErrorIs(t, err, repoerr.ErrMetricNotFound)
+		_, err = repo.Gauge(ctx, "fresh")
+		require.NoError(t, err)
+
+		require.NoError(t, repo.Delete(ctx, entity.GaugeType, "fresh"))
+		require.ErrorIs(t, repo.Delete(ctx, entity.GaugeType, "fresh"), repoerr.ErrMetricNotFound)
+		require.ErrorIs(t, repo.RevokeToken(ctx, "missing"), entity.ErrTokenNotFound)
+	})
+}
@@ -0,0 +1,455 @@
+// Package cache provides write-back cache in front of a persistent repository. Metrics are read from memory,
+// writes are coalesced and flushed to the wrapped repository on interval or when too many of them are pending.
+// Cache must be the only writer of the wrapped repository, writes of other server instances are not seen.
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/arxon31/metrics-collector/pkg/logger"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository"
+	"github.com/arxon31/metrics-collector/internal/repository/memory"
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+)
+
+const (
+	DefaultFlushInterval = time.Second
+	DefaultMaxPending    = 10000
+	// shutdownFlushTimeout limits the last flush after Run is canceled
+	shutdownFlushTimeout = 10 * time.Second
+)
+
+// Options configures flushing, zero values mean defaults
+type Options struct {
+	FlushInterval time.Duration
+	// MaxPending is the number of pending metrics triggering flush before interval elapses
+	MaxPending int
+}
+
+// pending keeps coalesced writes of a single tenant: the last gauge values and sums of counter deltas
+type pending struct {
+	gauges   map[string]float64
+	counters map[string]int64
+}
+
+func newPending() *pending {
+	return &pending{
+		gauges:   make(map[string]float64),
+		counters: make(map[string]int64),
+	}
+}
+
+func (p *pending) len() int {
+	return len(p.gauges) + len(p.counters)
+}
+
+// Repository serves metrics from memory and writes them back to wrapped repository.
+// Metadata and tokens are not cached.
+type Repository struct {
+	repository.Repository
+	view *memory.MapStorage
+	opts Options
+
+	// mu guards pending writes, it is held by operations which must not interleave with writes
+	mu           sync.Mutex
+	pending      map[string]*pending
+	pendingCount int
+	oldest       time.Time
+
+	// flushMu serializes flushes with each other and with operations bypassing cache
+	flushMu sync.Mutex
+	flushCh chan struct{}
+
+	statsMu sync.Mutex
+	stats   entity.CacheStats
+}
+
+// NewRepository wraps repo, Load must be called before cache serves reads
+func NewRepository(repo repository.Repository, opts Options) *Repository {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = DefaultMaxPending
+	}
+
+	return &Repository{
+		Repository: repo,
+		view:       memory.NewMapStorage(),
+		opts:       opts,
+		pending:    make(map[string]*pending),
+		flushCh:    make(chan struct{}, 1),
+	}
+}
+
+// Load reads metrics of all tenants from wrapped repository into memory,
+// loaded metrics keep their update times
+func (r *Repository) Load(ctx context.Context) error {
+	tenants, err := r.Repository.Tenants(ctx)
+	if err != nil {
+		return err
+	}
+
+	loaded := 0
+	for _, tenant := range tenants {
+		tenantCtx := auth.WithTenant(ctx, tenant)
+
+		metrics, err := r.Repository.Metrics(tenantCtx)
+		if err != nil {
+			return fmt.Errorf("tenant %q: %w", tenant, err)
+		}
+
+		err = r.view.StoreBatch(tenantCtx, metrics)
+		if err != nil {
+			return fmt.Errorf("tenant %q: %w", tenant, err)
+		}
+		loaded += len(metrics)
+	}
+
+	logger.Logger.Infof("cache loaded %d metrics of %d tenants", loaded, len(tenants))
+	return nil
+}
+
+// Run flushes pending writes on interval or when too many of them are pending until ctx is canceled,
+// then flushes the rest
+func (r *Repository) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), shutdownFlushTimeout)
+			defer cancel()
+			err := r.Flush(flushCtx)
+			if err != nil {
+				return fmt.Errorf("can not flush cache on shutdown: %w", err)
+			}
+			logger.Logger.Info("cache flushed on shutdown")
+			return nil
+		case <-ticker.C:
+		case <-r.flushCh:
+		}
+
+		err := r.Flush(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Logger.Errorf("can not flush cache: %v", err)
+		}
+	}
+}
+
+// Flush writes pending writes to wrapped repository, writes of tenants which failed are kept pending
+func (r *Repository) Flush(ctx context.Context) error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	r.mu.Lock()
+	batch, oldest := r.takePending()
+	r.mu.Unlock()
+
+	err := r.flush(ctx, batch, oldest)
+	if err != nil {
+		r.mu.Lock()
+		r.restorePending(batch, oldest)
+		r.mu.Unlock()
+	}
+	return err
+}
+
+// Stats returns flush statistics
+func (r *Repository) Stats() entity.CacheStats {
+	r.mu.Lock()
+	count, oldest := r.pendingCount, r.oldest
+	r.mu.Unlock()
+
+	r.statsMu.Lock()
+	stats := r.stats
+	r.statsMu.Unlock()
+
+	stats.Pending = count
+	if count > 0 {
+		stats.Lag = time.Since(oldest)
+	}
+	return stats
+}
+
+func (r *Repository) StoreGauge(ctx context.Context, name string, value float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.view.StoreGauge(ctx, name, value)
+	if err != nil {
+		return err
+	}
+	r.tenantPending(ctx).gauges[name] = value
+	r.written()
+	return nil
+}
+
+func (r *Repository) StoreCounter(ctx context.Context, name string, value int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.view.StoreCounter(ctx, name, value)
+	if err != nil {
+		return err
+	}
+	r.tenantPending(ctx).counters[name] += value
+	r.written()
+	return nil
+}
+
+func (r *Repository) StoreBatch(ctx context.Context, metrics []entity.MetricDTO) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.view.StoreBatch(ctx, metrics)
+	if err != nil {
+		return err
+	}
+
+	p := r.tenantPending(ctx)
+	for _, m := range metrics {
+		switch m.MetricType {
+		case entity.GaugeType:
+			p.gauges[m.Name] = *m.Gauge
+		case entity.CounterType:
+			p.counters[m.Name] += *m.Counter
+		}
+	}
+	r.written()
+	return nil
+}
+
+func (r *Repository) Gauge(ctx context.Context, name string) (float64, error) {
+	return r.view.Gauge(ctx, name)
+}
+
+func (r *Repository) Counter(ctx context.Context, name string) (int64, error) {
+	return r.view.Counter(ctx, name)
+}
+
+func (r *Repository) Metrics(ctx context.Context) ([]entity.MetricDTO, error) {
+	return r.view.Metrics(ctx)
+}
+
+// Tenants returns tenants of wrapped repository and tenants having metrics not flushed yet
+func (r *Repository) Tenants(ctx context.Context) ([]string, error) {
+	tenants, err := r.Repository.Tenants(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cached, err := r.view.Tenants(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(tenants))
+	for _, tenant := range tenants {
+		seen[tenant] = true
+	}
+	for _, tenant := range cached {
+		if !seen[tenant] {
+			tenants = append(tenants, tenant)
+		}
+	}
+	return tenants, nil
+}
+
+// Delete flushes pending writes and removes metric from cache and wrapped repository
+func (r *Repository) Delete(ctx context.Context, metricType, name string) error {
+	return r.bypass(ctx, func() error {
+		err := r.Repository.Delete(ctx, metricType, name)
+		if err != nil {
+			return err
+		}
+		err = r.view.Delete(ctx, metricType, name)
+		if errors.Is(err, repoerr.ErrMetricNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+// ResetCounter flushes pending writes and sets counter to zero in cache and wrapped repository
+func (r *Repository) ResetCounter(ctx context.Context, name string) error {
+	return r.bypass(ctx, func() error {
+		err := r.Repository.ResetCounter(ctx, name)
+		if err != nil {
+			return err
+		}
+		err = r.view.ResetCounter(ctx, name)
+		if errors.Is(err, repoerr.ErrMetricNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+// Evict removes metrics not updated in cache since metric.UpdatedAt. Update times of wrapped repository
+// are times of flushes, so it is evicted up to now: cache is its only writer and has no newer writes.
+func (r *Repository) Evict(ctx context.Context, metrics []entity.MetricDTO) error {
+	return r.bypass(ctx, func() error {
+		cached, err := r.view.Metrics(ctx)
+		if err != nil {
+			return err
+		}
+
+		updatedAt := make(map[string]time.Time, len(cached))
+		for _, m := range cached {
+			updatedAt[m.MetricType+"/"+m.Name] = m.UpdatedAt
+		}
+
+		now := time.Now()
+		stale := make([]entity.MetricDTO, 0, len(metrics))
+		for _, m := range metrics {
+			if t, ok := updatedAt[m.MetricType+"/"+m.Name]; ok && !t.After(m.UpdatedAt) {
+				stale = append(stale, entity.MetricDTO{Name: m.Name, MetricType: m.MetricType, UpdatedAt: now})
+			}
+		}
+		if len(stale) == 0 {
+			return nil
+		}
+
+		err = r.Repository.Evict(ctx, stale)
+		if err != nil {
+			return err
+		}
+		return r.view.Evict(ctx, metrics)
+	})
+}
+
+// bypass runs op changing wrapped repository directly, writes are paused and pending ones are flushed first
+func (r *Repository) bypass(ctx context.Context, op func() error) error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	batch, oldest := r.takePending()
+	err := r.flush(ctx, batch, oldest)
+	if err != nil {
+		r.restorePending(batch, oldest)
+		return err
+	}
+
+	return op()
+}
+
+// tenantPending returns pending writes of the tenant from context, must be called under mu
+func (r *Repository) tenantPending(ctx context.Context) *pending {
+	tenant := auth.Tenant(ctx)
+	p, ok := r.pending[tenant]
+	if !ok {
+		p = newPending()
+		r.pending[tenant] = p
+	}
+	return p
+}
+
+// written updates pending counters after tenantPending was written, must be called under mu
+func (r *Repository) written() {
+	count := 0
+	for _, p := range r.pending {
+		count += p.len()
+	}
+	if r.pendingCount == 0 && count > 0 {
+		r.oldest = time.Now()
+	}
+	r.pendingCount = count
+
+	if count >= r.opts.MaxPending {
+		select {
+		case r.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// takePending detaches pending writes, must be called under mu
+func (r *Repository) takePending() (map[string]*pending, time.Time) {
+	batch, oldest := r.pending, r.oldest
+	r.pending = make(map[string]*pending)
+	r.pendingCount = 0
+	return batch, oldest
+}
+
+// restorePending returns writes not flushed to pending ones, counter deltas written meanwhile are summed
+// and newer gauge values are kept, must be called under mu
+func (r *Repository) restorePending(batch map[string]*pending, oldest time.Time) {
+	for tenant, failed := range batch {
+		p, ok := r.pending[tenant]
+		if !ok {
+			r.pending[tenant] = failed
+			continue
+		}
+		for name, value := range failed.gauges {
+			if _, ok := p.gauges[name]; !ok {
+				p.gauges[name] = value
+			}
+		}
+		for name, delta := range failed.counters {
+			p.counters[name] += delta
+		}
+	}
+
+	count := 0
+	for _, p := range r.pending {
+		count += p.len()
+	}
+	if len(batch) > 0 {
+		r.oldest = oldest
+	}
+	r.pendingCount = count
+}
+
+// flush writes batch to wrapped repository, flushed tenants are removed from batch
+func (r *Repository) flush(ctx context.Context, batch map[string]*pending, oldest time.Time) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for tenant, p := range batch {
+		metrics := make([]entity.MetricDTO, 0, p.len())
+		for name, value := range p.gauges {
+			value := value
+			metrics = append(metrics, entity.MetricDTO{Name: name, MetricType: entity.GaugeType, Gauge: &value})
+		}
+		for name, delta := range p.counters {
+			delta := delta
+			metrics = append(metrics, entity.MetricDTO{Name: name, MetricType: entity.CounterType, Counter: &delta})
+		}
+
+		err := r.Repository.StoreBatch(auth.WithTenant(ctx, tenant), metrics)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tenant %q: %w", tenant, err))
+			continue
+		}
+		delete(batch, tenant)
+	}
+
+	err := errors.Join(errs...)
+
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+	if err != nil {
+		r.stats.FlushErrors++
+		r.stats.LastError = err.Error()
+		return err
+	}
+	now := time.Now()
+	r.stats.Flushes++
+	r.stats.LastFlush = now
+	r.stats.LastFlushLag = now.Sub(oldest)
+	r.stats.LastError = ""
+	return nil
+}
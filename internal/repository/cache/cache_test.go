@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/memory"
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+)
+
+// flakyRepository fails batches while down is set
+type flakyRepository struct {
+	*memory.MapStorage
+	down    bool
+	batches int
+}
+
+func (r *flakyRepository) StoreBatch(ctx context.Context, metrics []entity.MetricDTO) error {
+	if r.down {
+		return errors.New("connection refused")
+	}
+	r.batches++
+	return r.MapStorage.StoreBatch(ctx, metrics)
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	acme := auth.WithTenant(ctx, "acme")
+
+	t.Run("coalesces_writes", func(t *testing.T) {
+		backing := &flakyRepository{MapStorage: memory.NewMapStorage()}
+		require.NoError(t, backing.StoreCounter(ctx, "requests", 10))
+
+		repo := NewRepository(backing, Options{})
+		require.NoError(t, repo.Load(ctx))
+
+		delta := int64(2)
+		value := 0.7
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 1))
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 3))
+		require.NoError(t, repo.StoreGauge(ctx, "load", 0.5))
+		require.NoError(t, repo.StoreBatch(ctx, []entity.MetricDTO{
+			{Name: "requests", MetricType: entity.CounterType, Counter: &delta},
+			{Name: "load", MetricType: entity.GaugeType, Gauge: &value},
+		}))
+		require.NoError(t, repo.StoreGauge(acme, "temp", 36.6))
+
+		// reads are served before flush
+		counter, err := repo.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Equal(t, int64(16), counter)
+		_, err = backing.Gauge(ctx, "load")
+		require.ErrorIs(t, err, repoerr.ErrMetricNotFound)
+
+		stats := repo.Stats()
+		require.Equal(t, 3, stats.Pending)
+		require.Positive(t, stats.Lag)
+
+		require.NoError(t, repo.Flush(ctx))
+		require.Equal(t, 2, backing.batches)
+
+		counter, err = backing.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Equal(t, int64(16), counter)
+		gauge, err := backing.Gauge(ctx, "load")
+		require.NoError(t, err)
+		require.Equal(t, value, gauge)
+		gauge, err = backing.Gauge(acme, "temp")
+		require.NoError(t, err)
+		require.Equal(t, 36.6, gauge)
+
+		stats = repo.Stats()
+		require.Zero(t, stats.Pending)
+		require.Zero(t, stats.Lag)
+		require.Equal(t, int64(1), stats.Flushes)
+		require.False(t, stats.LastFlush.IsZero())
+	})
+
+	t.Run("failed_flush_keeps_writes", func(t *testing.T) {
+		backing := &flakyRepository{MapStorage: memory.NewMapStorage(), down: true}
+		repo := NewRepository(backing, Options{})
+
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 1))
+		require.NoError(t, repo.StoreGauge(ctx, "load", 0.5))
+		require.Error(t, repo.Flush(ctx))
+
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 2))
+		require.NoError(t, repo.StoreGauge(ctx, "load", 0.9))
+
+		stats := repo.Stats()
+		require.Equal(t, 2, stats.Pending)
+		require.Equal(t, int64(1), stats.FlushErrors)
+		require.NotEmpty(t, stats.LastError)
+
+		backing.down = false
+		require.NoError(t, repo.Flush(ctx))
+
+		counter, err := backing.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Equal(t, int64(3), counter)
+		gauge, err := backing.Gauge(ctx, "load")
+		require.NoError(t, err)
+		require.Equal(t, 0.9, gauge)
+		require.Empty(t, repo.Stats().LastError)
+	})
+
+	t.Run("delete_and_reset_flush_first", func(t *testing.T) {
+		backing := &flakyRepository{MapStorage: memory.NewMapStorage()}
+		repo := NewRepository(backing, Options{})
+
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 5))
+		require.NoError(t, repo.StoreGauge(ctx, "load", 0.5))
+
+		require.NoError(t, repo.ResetCounter(ctx, "requests"))
+		counter, err := backing.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Zero(t, counter)
+		counter, err = repo.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Zero(t, counter)
+
+		require.NoError(t, repo.Delete(ctx, entity.GaugeType, "load"))
+		_, err = repo.Gauge(ctx, "load")
+		require.ErrorIs(t, err, repoerr.ErrMetricNotFound)
+		_, err = backing.Gauge(ctx, "load")
+		require.ErrorIs(t, err, repoerr.ErrMetricNotFound)
+		require.ErrorIs(t, repo.Delete(ctx, entity.GaugeType, "load"), repoerr.ErrMetricNotFound)
+	})
+
+	t.Run("evict_removes_stale_metrics", func(t *testing.T) {
+		backing := &flakyRepository{MapStorage: memory.NewMapStorage()}
+		repo := NewRepository(backing, Options{})
+
+		require.NoError(t, repo.StoreGauge(ctx, "stale", 1))
+		before := time.Now()
+		require.NoError(t, repo.StoreGauge(ctx, "fresh", 1))
+		require.NoError(t, repo.Flush(ctx))
+
+		require.NoError(t, repo.Evict(ctx, []entity.MetricDTO{
+			{Name: "stale", MetricType: entity.GaugeType, UpdatedAt: before},
+			{Name: "fresh", MetricType: entity.GaugeType, UpdatedAt: before},
+		}))
+
+		for _, r := range []interface {
+			Gauge(ctx context.Context, name string) (float64, error)
+		}{repo, backing} {
+			_, err := r.Gauge(ctx, "stale")
+			require.ErrorIs(t, err, repoerr.ErrMetricNotFound)
+			_, err = r.Gauge(ctx, "fresh")
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("run_flushes_on_size_and_shutdown", func(t *testing.T) {
+		backing := &flakyRepository{MapStorage: memory.NewMapStorage()}
+		repo := NewRepository(backing, Options{FlushInterval: time.Hour, MaxPending: 2})
+
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error)
+		go func() { done <- repo.Run(runCtx) }()
+
+		require.NoError(t, repo.StoreCounter(ctx, "a", 1))
+		require.NoError(t, repo.StoreCounter(ctx, "b", 1))
+		require.Eventually(t, func() bool {
+			_, err := backing.Counter(ctx, "b")
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+
+		require.NoError(t, repo.StoreCounter(ctx, "c", 1))
+		cancel()
+		require.NoError(t, <-done)
+
+		counter, err := backing.Counter(ctx, "c")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), counter)
+	})
+}
@@ -3,107 +3,274 @@ package memory
 import (
 	"context"
 	"sync"
+	"time"
 
+	"github.com/arxon31/metrics-collector/internal/auth"
 	"github.com/arxon31/metrics-collector/internal/entity"
 	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
 )
 
+type gauge struct {
+	value     float64
+	updatedAt time.Time
+}
+
+type counter struct {
+	value     int64
+	updatedAt time.Time
+}
+
+// namespace keeps metrics of a single tenant
+type namespace struct {
+	gauges   map[string]gauge
+	counts   map[string]counter
+	metadata map[string]entity.Metadata
+}
+
+func newNamespace() *namespace {
+	return &namespace{
+		gauges:   make(map[string]gauge),
+		counts:   make(map[string]counter),
+		metadata: make(map[string]entity.Metadata),
+	}
+}
+
 type MapStorage struct {
-	rw     *sync.RWMutex
-	gauges map[string]float64
-	counts map[string]int64
+	rw         *sync.RWMutex
+	namespaces map[string]*namespace
+	tokens     map[string]entity.APIToken
 }
 
 func NewMapStorage() *MapStorage {
 	return &MapStorage{
-		rw:     &sync.RWMutex{},
-		gauges: make(map[string]float64),
-		counts: make(map[string]int64),
+		rw:         &sync.RWMutex{},
+		namespaces: map[string]*namespace{auth.DefaultTenant: newNamespace()},
+		tokens:     make(map[string]entity.APIToken),
+	}
+}
+
+// ns returns namespace of the tenant from context, must be called under lock
+func (s *MapStorage) ns(ctx context.Context) *namespace {
+	tenant := auth.Tenant(ctx)
+	ns, ok := s.namespaces[tenant]
+	if !ok {
+		ns = newNamespace()
+		s.namespaces[tenant] = ns
+	}
+	return ns
+}
+
+// view returns namespace of the tenant from context without creating it, must be called under read lock
+func (s *MapStorage) view(ctx context.Context) *namespace {
+	if ns, ok := s.namespaces[auth.Tenant(ctx)]; ok {
+		return ns
 	}
+	return emptyNamespace
 }
 
-func (s *MapStorage) StoreGauge(_ context.Context, name string, value float64) error {
+var emptyNamespace = newNamespace()
+
+func (s *MapStorage) StoreGauge(ctx context.Context, name string, value float64) error {
 	s.rw.Lock()
 	defer s.rw.Unlock()
-	s.gauges[name] = value
+	s.ns(ctx).gauges[name] = gauge{value: value, updatedAt: time.Now()}
 	return nil
 }
 
-func (s *MapStorage) StoreCounter(_ context.Context, name string, value int64) error {
+func (s *MapStorage) StoreCounter(ctx context.Context, name string, value int64) error {
 	s.rw.Lock()
 	defer s.rw.Unlock()
-	if _, ok := s.counts[name]; !ok {
-		s.counts[name] = value
-		return nil
-	}
-	s.counts[name] += value
+	ns := s.ns(ctx)
+	ns.counts[name] = counter{value: ns.counts[name].value + value, updatedAt: time.Now()}
 	return nil
 }
 
-func (s *MapStorage) Gauge(_ context.Context, name string) (float64, error) {
+func (s *MapStorage) Gauge(ctx context.Context, name string) (float64, error) {
 	s.rw.RLock()
 	defer s.rw.RUnlock()
-	if val, ok := s.gauges[name]; ok {
-		return val, nil
+	if g, ok := s.view(ctx).gauges[name]; ok {
+		return g.value, nil
 	}
 	return -1, repoerr.ErrMetricNotFound
 }
 
-func (s *MapStorage) Counter(_ context.Context, name string) (int64, error) {
+func (s *MapStorage) Counter(ctx context.Context, name string) (int64, error) {
 	s.rw.RLock()
 	defer s.rw.RUnlock()
-	if val, ok := s.counts[name]; ok {
-		return val, nil
+	if c, ok := s.view(ctx).counts[name]; ok {
+		return c.value, nil
 	}
 	return -1, repoerr.ErrMetricNotFound
 }
 
-func (s *MapStorage) Metrics(_ context.Context) ([]entity.MetricDTO, error) {
+func (s *MapStorage) Metrics(ctx context.Context) ([]entity.MetricDTO, error) {
 	s.rw.RLock()
 	defer s.rw.RUnlock()
 
-	metrics := make([]entity.MetricDTO, 0, len(s.gauges)+len(s.counts))
+	ns := s.view(ctx)
+	metrics := make([]entity.MetricDTO, 0, len(ns.gauges)+len(ns.counts))
 
-	for name, value := range s.gauges {
-		val := value
+	for name, g := range ns.gauges {
+		val := g.value
 		metrics = append(metrics, entity.MetricDTO{
 			Name:       name,
 			MetricType: entity.GaugeType,
 			Gauge:      &val,
+			UpdatedAt:  g.updatedAt,
 		})
 	}
 
-	for name, value := range s.counts {
-		val := value
+	for name, c := range ns.counts {
+		val := c.value
 		metrics = append(metrics, entity.MetricDTO{
 			Name:       name,
 			MetricType: entity.CounterType,
 			Counter:    &val,
+			UpdatedAt:  c.updatedAt,
 		})
 	}
 
 	return metrics, nil
 }
 
-func (s *MapStorage) StoreBatch(_ context.Context, metrics []entity.MetricDTO) error {
+func (s *MapStorage) StoreBatch(ctx context.Context, metrics []entity.MetricDTO) error {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	ns := s.ns(ctx)
+	now := time.Now()
+	for _, m := range metrics {
+		switch m.MetricType {
+		case entity.GaugeType:
+			ns.gauges[m.Name] = gauge{value: *m.Gauge, updatedAt: m.UpdateTime(now)}
+		case entity.CounterType:
+			ns.counts[m.Name] = counter{value: ns.counts[m.Name].value + *m.Counter, updatedAt: m.UpdateTime(now)}
+		}
+	}
+	return nil
+}
+
+// Evict removes provided metrics unless they were updated after metric.UpdatedAt
+func (s *MapStorage) Evict(ctx context.Context, metrics []entity.MetricDTO) error {
 	s.rw.Lock()
 	defer s.rw.Unlock()
+	ns := s.view(ctx)
 	for _, m := range metrics {
 		switch m.MetricType {
 		case entity.GaugeType:
-			value := *m.Gauge
-			s.gauges[m.Name] = value
+			if g, ok := ns.gauges[m.Name]; ok && !g.updatedAt.After(m.UpdatedAt) {
+				delete(ns.gauges, m.Name)
+			}
 		case entity.CounterType:
-			value := *m.Counter
-			if _, ok := s.counts[m.Name]; !ok {
-				s.counts[m.Name] = value
-				return nil
+			if c, ok := ns.counts[m.Name]; ok && !c.updatedAt.After(m.UpdatedAt) {
+				delete(ns.counts, m.Name)
 			}
-			s.counts[m.Name] += value
 		}
 	}
 	return nil
+}
 
+// Delete removes metric
+func (s *MapStorage) Delete(ctx context.Context, metricType, name string) error {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	ns := s.view(ctx)
+	switch metricType {
+	case entity.GaugeType:
+		if _, ok := ns.gauges[name]; ok {
+			delete(ns.gauges, name)
+			return nil
+		}
+	case entity.CounterType:
+		if _, ok := ns.counts[name]; ok {
+			delete(ns.counts, name)
+			return nil
+		}
+	}
+	return repoerr.ErrMetricNotFound
+}
+
+// ResetCounter sets counter value to zero
+func (s *MapStorage) ResetCounter(ctx context.Context, name string) error {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	ns := s.view(ctx)
+	if _, ok := ns.counts[name]; !ok {
+		return repoerr.ErrMetricNotFound
+	}
+	ns.counts[name] = counter{value: 0, updatedAt: time.Now()}
+	return nil
+}
+
+// StoreMetadata replaces metric metadata
+func (s *MapStorage) StoreMetadata(ctx context.Context, metadata entity.Metadata) error {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	s.ns(ctx).metadata[metadata.Name] = metadata
+	return nil
+}
+
+// Metadata returns metadata of all metrics
+func (s *MapStorage) Metadata(ctx context.Context) ([]entity.Metadata, error) {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	ns := s.view(ctx)
+	metadata := make([]entity.Metadata, 0, len(ns.metadata))
+	for _, md := range ns.metadata {
+		metadata = append(metadata, md)
+	}
+
+	return metadata, nil
+}
+
+// Tenants returns names of all tenants having a namespace
+func (s *MapStorage) Tenants(_ context.Context) ([]string, error) {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	tenants := make([]string, 0, len(s.namespaces))
+	for tenant := range s.namespaces {
+		tenants = append(tenants, tenant)
+	}
+
+	return tenants, nil
+}
+
+// StoreToken saves api token
+func (s *MapStorage) StoreToken(_ context.Context, token entity.APIToken) error {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	s.tokens[token.ID] = token
+	return nil
+}
+
+// Tokens returns all api tokens including revoked ones
+func (s *MapStorage) Tokens(_ context.Context) ([]entity.APIToken, error) {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	tokens := make([]entity.APIToken, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// RevokeToken marks api token as revoked
+func (s *MapStorage) RevokeToken(_ context.Context, id string) error {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	token, ok := s.tokens[id]
+	if !ok {
+		return entity.ErrTokenNotFound
+	}
+	if token.RevokedAt == nil {
+		now := time.Now()
+		token.RevokedAt = &now
+		s.tokens[id] = token
+	}
+	return nil
 }
 
 func (s *MapStorage) Ping() error {
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+)
+
+const (
+	// breakerThreshold is the number of consecutive failures to reach database opening breaker
+	breakerThreshold = 5
+	// breakerCooldown is how long requests fail fast before a probe is let through
+	breakerCooldown = 10 * time.Second
+)
+
+// breaker fails requests fast while database is down instead of letting each of them wait for connection timeout.
+// It opens after threshold consecutive unavailability errors, after cooldown a single probe
+// is let through and its result closes the breaker or opens it for another cooldown.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+	now       func() time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// allow returns repoerr.ErrUnavailable if request must not reach database
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+
+	now := b.now()
+	if b.probing || now.Before(b.openUntil) {
+		return fmt.Errorf("%w: database is down, retry after %s", repoerr.ErrUnavailable, b.openUntil.Sub(now).Round(time.Second))
+	}
+
+	b.probing = true
+	return nil
+}
+
+// record counts result of request let through by allow
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	if err == nil || !isUnavailable(err) {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	down := &pgconn.PgError{Code: "08006"}
+
+	// errors reported by a reachable database do not open breaker
+	require.NoError(t, b.allow())
+	b.record(&pgconn.PgError{Code: "23505"})
+	require.NoError(t, b.allow())
+	b.record(down)
+	require.NoError(t, b.allow())
+	b.record(errors.New("scan error"))
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, b.allow())
+		b.record(down)
+	}
+	require.ErrorIs(t, b.allow(), repoerr.ErrUnavailable)
+
+	// a single probe is let through after cooldown
+	now = now.Add(time.Minute)
+	require.NoError(t, b.allow())
+	require.ErrorIs(t, b.allow(), repoerr.ErrUnavailable)
+	b.record(down)
+	require.ErrorIs(t, b.allow(), repoerr.ErrUnavailable)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, b.allow())
+	b.record(nil)
+	require.NoError(t, b.allow())
+	require.NoError(t, b.allow())
+}
@@ -3,28 +3,167 @@ package postgres
 import (
 	"database/sql"
 	"errors"
-	"log"
+	"fmt"
+	"io/fs"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "github.com/lib/pq"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+
+	"github.com/arxon31/metrics-collector/migrations"
+)
+
+const (
+	// migrationsTable keeps applied version and dirty flag of schema
+	migrationsTable = "schema_migrations"
+	// migrationsLockTimeout limits how long a replica waits for another one applying migrations
+	migrationsLockTimeout = time.Minute
 )
 
-func migrationsUp(db *sql.DB) {
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
+var ErrMigrationSteps = errors.New("number of steps must be positive")
+
+// MigrationStatus describes schema version of database
+type MigrationStatus struct {
+	// Version is the last applied migration, zero if none were applied
+	Version uint
+	// Latest is the last migration known to the server
+	Latest uint
+	// Pending are migrations not applied yet
+	Pending []uint
+	// Dirty is set when a migration failed in the middle, schema has to be fixed and forced to a version manually
+	Dirty bool
+}
+
+// Migrator applies embedded migrations. Version is kept in schema_migrations table and
+// migrations run under postgres advisory lock, so replicas started together apply them once.
+type Migrator struct {
+	instance *migrate.Migrate
+	source   fs.FS
+}
+
+// NewMigrator connects to database by url, migrator owns its connection and has to be closed
+func NewMigrator(url string) (*Migrator, error) {
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := postgres.WithInstance(db, &postgres.Config{MigrationsTable: migrationsTable})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can not create migrations driver: %w", err)
+	}
+
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("can not read migrations: %w", err)
+	}
+
+	instance, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("can not create migrator: %w", err)
+	}
+	instance.LockTimeout = migrationsLockTimeout
+
+	return &Migrator{instance: instance, source: migrations.FS}, nil
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up() error {
+	err := m.instance.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Down rolls back steps last migrations, down migrations fail instead of dropping tables holding data
+func (m *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return ErrMigrationSteps
+	}
+
+	err := m.instance.Steps(-steps)
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Force sets version without running migrations and clears dirty flag, it is used after fixing a failed migration
+func (m *Migrator) Force(version int) error {
+	return m.instance.Force(version)
+}
+
+// Status returns applied and pending migrations
+func (m *Migrator) Status() (MigrationStatus, error) {
+	var status MigrationStatus
+
+	version, dirty, err := m.instance.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return status, err
+	}
+	status.Version = version
+	status.Dirty = dirty
+
+	versions, err := migrationVersions(m.source)
 	if err != nil {
-		panic(err)
+		return status, err
 	}
-	instance, err := migrate.NewWithDatabaseInstance(
-		"file://./migrations",
-		"postgres", driver)
+	for _, v := range versions {
+		if v > status.Version {
+			status.Pending = append(status.Pending, v)
+		}
+	}
+	if len(versions) > 0 {
+		status.Latest = versions[len(versions)-1]
+	}
+
+	return status, nil
+}
+
+// Close releases database connection
+func (m *Migrator) Close() error {
+	sourceErr, dbErr := m.instance.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+// migrationVersions returns versions of migrations in ascending order
+func migrationVersions(fsys fs.FS) ([]uint, error) {
+	source, err := iofs.New(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	version, err := source.First()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+
+	versions := []uint{version}
+	for {
+		version, err = source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return versions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
 	}
+}
 
-	err = instance.Up()
-	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		log.Fatal(err)
+// migrateUp applies pending migrations on server start, it is safe to run concurrently from several replicas
+func migrateUp(url string) error {
+	migrator, err := NewMigrator(url)
+	if err != nil {
+		return err
 	}
+	defer migrator.Close()
+
+	return migrator.Up()
 }
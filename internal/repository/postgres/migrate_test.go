@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/migrations"
+)
+
+// testDSNEnv points to a database tests may write to, tests using database are skipped without it
+const testDSNEnv = "TEST_DATABASE_DSN"
+
+func TestMigrationsSequence(t *testing.T) {
+	versions, err := migrationVersions(migrations.FS)
+	require.NoError(t, err)
+
+	for i, version := range versions {
+		require.Equal(t, uint(i+1), version, "migrations must be numbered sequentially")
+
+		for _, direction := range []string{"up", "down"} {
+			files, err := fs.Glob(migrations.FS, fmt.Sprintf("%06d_*.%s.sql", version, direction))
+			require.NoError(t, err)
+			require.Len(t, files, 1, "migration %d must have a single %s file", version, direction)
+
+			if direction != "up" {
+				continue
+			}
+			data, err := fs.ReadFile(migrations.FS, files[0])
+			require.NoError(t, err)
+			sql := strings.ToUpper(string(data))
+			require.NotContains(t, sql, "DROP TABLE", "%s drops data", files[0])
+			require.NotContains(t, sql, "DELETE FROM", "%s drops data", files[0])
+		}
+	}
+}
+
+func TestMigrationsSurviveRestart(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	ctx := auth.WithTenant(context.Background(), fmt.Sprintf("migrate-test-%d", time.Now().UnixNano()))
+
+	repo, err := NewPostgres(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		repo, err := NewPostgres(dsn)
+		if err != nil {
+			return
+		}
+		defer repo.db.Close()
+		_ = repo.Delete(ctx, entity.CounterType, "restarts")
+		_ = repo.Delete(ctx, entity.GaugeType, "load")
+	})
+
+	require.NoError(t, repo.StoreCounter(ctx, "restarts", 5))
+	require.NoError(t, repo.StoreGauge(ctx, "load", 0.5))
+	require.NoError(t, repo.db.Close())
+
+	// every server start applies migrations again
+	for i := 0; i < 2; i++ {
+		repo, err = NewPostgres(dsn)
+		require.NoError(t, err)
+
+		counter, err := repo.Counter(ctx, "restarts")
+		require.NoError(t, err)
+		require.Equal(t, int64(5), counter)
+
+		gauge, err := repo.Gauge(ctx, "load")
+		require.NoError(t, err)
+		require.Equal(t, 0.5, gauge)
+
+		require.NoError(t, repo.db.Close())
+	}
+
+	migrator, err := NewMigrator(dsn)
+	require.NoError(t, err)
+	defer migrator.Close()
+
+	status, err := migrator.Status()
+	require.NoError(t, err)
+	require.False(t, status.Dirty)
+	require.Empty(t, status.Pending)
+	require.Equal(t, status.Latest, status.Version)
+
+	require.ErrorIs(t, migrator.Down(0), ErrMigrationSteps)
+}
@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+// batch statements write all metrics of a type at once, names must be unique within a batch
+// because a row can not be updated twice by a single INSERT ... ON CONFLICT
+const (
+	gaugeBatchQuery = `INSERT INTO gauges (tenant, name, value, updated_at)
+		SELECT $1, batch.name, batch.value, COALESCE(batch.updated_at, now())
+		FROM unnest($2::text[], $3::double precision[], $4::timestamptz[]) AS batch(name, value, updated_at)
+		ON CONFLICT (tenant, name) DO UPDATE SET value=excluded.value, updated_at=excluded.updated_at`
+	counterBatchQuery = `INSERT INTO counters (tenant, name, value, updated_at)
+		SELECT $1, batch.name, batch.value, COALESCE(batch.updated_at, now())
+		FROM unnest($2::text[], $3::bigint[], $4::timestamptz[]) AS batch(name, value, updated_at)
+		ON CONFLICT (tenant, name) DO UPDATE SET value=counters.value+excluded.value, updated_at=excluded.updated_at`
+)
+
+// PoolConfig sizes connection pool, zero values keep pgxpool defaults
+type PoolConfig struct {
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+}
+
+// Pool is a postgres repository on pgx native connection pool. Batches are aggregated
+// and written by a single statement per metric type, the other queries share the pool through database/sql.
+type Pool struct {
+	*Postgres
+	pool *pgxpool.Pool
+}
+
+// NewPool connects to database by url and applies pending migrations
+func NewPool(ctx context.Context, url string, cfg PoolConfig) (*Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	err = pool.Ping(ctx)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	err = migrateUp(url)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("can not apply migrations: %w", err)
+	}
+
+	return &Pool{
+		Postgres: &Postgres{
+			db:      stdlib.OpenDBFromPool(pool),
+			url:     url,
+			breaker: newBreaker(breakerThreshold, breakerCooldown),
+		},
+		pool: pool,
+	}, nil
+}
+
+// StoreBatch stores batch of metrics in a single transaction, counters of the same name are summed
+// and the last gauge value wins, kept update times are the latest ones
+func (p *Pool) StoreBatch(ctx context.Context, metrics []entity.MetricDTO) error {
+	batch := aggregateBatch(metrics)
+	if len(batch.gaugeNames) == 0 && len(batch.counterNames) == 0 {
+		return nil
+	}
+
+	tenant := auth.Tenant(ctx)
+	return p.exec(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+			if len(batch.gaugeNames) > 0 {
+				_, err := tx.Exec(ctx, gaugeBatchQuery, tenant, batch.gaugeNames, batch.gaugeValues, batch.gaugeUpdated)
+				if err != nil {
+					return err
+				}
+			}
+			if len(batch.counterNames) > 0 {
+				_, err := tx.Exec(ctx, counterBatchQuery, tenant, batch.counterNames, batch.counterValues, batch.counterUpdated)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Ping checks connection, it fails fast while database is considered down
+func (p *Pool) Ping() error {
+	return p.exec(context.Background(), p.pool.Ping)
+}
+
+// Close closes all connections of pool
+func (p *Pool) Close() {
+	p.db.Close()
+	p.pool.Close()
+}
+
+// aggregatedBatch keeps columns of batch statements
+type aggregatedBatch struct {
+	gaugeNames     []string
+	gaugeValues    []float64
+	gaugeUpdated   []pgtype.Timestamptz
+	counterNames   []string
+	counterValues  []int64
+	counterUpdated []pgtype.Timestamptz
+}
+
+// aggregateBatch merges metrics of the same name and sorts them by name,
+// so concurrent batches lock rows in the same order and do not deadlock
+func aggregateBatch(metrics []entity.MetricDTO) aggregatedBatch {
+	gauges := make(map[string]float64)
+	counters := make(map[string]int64)
+	updated := make(map[string]time.Time)
+	for _, m := range metrics {
+		switch {
+		case m.MetricType == entity.GaugeType && m.Gauge != nil:
+			gauges[m.Name] = *m.Gauge
+		case m.MetricType == entity.CounterType && m.Counter != nil:
+			counters[m.Name] += *m.Counter
+		default:
+			continue
+		}
+		key := m.MetricType + "/" + m.Name
+		if m.UpdatedAt.After(updated[key]) {
+			updated[key] = m.UpdatedAt
+		}
+	}
+
+	// zero update time is sent as NULL so database sets the current time
+	updateTime := func(metricType, name string) pgtype.Timestamptz {
+		at := updated[metricType+"/"+name]
+		return pgtype.Timestamptz{Time: at, Valid: !at.IsZero()}
+	}
+
+	var batch aggregatedBatch
+	batch.gaugeNames = sortedKeys(gauges)
+	batch.gaugeValues = make([]float64, len(batch.gaugeNames))
+	batch.gaugeUpdated = make([]pgtype.Timestamptz, len(batch.gaugeNames))
+	for i, name := range batch.gaugeNames {
+		batch.gaugeValues[i] = gauges[name]
+		batch.gaugeUpdated[i] = updateTime(entity.GaugeType, name)
+	}
+	batch.counterNames = sortedKeys(counters)
+	batch.counterValues = make([]int64, len(batch.counterNames))
+	batch.counterUpdated = make([]pgtype.Timestamptz, len(batch.counterNames))
+	for i, name := range batch.counterNames {
+		batch.counterValues[i] = counters[name]
+		batch.counterUpdated[i] = updateTime(entity.CounterType, name)
+	}
+
+	return batch
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
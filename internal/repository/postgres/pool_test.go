@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+func TestAggregateBatch(t *testing.T) {
+	counter := func(v int64) *int64 { return &v }
+	gauge := func(v float64) *float64 { return &v }
+
+	restored := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	batch := aggregateBatch([]entity.MetricDTO{
+		{Name: "requests", MetricType: entity.CounterType, Counter: counter(2), UpdatedAt: restored},
+		{Name: "load", MetricType: entity.GaugeType, Gauge: gauge(0.5)},
+		{Name: "errors", MetricType: entity.CounterType, Counter: counter(1)},
+		{Name: "requests", MetricType: entity.CounterType, Counter: counter(3)},
+		{Name: "load", MetricType: entity.GaugeType, Gauge: gauge(0.7)},
+		{Name: "broken", MetricType: entity.GaugeType},
+	})
+
+	require.Equal(t, []string{"load"}, batch.gaugeNames)
+	require.Equal(t, []float64{0.7}, batch.gaugeValues)
+	require.Equal(t, []string{"errors", "requests"}, batch.counterNames)
+	require.Equal(t, []int64{1, 5}, batch.counterValues)
+	require.Equal(t, []pgtype.Timestamptz{{}}, batch.gaugeUpdated, "update time is set by database")
+	require.Equal(t, []pgtype.Timestamptz{{}, {Time: restored, Valid: true}}, batch.counterUpdated)
+}
+
+func TestPoolStoreBatch(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	ctx := auth.WithTenant(context.Background(), fmt.Sprintf("pool-test-%d", time.Now().UnixNano()))
+
+	repo, err := NewPool(ctx, dsn, PoolConfig{MaxConns: 2})
+	require.NoError(t, err)
+	defer repo.Close()
+	t.Cleanup(func() {
+		_ = repo.Delete(ctx, entity.CounterType, "requests")
+		_ = repo.Delete(ctx, entity.GaugeType, "load")
+	})
+
+	delta := int64(2)
+	value := 0.5
+	batch := []entity.MetricDTO{
+		{Name: "requests", MetricType: entity.CounterType, Counter: &delta},
+		{Name: "requests", MetricType: entity.CounterType, Counter: &delta},
+		{Name: "load", MetricType: entity.GaugeType, Gauge: &value},
+	}
+	require.NoError(t, repo.StoreBatch(ctx, batch))
+	require.NoError(t, repo.StoreBatch(ctx, batch))
+
+	counter, err := repo.Counter(ctx, "requests")
+	require.NoError(t, err)
+	require.Equal(t, int64(8), counter)
+
+	gauge, err := repo.Gauge(ctx, "load")
+	require.NoError(t, err)
+	require.Equal(t, value, gauge)
+}
+
+// BenchmarkStoreBatch compares a statement per metric over database/sql with aggregated batch statements over pgxpool
+func BenchmarkStoreBatch(b *testing.B) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		b.Skipf("%s is not set", testDSNEnv)
+	}
+
+	ctx := context.Background()
+
+	sqlRepo, err := NewPostgres(dsn)
+	require.NoError(b, err)
+	defer sqlRepo.db.Close()
+
+	poolRepo, err := NewPool(ctx, dsn, PoolConfig{})
+	require.NoError(b, err)
+	defer poolRepo.Close()
+
+	repos := []struct {
+		name string
+		repo interface {
+			StoreBatch(ctx context.Context, metrics []entity.MetricDTO) error
+		}
+	}{
+		{name: "database_sql", repo: sqlRepo},
+		{name: "pgxpool", repo: poolRepo},
+	}
+
+	for _, size := range []int{10, 100, 1000} {
+		batch := benchmarkBatch(size)
+		for _, r := range repos {
+			tenantCtx := auth.WithTenant(ctx, fmt.Sprintf("bench-%s-%d", r.name, size))
+			b.Run(fmt.Sprintf("%s/%d", r.name, size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					err := r.repo.StoreBatch(tenantCtx, batch)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+
+			for _, m := range batch {
+				_ = sqlRepo.Delete(tenantCtx, m.MetricType, m.Name)
+			}
+		}
+	}
+}
+
+// benchmarkBatch returns size metrics, half gauges and half counters with unique names
+func benchmarkBatch(size int) []entity.MetricDTO {
+	batch := make([]entity.MetricDTO, 0, size)
+	for i := 0; i < size; i++ {
+		value := float64(i)
+		delta := int64(i)
+		if i%2 == 0 {
+			batch = append(batch, entity.MetricDTO{Name: fmt.Sprintf("gauge%d", i), MetricType: entity.GaugeType, Gauge: &value})
+			continue
+		}
+		batch = append(batch, entity.MetricDTO{Name: fmt.Sprintf("counter%d", i), MetricType: entity.CounterType, Counter: &delta})
+	}
+	return batch
+}
@@ -3,29 +3,27 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"time"
 
 	"github.com/arxon31/metrics-collector/pkg/logger"
 
+	"github.com/arxon31/metrics-collector/internal/auth"
 	"github.com/arxon31/metrics-collector/internal/entity"
 	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
 
 	_ "github.com/jackc/pgx/stdlib"
 )
 
-type migrationsUpper interface {
-	up(db *sql.DB)
-}
-
 type Postgres struct {
-	db  *sql.DB
-	url string
+	db      *sql.DB
+	url     string
+	breaker *breaker
 }
 
 const (
-	retryAttempts = 3
-	startSleep    = 1 * time.Second
+	gaugeQuery   = `INSERT INTO gauges (tenant, name, value, updated_at) VALUES ($1, $2, $3, COALESCE($4::timestamptz, now())) ON CONFLICT (tenant, name) DO UPDATE SET value=$3, updated_at=excluded.updated_at`
+	counterQuery = `INSERT INTO counters (tenant, name, value, updated_at) VALUES ($1, $2, $3, COALESCE($4::timestamptz, now())) ON CONFLICT (tenant, name) DO UPDATE SET value=counters.value+$3, updated_at=excluded.updated_at`
 )
 
 func NewPostgres(url string) (*Postgres, error) {
@@ -37,110 +35,110 @@ func NewPostgres(url string) (*Postgres, error) {
 
 	err = db.Ping()
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	migrationsUp(db)
+	err = migrateUp(url)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can not apply migrations: %w", err)
+	}
 
 	psql := &Postgres{
-		db:  db,
-		url: url,
+		db:      db,
+		url:     url,
+		breaker: newBreaker(breakerThreshold, breakerCooldown),
 	}
 
 	return psql, nil
 }
 
 func (s *Postgres) StoreBatch(ctx context.Context, metrics []entity.MetricDTO) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	gaugesQuery := `INSERT INTO gauges (name, value) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET value=$2`
-	countersQuery := `INSERT INTO counters (name, value) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET value=counters.value+$2`
-
-	for _, m := range metrics {
-		switch m.MetricType {
-		case entity.GaugeType:
-			_, err = tx.ExecContext(ctx, gaugesQuery, m.Name, *m.Gauge)
-			if err != nil {
-				return err
-			}
-		case entity.CounterType:
-			_, err = tx.ExecContext(ctx, countersQuery, m.Name, *m.Counter)
-			if err != nil {
-				return err
+	tenant := auth.Tenant(ctx)
+	return s.exec(ctx, func(ctx context.Context) error {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		for _, m := range metrics {
+			switch m.MetricType {
+			case entity.GaugeType:
+				_, err = tx.ExecContext(ctx, gaugeQuery, tenant, m.Name, *m.Gauge, updateTime(m))
+				if err != nil {
+					return err
+				}
+			case entity.CounterType:
+				_, err = tx.ExecContext(ctx, counterQuery, tenant, m.Name, *m.Counter, updateTime(m))
+				if err != nil {
+					return err
+				}
 			}
 		}
-	}
 
-	return tx.Commit()
+		return tx.Commit()
+	})
 }
 
-func (s *Postgres) StoreGauge(ctx context.Context, name string, value float64) error {
-	query := `INSERT INTO gauges (name, value) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET value=$2`
-	stmt, err := s.db.PrepareContext(ctx, query)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-
-	_, err = stmt.ExecContext(ctx, name, value)
-	if err != nil {
-		err = s.retryStore(retryAttempts, stmt, name, value)
-		if err != nil {
-			return err
-		}
+// updateTime returns update time kept by metric, nil lets database set the current time
+func updateTime(m entity.MetricDTO) any {
+	if m.UpdatedAt.IsZero() {
+		return nil
 	}
+	return m.UpdatedAt
+}
 
-	return nil
+func (s *Postgres) StoreGauge(ctx context.Context, name string, value float64) error {
+	tenant := auth.Tenant(ctx)
+	return s.exec(ctx, func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, gaugeQuery, tenant, name, value, nil)
+		return err
+	})
 }
 
 func (s *Postgres) StoreCounter(ctx context.Context, name string, value int64) error {
-	query := `INSERT INTO counters (name, value) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET value=counters.value+$2`
-	stmt, err := s.db.PrepareContext(ctx, query)
-	if err != nil {
+	tenant := auth.Tenant(ctx)
+	return s.exec(ctx, func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, counterQuery, tenant, name, value, nil)
 		return err
-	}
-	defer stmt.Close()
-
-	_, err = stmt.ExecContext(ctx, name, value)
-	if err != nil {
-		err = s.retryStore(retryAttempts, stmt, name, value)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	})
 }
 
 func (s *Postgres) Gauge(ctx context.Context, name string) (float64, error) {
-	query := `SELECT value FROM gauges WHERE name=$1;`
-	row := s.db.QueryRowContext(ctx, query, name)
+	query := `SELECT value FROM gauges WHERE tenant=$1 AND name=$2;`
+	row := s.db.QueryRowContext(ctx, query, auth.Tenant(ctx), name)
 
 	var val float64
 	err := row.Scan(&val)
-	if err != nil {
+	if errors.Is(err, sql.ErrNoRows) {
 		return 0, repoerr.ErrMetricNotFound
 	}
+	if err != nil {
+		return 0, err
+	}
 	return val, nil
 }
 func (s *Postgres) Counter(ctx context.Context, name string) (int64, error) {
-	query := `SELECT value FROM counters WHERE name=$1;`
-	row := s.db.QueryRowContext(ctx, query, name)
+	query := `SELECT value FROM counters WHERE tenant=$1 AND name=$2;`
+	row := s.db.QueryRowContext(ctx, query, auth.Tenant(ctx), name)
 
 	var val int64
 	err := row.Scan(&val)
-	if err != nil {
+	if errors.Is(err, sql.ErrNoRows) {
 		return 0, repoerr.ErrMetricNotFound
 	}
+	if err != nil {
+		return 0, err
+	}
 	return val, nil
 }
 func (s *Postgres) Metrics(ctx context.Context) ([]entity.MetricDTO, error) {
-	query := `SELECT (name, value) FROM gauges;`
-	rows, err := s.db.QueryContext(ctx, query)
+	tenant := auth.Tenant(ctx)
+
+	query := `SELECT name, value, updated_at FROM gauges WHERE tenant=$1;`
+	rows, err := s.db.QueryContext(ctx, query, tenant)
 	if err != nil {
 		return nil, err
 	}
@@ -150,7 +148,7 @@ func (s *Postgres) Metrics(ctx context.Context) ([]entity.MetricDTO, error) {
 	for rows.Next() {
 		var gaugeMetric = entity.MetricDTO{MetricType: entity.GaugeType}
 
-		err = rows.Scan(&gaugeMetric.Name, gaugeMetric.Gauge)
+		err = rows.Scan(&gaugeMetric.Name, &gaugeMetric.Gauge, &gaugeMetric.UpdatedAt)
 		if err != nil {
 			logger.Logger.Error(err)
 			continue
@@ -164,15 +162,15 @@ func (s *Postgres) Metrics(ctx context.Context) ([]entity.MetricDTO, error) {
 		logger.Logger.Error(err)
 	}
 
-	query = `SELECT (name, value) FROM counters;`
-	rows, err = s.db.QueryContext(ctx, query)
+	query = `SELECT name, value, updated_at FROM counters WHERE tenant=$1;`
+	rows, err = s.db.QueryContext(ctx, query, tenant)
 	if err != nil {
 		return nil, err
 	}
 
 	for rows.Next() {
 		var counterMetric = entity.MetricDTO{MetricType: entity.CounterType}
-		err = rows.Scan(&counterMetric.Name, &counterMetric.Counter)
+		err = rows.Scan(&counterMetric.Name, &counterMetric.Counter, &counterMetric.UpdatedAt)
 		if err != nil {
 			logger.Logger.Error(err)
 			continue
@@ -189,26 +187,191 @@ func (s *Postgres) Metrics(ctx context.Context) ([]entity.MetricDTO, error) {
 	return metrics, nil
 }
 
-func (s *Postgres) Ping() error {
-	err := s.db.Ping()
-	if err != nil {
+// Evict removes provided metrics unless they were updated after metric.UpdatedAt
+func (s *Postgres) Evict(ctx context.Context, metrics []entity.MetricDTO) error {
+	gaugesQuery := `DELETE FROM gauges WHERE tenant=$1 AND name=$2 AND updated_at<=$3`
+	countersQuery := `DELETE FROM counters WHERE tenant=$1 AND name=$2 AND updated_at<=$3`
+
+	tenant := auth.Tenant(ctx)
+	return s.exec(ctx, func(ctx context.Context) error {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		for _, m := range metrics {
+			switch m.MetricType {
+			case entity.GaugeType:
+				_, err = tx.ExecContext(ctx, gaugesQuery, tenant, m.Name, m.UpdatedAt)
+			case entity.CounterType:
+				_, err = tx.ExecContext(ctx, countersQuery, tenant, m.Name, m.UpdatedAt)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Commit()
+	})
+}
+
+// Delete removes metric
+func (s *Postgres) Delete(ctx context.Context, metricType, name string) error {
+	var query string
+	switch metricType {
+	case entity.GaugeType:
+		query = `DELETE FROM gauges WHERE tenant=$1 AND name=$2`
+	case entity.CounterType:
+		query = `DELETE FROM counters WHERE tenant=$1 AND name=$2`
+	default:
+		return repoerr.ErrMetricNotFound
+	}
+
+	return s.execAffected(ctx, query, auth.Tenant(ctx), name)
+}
+
+// ResetCounter sets counter value to zero
+func (s *Postgres) ResetCounter(ctx context.Context, name string) error {
+	query := `UPDATE counters SET value=0, updated_at=now() WHERE tenant=$1 AND name=$2`
+
+	return s.execAffected(ctx, query, auth.Tenant(ctx), name)
+}
+
+// StoreMetadata replaces metric metadata
+func (s *Postgres) StoreMetadata(ctx context.Context, metadata entity.Metadata) error {
+	query := `INSERT INTO metadata (tenant, name, type, unit, description, owner) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (tenant, name) DO UPDATE SET type=$3, unit=$4, description=$5, owner=$6`
+
+	tenant := auth.Tenant(ctx)
+	return s.exec(ctx, func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, query, tenant, metadata.Name, metadata.Type, metadata.Unit, metadata.Description, metadata.Owner)
 		return err
+	})
+}
+
+// Metadata returns metadata of all metrics
+func (s *Postgres) Metadata(ctx context.Context) ([]entity.Metadata, error) {
+	query := `SELECT name, type, unit, description, owner FROM metadata WHERE tenant=$1;`
+	rows, err := s.db.QueryContext(ctx, query, auth.Tenant(ctx))
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return nil
+	metadata := make([]entity.Metadata, 0)
+	for rows.Next() {
+		var md entity.Metadata
+		err = rows.Scan(&md.Name, &md.Type, &md.Unit, &md.Description, &md.Owner)
+		if err != nil {
+			return nil, err
+		}
+		metadata = append(metadata, md)
+	}
+
+	return metadata, rows.Err()
 }
 
-func (s *Postgres) retryStore(attempts int, stmt *sql.Stmt, name string, value any) (err error) {
-	sleep := startSleep
-	for i := 0; i < attempts; i++ {
-		if i > 0 {
-			time.Sleep(sleep)
-			sleep += 2 * time.Second
+// Tenants returns names of all tenants having metrics or metadata
+func (s *Postgres) Tenants(ctx context.Context) ([]string, error) {
+	query := `SELECT tenant FROM gauges UNION SELECT tenant FROM counters UNION SELECT tenant FROM metadata;`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tenants := make([]string, 0)
+	for rows.Next() {
+		var tenant string
+		if err = rows.Scan(&tenant); err != nil {
+			return nil, err
 		}
-		_, err = stmt.Exec(name, value)
-		if err == nil {
-			return nil
+		tenants = append(tenants, tenant)
+	}
+
+	return tenants, rows.Err()
+}
+
+// StoreToken saves api token
+func (s *Postgres) StoreToken(ctx context.Context, token entity.APIToken) error {
+	query := `INSERT INTO api_tokens (id, tenant, hash, created_at, revoked_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET revoked_at=$5`
+
+	return s.exec(ctx, func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, query, token.ID, token.Tenant, token.Hash, token.CreatedAt, token.RevokedAt)
+		return err
+	})
+}
+
+// Tokens returns all api tokens including revoked ones
+func (s *Postgres) Tokens(ctx context.Context) ([]entity.APIToken, error) {
+	query := `SELECT id, tenant, hash, created_at, revoked_at FROM api_tokens;`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]entity.APIToken, 0)
+	for rows.Next() {
+		var token entity.APIToken
+		err = rows.Scan(&token.ID, &token.Tenant, &token.Hash, &token.CreatedAt, &token.RevokedAt)
+		if err != nil {
+			return nil, err
 		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// RevokeToken marks api token as revoked
+func (s *Postgres) RevokeToken(ctx context.Context, id string) error {
+	query := `UPDATE api_tokens SET revoked_at=COALESCE(revoked_at, now()) WHERE id=$1`
+
+	err := s.execAffected(ctx, query, id)
+	if errors.Is(err, repoerr.ErrMetricNotFound) {
+		return entity.ErrTokenNotFound
 	}
-	return fmt.Errorf("after %d attempts, error: %s", attempts, err)
+	return err
+}
+
+// execAffected runs write query with exec, repoerr.ErrMetricNotFound is returned if no rows were affected
+func (s *Postgres) execAffected(ctx context.Context, query string, args ...any) error {
+	var affected int64
+	err := s.exec(ctx, func(ctx context.Context) error {
+		res, err := s.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		affected, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repoerr.ErrMetricNotFound
+	}
+	return nil
+}
+
+// Ping checks connection, it fails fast while database is considered down
+func (s *Postgres) Ping() error {
+	return s.exec(context.Background(), func(ctx context.Context) error {
+		return s.db.PingContext(ctx)
+	})
+}
+
+// exec runs op retrying transient errors, op is not called while circuit breaker is open
+func (s *Postgres) exec(ctx context.Context, op func(ctx context.Context) error) error {
+	err := s.breaker.allow()
+	if err != nil {
+		return err
+	}
+
+	err = retry(ctx, op)
+	s.breaker.record(err)
+	return err
 }
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	retryAttempts = 3
+	startSleep    = 1 * time.Second
+	sleepStep     = 2 * time.Second
+)
+
+// transient SQLSTATE codes, the statement did not take effect and may be repeated
+const (
+	classConnectionException = "08"
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+	codeAdminShutdown        = "57P01"
+	codeCrashShutdown        = "57P02"
+	codeCannotConnectNow     = "57P03"
+)
+
+// sqlState returns SQLSTATE code of err, both pgx drivers in use expose it
+func sqlState(err error) string {
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		return pgErr.SQLState()
+	}
+	return ""
+}
+
+// isRetryable reports whether err is transient and repeating the statement can not apply it twice.
+// Network errors in the middle of a statement are not retried, a counter may have been already increased.
+func isRetryable(err error) bool {
+	switch code := sqlState(err); {
+	case strings.HasPrefix(code, classConnectionException):
+		return true
+	case code == codeSerializationFailure, code == codeDeadlockDetected,
+		code == codeAdminShutdown, code == codeCrashShutdown, code == codeCannotConnectNow:
+		return true
+	}
+
+	return errors.Is(err, driver.ErrBadConn) || pgconn.SafeToRetry(err)
+}
+
+// isUnavailable reports whether err means database can not be reached, such errors open circuit breaker
+func isUnavailable(err error) bool {
+	switch code := sqlState(err); {
+	case strings.HasPrefix(code, classConnectionException):
+		return true
+	case code == codeAdminShutdown, code == codeCrashShutdown, code == codeCannotConnectNow:
+		return true
+	case code != "":
+		return false
+	}
+
+	var netErr net.Error
+	var connectErr *pgconn.ConnectError
+	return errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr) || errors.As(err, &connectErr)
+}
+
+// retry runs op until it succeeds, fails with not retryable error or attempts are exhausted,
+// backoff is interrupted by ctx
+func retry(ctx context.Context, op func(ctx context.Context) error) error {
+	sleep := startSleep
+
+	var err error
+	for i := 0; i < retryAttempts; i++ {
+		if i > 0 {
+			timer := time.NewTimer(sleep)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("%w, last error: %w", ctx.Err(), err)
+			case <-timer.C:
+			}
+			sleep += sleepStep
+		}
+
+		err = op(ctx)
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("after %d attempts, error: %w", retryAttempts, err)
+}
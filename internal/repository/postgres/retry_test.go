@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		retryable   bool
+		unavailable bool
+	}{
+		{"connection_failure", &pgconn.PgError{Code: "08006"}, true, true},
+		{"serialization_failure", &pgconn.PgError{Code: "40001"}, true, false},
+		{"deadlock", fmt.Errorf("store: %w", &pgconn.PgError{Code: "40P01"}), true, false},
+		{"cannot_connect_now", &pgconn.PgError{Code: "57P03"}, true, true},
+		{"unique_violation", &pgconn.PgError{Code: "23505"}, false, false},
+		{"undefined_table", &pgconn.PgError{Code: "42P01"}, false, false},
+		{"bad_conn", driver.ErrBadConn, true, true},
+		{"network", &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, false, true},
+		{"canceled", context.Canceled, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.retryable, isRetryable(tt.err))
+			require.Equal(t, tt.unavailable, isUnavailable(tt.err))
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("does_not_retry_permanent_error", func(t *testing.T) {
+		calls := 0
+		err := retry(context.Background(), func(ctx context.Context) error {
+			calls++
+			return &pgconn.PgError{Code: "23505"}
+		})
+		require.Error(t, err)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("backoff_is_interrupted_by_context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		calls := 0
+		start := time.Now()
+		err := retry(ctx, func(ctx context.Context) error {
+			calls++
+			return &pgconn.PgError{Code: "40P01"}
+		})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, 1, calls)
+		require.Less(t, time.Since(start), startSleep)
+	})
+}
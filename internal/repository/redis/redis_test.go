@@ -0,0 +1,138 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+)
+
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	repo, err := NewRedis(context.Background(), "redis://"+server.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+
+	return repo, server
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	acme := auth.WithTenant(ctx, "acme")
+
+	t.Run("replicas_share_state", func(t *testing.T) {
+		repo, server := newTestRedis(t)
+		replica, err := NewRedis(ctx, "redis://"+server.Addr())
+		require.NoError(t, err)
+		defer replica.Close()
+
+		require.NoError(t, repo.StoreGauge(ctx, "load", 0.5))
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 2))
+		require.NoError(t, replica.StoreCounter(ctx, "requests", 3))
+		require.NoError(t, repo.StoreGauge(acme, "temp", 36.6))
+		require.NoError(t, repo.StoreMetadata(acme, entity.Metadata{Name: "temp", Unit: "celsius"}))
+
+		gauge, err := replica.Gauge(ctx, "load")
+		require.NoError(t, err)
+		require.Equal(t, 0.5, gauge)
+
+		counter, err := replica.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Equal(t, int64(5), counter)
+
+		_, err = replica.Gauge(ctx, "temp")
+		require.ErrorIs(t, err, repoerr.ErrMetricNotFound)
+
+		tenants, err := replica.Tenants(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{auth.DefaultTenant, "acme"}, tenants)
+
+		metadata, err := replica.Metadata(acme)
+		require.NoError(t, err)
+		require.Equal(t, []entity.Metadata{{Name: "temp", Unit: "celsius"}}, metadata)
+	})
+
+	t.Run("store_batch", func(t *testing.T) {
+		repo, _ := newTestRedis(t)
+
+		delta := int64(2)
+		value := 1.5
+		require.NoError(t, repo.StoreBatch(ctx, []entity.MetricDTO{
+			{Name: "requests", MetricType: entity.CounterType, Counter: &delta},
+			{Name: "requests", MetricType: entity.CounterType, Counter: &delta},
+			{Name: "load", MetricType: entity.GaugeType, Gauge: &value},
+		}))
+
+		metrics, err := repo.Metrics(ctx)
+		require.NoError(t, err)
+		require.Len(t, metrics, 2)
+		for _, m := range metrics {
+			require.False(t, m.UpdatedAt.IsZero())
+			switch m.MetricType {
+			case entity.CounterType:
+				require.Equal(t, int64(4), *m.Counter)
+			case entity.GaugeType:
+				require.Equal(t, value, *m.Gauge)
+			}
+		}
+	})
+
+	t.Run("delete_reset_and_evict", func(t *testing.T) {
+		repo, _ := newTestRedis(t)
+
+		require.ErrorIs(t, repo.Delete(ctx, entity.GaugeType, "missing"), repoerr.ErrMetricNotFound)
+		require.ErrorIs(t, repo.ResetCounter(ctx, "missing"), repoerr.ErrMetricNotFound)
+
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 7))
+		require.NoError(t, repo.ResetCounter(ctx, "requests"))
+		counter, err := repo.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Zero(t, counter)
+
+		require.NoError(t, repo.StoreGauge(ctx, "stale", 1))
+		before := time.Now()
+		require.NoError(t, repo.StoreGauge(ctx, "fresh", 2))
+		require.NoError(t, repo.Evict(ctx, []entity.MetricDTO{
+			{Name: "stale", MetricType: entity.GaugeType, UpdatedAt: before},
+			{Name: "fresh", MetricType: entity.GaugeType, UpdatedAt: before},
+		}))
+		_, err = repo.Gauge(ctx, "stale")
+		require.ErrorIs(t, err, repoerr.ErrMetricNotFound)
+		_, err = repo.Gauge(ctx, "fresh")
+		require.NoError(t, err)
+
+		require.NoError(t, repo.Delete(ctx, entity.GaugeType, "fresh"))
+		require.ErrorIs(t, repo.Delete(ctx, entity.GaugeType, "fresh"), repoerr.ErrMetricNotFound)
+	})
+
+	t.Run("tokens", func(t *testing.T) {
+		repo, _ := newTestRedis(t)
+
+		require.ErrorIs(t, repo.RevokeToken(ctx, "missing"), entity.ErrTokenNotFound)
+		require.NoError(t, repo.StoreToken(ctx, entity.APIToken{ID: "t1", Tenant: "acme", Hash: "h"}))
+		require.NoError(t, repo.RevokeToken(ctx, "t1"))
+		require.NoError(t, repo.RevokeToken(ctx, "t1"))
+
+		tokens, err := repo.Tokens(ctx)
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		require.Equal(t, "h", tokens[0].Hash)
+		require.NotNil(t, tokens[0].RevokedAt)
+	})
+
+	t.Run("ping", func(t *testing.T) {
+		repo, server := newTestRedis(t)
+
+		require.NoError(t, repo.Ping())
+		server.Close()
+		require.Error(t, repo.Ping())
+	})
+}
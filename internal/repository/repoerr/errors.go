@@ -4,4 +4,6 @@ import "errors"
 
 var (
 	ErrMetricNotFound = errors.New("metric not found")
+	// ErrUnavailable is returned without touching storage while it is considered down
+	ErrUnavailable = errors.New("storage is unavailable")
 )
@@ -3,10 +3,15 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/bolt"
 	"github.com/arxon31/metrics-collector/internal/repository/memory"
 	"github.com/arxon31/metrics-collector/internal/repository/postgres"
+	"github.com/arxon31/metrics-collector/internal/repository/redis"
 )
 
 // Repository provides access to metrics
@@ -21,16 +26,79 @@ type Repository interface {
 	Counter(ctx context.Context, name string) (int64, error)
 	// Metrics returns all metrics values
 	Metrics(ctx context.Context) ([]entity.MetricDTO, error)
-	// StoreBatch stores batch of metrics
+	// StoreBatch stores batch of metrics, metric.UpdatedAt is kept as update time if it is set
 	StoreBatch(ctx context.Context, metrics []entity.MetricDTO) error
+	// Evict removes metrics which were not updated since metric.UpdatedAt
+	Evict(ctx context.Context, metrics []entity.MetricDTO) error
+	// Delete removes metric
+	Delete(ctx context.Context, metricType, name string) error
+	// ResetCounter sets counter value to zero
+	ResetCounter(ctx context.Context, name string) error
+	// StoreMetadata replaces metric metadata
+	StoreMetadata(ctx context.Context, metadata entity.Metadata) error
+	// Metadata returns metadata of all metrics
+	Metadata(ctx context.Context) ([]entity.Metadata, error)
+	// Tenants returns names of all tenants having metrics
+	Tenants(ctx context.Context) ([]string, error)
+	// StoreToken saves api token
+	StoreToken(ctx context.Context, token entity.APIToken) error
+	// Tokens returns all api tokens
+	Tokens(ctx context.Context) ([]entity.APIToken, error)
+	// RevokeToken marks api token as revoked
+	RevokeToken(ctx context.Context, id string) error
 	// Ping checks connection
 	Ping() error
 }
 
-func New(url string) (Repository, error) {
-	if url == "" {
+// Storage backends selected by scheme of storage DSN
+const (
+	BackendMemory   = "memory"
+	BackendFile     = "file"
+	BackendPostgres = "postgres"
+	BackendRedis    = "redis"
+)
+
+var ErrStorageScheme = errors.New("unsupported storage scheme")
+
+// Backend returns storage backend of dsn: empty dsn and memory:// are in-memory, file:///path is
+// an embedded database file, redis:// and rediss:// are redis, postgres:// and postgresql:// URLs
+// or key=value connection strings are postgres
+func Backend(dsn string) (string, error) {
+	scheme, _, found := strings.Cut(dsn, "://")
+	switch {
+	case dsn == "", scheme == BackendMemory:
+		return BackendMemory, nil
+	case !found:
+		return BackendPostgres, nil
+	case scheme == BackendFile:
+		return BackendFile, nil
+	case scheme == "postgres", scheme == "postgresql":
+		return BackendPostgres, nil
+	case scheme == BackendRedis, scheme == "rediss":
+		return BackendRedis, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrStorageScheme, scheme)
+}
+
+// New creates repository for storage dsn, see Backend, pool sizes postgres connection pool
+func New(ctx context.Context, dsn string, pool postgres.PoolConfig) (Repository, error) {
+	backend, err := Backend(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch backend {
+	case BackendFile:
+		path := strings.TrimPrefix(dsn, BackendFile+"://")
+		if path == "" {
+			return nil, fmt.Errorf("%w: file path is empty", ErrStorageScheme)
+		}
+		return bolt.NewBolt(path)
+	case BackendPostgres:
+		return postgres.NewPool(ctx, dsn, pool)
+	case BackendRedis:
+		return redis.NewRedis(ctx, dsn)
+	default:
 		return memory.NewMapStorage(), nil
-	} else {
-		return postgres.NewPostgres(url)
 	}
 }
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend(t *testing.T) {
+	tests := []struct {
+		dsn     string
+		backend string
+		err     error
+	}{
+		{"", BackendMemory, nil},
+		{"memory://", BackendMemory, nil},
+		{"file:///var/lib/metrics.db", BackendFile, nil},
+		{"postgres://user@localhost/metrics", BackendPostgres, nil},
+		{"postgresql://user@localhost/metrics", BackendPostgres, nil},
+		{"host=localhost user=metrics", BackendPostgres, nil},
+		{"redis://localhost:6379/0", BackendRedis, nil},
+		{"rediss://localhost:6380", BackendRedis, nil},
+		{"mysql://localhost", "", ErrStorageScheme},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			backend, err := Backend(tt.dsn)
+			require.ErrorIs(t, err, tt.err)
+			require.Equal(t, tt.backend, backend)
+		})
+	}
+}
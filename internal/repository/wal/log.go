@@ -0,0 +1,425 @@
+// Package wal provides append-only write-ahead log of in-memory repository writes,
+// so writes made after the last dump survive a crash.
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arxon31/metrics-collector/pkg/logger"
+)
+
+// Fsync policies
+const (
+	// FsyncAlways syncs every record before write is acknowledged
+	FsyncAlways = "always"
+	// FsyncInterval syncs once per SyncInterval, a crash loses at most the last interval
+	FsyncInterval = "interval"
+	// FsyncNever leaves syncing to operating system
+	FsyncNever = "never"
+)
+
+const (
+	segmentSuffix = ".wal"
+	headerSize    = 8
+	// records larger than that are considered corrupt length
+	maxRecordSize = 64 << 20
+
+	DefaultSegmentSize  = 64 << 20
+	DefaultSyncInterval = time.Second
+)
+
+var (
+	ErrFsyncPolicy = errors.New("wal fsync policy must be always, interval or never")
+	ErrCorrupt     = errors.New("wal segment is corrupt")
+	ErrClosed      = errors.New("wal is closed")
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Options configures log, zero values mean defaults
+type Options struct {
+	Fsync        string
+	SegmentSize  int64
+	SyncInterval time.Duration
+}
+
+// Log writes records to numbered segment files, a new segment is started on open,
+// when current one exceeds SegmentSize and on Rotate.
+type Log struct {
+	dir  string
+	opts Options
+
+	mu      sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	seq     uint64
+	size    int64
+	dirty   bool
+	closed  bool
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// Open opens log in dir creating it if needed, existing segments are kept for Replay
+func Open(dir string, opts Options) (*Log, error) {
+	if opts.Fsync == "" {
+		opts.Fsync = FsyncInterval
+	}
+	if opts.Fsync != FsyncAlways && opts.Fsync != FsyncInterval && opts.Fsync != FsyncNever {
+		return nil, fmt.Errorf("%q: %w", opts.Fsync, ErrFsyncPolicy)
+	}
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = DefaultSegmentSize
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = DefaultSyncInterval
+	}
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("can not create wal directory: %w", err)
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{dir: dir, opts: opts}
+
+	// writes never continue a segment from previous run, its tail may be torn
+	next := uint64(1)
+	if len(segments) > 0 {
+		next = segments[len(segments)-1] + 1
+	}
+	err = l.openSegment(next)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Fsync == FsyncInterval {
+		l.stop = make(chan struct{})
+		l.stopped = make(chan struct{})
+		go l.syncLoop()
+	}
+
+	return l, nil
+}
+
+// Append writes record, with FsyncAlways it returns after record is on disk
+func (l *Log) Append(payload []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+
+	var header [headerSize]byte
+	binary.LittleEndian.PutUint32(header[:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[4:], crc32.Checksum(payload, crcTable))
+
+	_, err := l.writer.Write(header[:])
+	if err != nil {
+		return err
+	}
+	_, err = l.writer.Write(payload)
+	if err != nil {
+		return err
+	}
+	l.size += int64(headerSize + len(payload))
+	l.dirty = true
+
+	switch l.opts.Fsync {
+	case FsyncAlways:
+		err = l.sync()
+	case FsyncNever:
+		// buffer is flushed so a crash of the process, not the host, loses nothing
+		err = l.writer.Flush()
+	}
+	if err != nil {
+		return err
+	}
+
+	if l.size >= l.opts.SegmentSize {
+		return l.rotate()
+	}
+
+	return nil
+}
+
+// Rotate starts a new segment and returns its sequence number,
+// records appended before the call are in segments with lower numbers
+func (l *Log) Rotate() (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return 0, ErrClosed
+	}
+
+	err := l.rotate()
+	return l.seq, err
+}
+
+// Truncate removes segments with sequence numbers lower than seq, current segment is never removed
+func (l *Log) Truncate(seq uint64) error {
+	l.mu.Lock()
+	current := l.seq
+	l.mu.Unlock()
+
+	segments, err := listSegments(l.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range segments {
+		if s >= seq || s >= current {
+			break
+		}
+		err = os.Remove(l.segmentPath(s))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Replay calls apply for every record of segments starting from seq written before Open.
+// Torn record at the end of a segment is a crash during write, segment is truncated before it.
+func (l *Log) Replay(from uint64, apply func(payload []byte) error) error {
+	l.mu.Lock()
+	current := l.seq
+	l.mu.Unlock()
+
+	segments, err := listSegments(l.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range segments {
+		if s < from || s >= current {
+			continue
+		}
+		err = l.replaySegment(s, apply)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close flushes and syncs current segment
+func (l *Log) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	err := l.sync()
+	closeErr := l.file.Close()
+	l.mu.Unlock()
+
+	if l.stop != nil {
+		close(l.stop)
+		<-l.stopped
+	}
+
+	return errors.Join(err, closeErr)
+}
+
+func (l *Log) replaySegment(seq uint64, apply func(payload []byte) error) error {
+	path := l.segmentPath(seq)
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+
+	for {
+		payload, err := readRecord(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, ErrCorrupt) {
+			if !isTail(file, offset) {
+				return fmt.Errorf("%s at offset %d: %w", path, offset, ErrCorrupt)
+			}
+			logger.Logger.Warnf("wal: dropping torn record at %s offset %d", path, offset)
+			return file.Truncate(offset)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = apply(payload)
+		if err != nil {
+			return fmt.Errorf("%s at offset %d: %w", path, offset, err)
+		}
+		offset += int64(headerSize + len(payload))
+	}
+}
+
+// isTail reports whether a broken record is the last thing in the file,
+// corruption followed by valid data is not a torn write
+func isTail(file *os.File, offset int64) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	// a torn write leaves at most one partially written record
+	rest := info.Size() - offset
+	if rest <= headerSize {
+		return true
+	}
+
+	var header [headerSize]byte
+	_, err = file.ReadAt(header[:], offset)
+	if err != nil {
+		return false
+	}
+	length := int64(binary.LittleEndian.Uint32(header[:4]))
+	return rest <= headerSize+length
+}
+
+func readRecord(reader *bufio.Reader) ([]byte, error) {
+	var header [headerSize]byte
+	_, err := io.ReadFull(reader, header[:])
+	if err != nil {
+		return nil, err
+	}
+
+	length := binary.LittleEndian.Uint32(header[:4])
+	if length > maxRecordSize {
+		return nil, ErrCorrupt
+	}
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(reader, payload)
+	if errors.Is(err, io.EOF) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:]) {
+		return nil, ErrCorrupt
+	}
+
+	return payload, nil
+}
+
+// rotate must be called under lock
+func (l *Log) rotate() error {
+	err := l.sync()
+	if err != nil {
+		return err
+	}
+	err = l.file.Close()
+	if err != nil {
+		return err
+	}
+	return l.openSegment(l.seq + 1)
+}
+
+// openSegment must be called under lock or before log is shared
+func (l *Log) openSegment(seq uint64) error {
+	file, err := os.OpenFile(l.segmentPath(seq), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("can not create wal segment: %w", err)
+	}
+
+	l.file = file
+	l.writer = bufio.NewWriter(file)
+	l.seq = seq
+	l.size = 0
+	return nil
+}
+
+// sync must be called under lock
+func (l *Log) sync() error {
+	if !l.dirty {
+		return nil
+	}
+	err := l.writer.Flush()
+	if err != nil {
+		return err
+	}
+	err = l.file.Sync()
+	if err != nil {
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+func (l *Log) syncLoop() {
+	defer close(l.stopped)
+
+	ticker := time.NewTicker(l.opts.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			var err error
+			if !l.closed {
+				err = l.sync()
+			}
+			l.mu.Unlock()
+			if err != nil {
+				logger.Logger.Errorln("wal: can not sync:", err)
+			}
+		}
+	}
+}
+
+func (l *Log) segmentPath(seq uint64) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%020d%s", seq, segmentSuffix))
+}
+
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]uint64, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, seq)
+	}
+
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return segments, nil
+}
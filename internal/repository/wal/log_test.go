@@ -0,0 +1,122 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func replayAll(t *testing.T, l *Log, from uint64) []string {
+	t.Helper()
+
+	var records []string
+	err := l.Replay(from, func(payload []byte) error {
+		records = append(records, string(payload))
+		return nil
+	})
+	require.NoError(t, err)
+	return records
+}
+
+func TestLog(t *testing.T) {
+	t.Run("replay_after_reopen", func(t *testing.T) {
+		dir := t.TempDir()
+
+		l, err := Open(dir, Options{Fsync: FsyncAlways})
+		require.NoError(t, err)
+		require.NoError(t, l.Append([]byte("a")))
+		require.NoError(t, l.Append([]byte("b")))
+		require.NoError(t, l.Close())
+
+		l, err = Open(dir, Options{})
+		require.NoError(t, err)
+		defer l.Close()
+		require.NoError(t, l.Append([]byte("c")))
+
+		require.Equal(t, []string{"a", "b"}, replayAll(t, l, 0), "records of current run are not replayed")
+	})
+
+	t.Run("torn_tail", func(t *testing.T) {
+		dir := t.TempDir()
+
+		l, err := Open(dir, Options{Fsync: FsyncNever})
+		require.NoError(t, err)
+		require.NoError(t, l.Append([]byte("first")))
+		require.NoError(t, l.Append([]byte("second")))
+		path := l.segmentPath(l.seq)
+		require.NoError(t, l.Close())
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.NoError(t, os.Truncate(path, info.Size()-3))
+
+		l, err = Open(dir, Options{})
+		require.NoError(t, err)
+		defer l.Close()
+
+		require.Equal(t, []string{"first"}, replayAll(t, l, 0))
+
+		info, err = os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, int64(headerSize+len("first")), info.Size(), "torn record is cut off")
+	})
+
+	t.Run("corrupt_record_before_valid_one", func(t *testing.T) {
+		dir := t.TempDir()
+
+		l, err := Open(dir, Options{Fsync: FsyncNever})
+		require.NoError(t, err)
+		require.NoError(t, l.Append([]byte("first")))
+		require.NoError(t, l.Append([]byte("second")))
+		path := l.segmentPath(l.seq)
+		require.NoError(t, l.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		data[headerSize] ^= 0xff
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+
+		l, err = Open(dir, Options{})
+		require.NoError(t, err)
+		defer l.Close()
+
+		err = l.Replay(0, func([]byte) error { return nil })
+		require.ErrorIs(t, err, ErrCorrupt)
+	})
+
+	t.Run("rotate_and_truncate", func(t *testing.T) {
+		dir := t.TempDir()
+
+		l, err := Open(dir, Options{Fsync: FsyncNever, SegmentSize: 20})
+		require.NoError(t, err)
+		require.NoError(t, l.Append([]byte("0123456789abcdef")), "segment is rotated when full")
+		require.NoError(t, l.Append([]byte("b")))
+
+		seq, err := l.Rotate()
+		require.NoError(t, err)
+		require.NoError(t, l.Append([]byte("c")))
+		require.NoError(t, l.Close())
+
+		segments, err := listSegments(dir)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{1, 2, 3}, segments)
+		require.Equal(t, uint64(3), seq)
+
+		l, err = Open(dir, Options{})
+		require.NoError(t, err)
+		defer l.Close()
+
+		require.Equal(t, []string{"c"}, replayAll(t, l, seq))
+
+		require.NoError(t, l.Truncate(seq))
+		segments, err = listSegments(dir)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{3, 4}, segments)
+	})
+
+	t.Run("unknown_fsync_policy", func(t *testing.T) {
+		_, err := Open(t.TempDir(), Options{Fsync: "sometimes"})
+		require.ErrorIs(t, err, ErrFsyncPolicy)
+	})
+}
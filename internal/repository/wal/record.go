@@ -0,0 +1,34 @@
+package wal
+
+import (
+	"time"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+// operations of logged records
+const (
+	opGauge    = "gauge"
+	opCounter  = "counter"
+	opBatch    = "batch"
+	opDelete   = "delete"
+	opReset    = "reset"
+	opMetadata = "metadata"
+	opToken    = "token"
+	opRevoke   = "revoke"
+)
+
+// record is a single logged repository write
+type record struct {
+	Op       string               `json:"op"`
+	Tenant   string               `json:"tenant,omitempty"`
+	Name     string               `json:"name,omitempty"`
+	Type     string               `json:"type,omitempty"`
+	Gauge    float64              `json:"gauge,omitempty"`
+	Counter  int64                `json:"counter,omitempty"`
+	Metrics  entity.StoredMetrics `json:"metrics,omitempty"`
+	Metadata *entity.Metadata     `json:"metadata,omitempty"`
+	Token    *entity.APIToken     `json:"token,omitempty"`
+	// At is the time of write, replayed metrics keep it as their update time
+	At time.Time `json:"at"`
+}
@@ -0,0 +1,194 @@
+package wal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository"
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+)
+
+// Repository logs every write to WAL before applying it to wrapped repository.
+// Evict is not logged: metrics evicted after the last checkpoint come back on replay
+// with their logged update times, so the next eviction pass removes them again.
+type Repository struct {
+	repository.Repository
+	log *Log
+
+	// mu keeps log order equal to apply order and pauses writes during checkpoint
+	mu sync.Mutex
+}
+
+// NewRepository wraps repo, records are appended to log
+func NewRepository(repo repository.Repository, log *Log) *Repository {
+	return &Repository{
+		Repository: repo,
+		log:        log,
+	}
+}
+
+func (r *Repository) StoreGauge(ctx context.Context, name string, value float64) error {
+	rec := record{Op: opGauge, Tenant: auth.Tenant(ctx), Name: name, Gauge: value}
+	return r.write(rec, func() error {
+		return r.Repository.StoreGauge(ctx, name, value)
+	})
+}
+
+func (r *Repository) StoreCounter(ctx context.Context, name string, value int64) error {
+	rec := record{Op: opCounter, Tenant: auth.Tenant(ctx), Name: name, Counter: value}
+	return r.write(rec, func() error {
+		return r.Repository.StoreCounter(ctx, name, value)
+	})
+}
+
+func (r *Repository) StoreBatch(ctx context.Context, metrics []entity.MetricDTO) error {
+	rec := record{Op: opBatch, Tenant: auth.Tenant(ctx), Metrics: metrics}
+	return r.write(rec, func() error {
+		return r.Repository.StoreBatch(ctx, metrics)
+	})
+}
+
+func (r *Repository) Delete(ctx context.Context, metricType, name string) error {
+	rec := record{Op: opDelete, Tenant: auth.Tenant(ctx), Type: metricType, Name: name}
+	return r.write(rec, func() error {
+		return r.Repository.Delete(ctx, metricType, name)
+	})
+}
+
+func (r *Repository) ResetCounter(ctx context.Context, name string) error {
+	rec := record{Op: opReset, Tenant: auth.Tenant(ctx), Name: name}
+	return r.write(rec, func() error {
+		return r.Repository.ResetCounter(ctx, name)
+	})
+}
+
+func (r *Repository) StoreMetadata(ctx context.Context, metadata entity.Metadata) error {
+	rec := record{Op: opMetadata, Tenant: auth.Tenant(ctx), Metadata: &metadata}
+	return r.write(rec, func() error {
+		return r.Repository.StoreMetadata(ctx, metadata)
+	})
+}
+
+func (r *Repository) StoreToken(ctx context.Context, token entity.APIToken) error {
+	rec := record{Op: opToken, Token: &token}
+	return r.write(rec, func() error {
+		return r.Repository.StoreToken(ctx, token)
+	})
+}
+
+func (r *Repository) RevokeToken(ctx context.Context, id string) error {
+	rec := record{Op: opRevoke, Name: id}
+	return r.write(rec, func() error {
+		return r.Repository.RevokeToken(ctx, id)
+	})
+}
+
+// Checkpoint calls capture with writes paused and returns sequence number of the first WAL segment
+// not covered by captured state. Segments before it may be removed with Truncate once the state is saved.
+func (r *Repository) Checkpoint(capture func() error) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seq, err := r.log.Rotate()
+	if err != nil {
+		return 0, err
+	}
+
+	return seq, capture()
+}
+
+// Truncate removes WAL segments before seq
+func (r *Repository) Truncate(seq uint64) error {
+	return r.log.Truncate(seq)
+}
+
+// Replay applies records of WAL segments starting from seq to wrapped repository without logging them again
+func (r *Repository) Replay(ctx context.Context, from uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.log.Replay(from, func(payload []byte) error {
+		var rec record
+		err := json.Unmarshal(payload, &rec)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+
+		err = r.apply(auth.WithTenant(ctx, rec.Tenant), rec)
+		// writes are logged before applied, a write rejected by repository is rejected on replay as well
+		if errors.Is(err, repoerr.ErrMetricNotFound) || errors.Is(err, entity.ErrTokenNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+// Close closes WAL, wrapped repository is left open
+func (r *Repository) Close() error {
+	return r.log.Close()
+}
+
+func (r *Repository) write(rec record, apply func() error) error {
+	rec.At = time.Now()
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err = r.log.Append(payload)
+	if err != nil {
+		return fmt.Errorf("can not write to wal: %w", err)
+	}
+
+	return apply()
+}
+
+// apply replays rec, metrics are stored as updated at the time of write
+func (r *Repository) apply(ctx context.Context, rec record) error {
+	switch rec.Op {
+	case opGauge:
+		return r.Repository.StoreBatch(ctx, []entity.MetricDTO{{Name: rec.Name, MetricType: entity.GaugeType, Gauge: &rec.Gauge, UpdatedAt: rec.At}})
+	case opCounter:
+		return r.Repository.StoreBatch(ctx, []entity.MetricDTO{{Name: rec.Name, MetricType: entity.CounterType, Counter: &rec.Counter, UpdatedAt: rec.At}})
+	case opBatch:
+		metrics := make([]entity.MetricDTO, 0, len(rec.Metrics))
+		for _, m := range rec.Metrics {
+			m.UpdatedAt = m.UpdateTime(rec.At)
+			metrics = append(metrics, m)
+		}
+		return r.Repository.StoreBatch(ctx, metrics)
+	case opDelete:
+		return r.Repository.Delete(ctx, rec.Type, rec.Name)
+	case opReset:
+		err := r.Repository.ResetCounter(ctx, rec.Name)
+		if err != nil {
+			return err
+		}
+		// zero delta only moves update time of reset counter back to the time of write
+		var zero int64
+		return r.Repository.StoreBatch(ctx, []entity.MetricDTO{{Name: rec.Name, MetricType: entity.CounterType, Counter: &zero, UpdatedAt: rec.At}})
+	case opMetadata:
+		if rec.Metadata == nil {
+			return ErrCorrupt
+		}
+		return r.Repository.StoreMetadata(ctx, *rec.Metadata)
+	case opToken:
+		if rec.Token == nil {
+			return ErrCorrupt
+		}
+		return r.Repository.StoreToken(ctx, *rec.Token)
+	case opRevoke:
+		return r.Repository.RevokeToken(ctx, rec.Name)
+	default:
+		return fmt.Errorf("%w: unknown operation %q", ErrCorrupt, rec.Op)
+	}
+}
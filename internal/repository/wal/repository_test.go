@@ -0,0 +1,134 @@
+package wal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/memory"
+)
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	acme := auth.WithTenant(ctx, "acme")
+
+	t.Run("replay_restores_writes", func(t *testing.T) {
+		dir := t.TempDir()
+
+		l, err := Open(dir, Options{Fsync: FsyncAlways})
+		require.NoError(t, err)
+		repo := NewRepository(memory.NewMapStorage(), l)
+
+		delta := int64(3)
+		value := 1.5
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 2))
+		require.NoError(t, repo.StoreBatch(ctx, []entity.MetricDTO{
+			{Name: "requests", MetricType: entity.CounterType, Counter: &delta},
+			{Name: "load", MetricType: entity.GaugeType, Gauge: &value},
+		}))
+		require.NoError(t, repo.StoreGauge(acme, "temp", 36.6))
+		require.NoError(t, repo.StoreGauge(ctx, "stale", 1))
+		require.NoError(t, repo.Delete(ctx, entity.GaugeType, "stale"))
+		require.NoError(t, repo.StoreToken(ctx, entity.APIToken{ID: "t1", Tenant: "acme"}))
+		require.NoError(t, repo.RevokeToken(ctx, "t1"))
+		require.Error(t, repo.ResetCounter(ctx, "missing"))
+		require.NoError(t, repo.Close())
+
+		l, err = Open(dir, Options{})
+		require.NoError(t, err)
+		restored := memory.NewMapStorage()
+		repo = NewRepository(restored, l)
+		defer repo.Close()
+		require.NoError(t, repo.Replay(ctx, 0))
+
+		counter, err := restored.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Equal(t, int64(5), counter)
+
+		gauge, err := restored.Gauge(ctx, "load")
+		require.NoError(t, err)
+		require.Equal(t, value, gauge)
+
+		gauge, err = restored.Gauge(acme, "temp")
+		require.NoError(t, err)
+		require.Equal(t, 36.6, gauge)
+
+		_, err = restored.Gauge(ctx, "stale")
+		require.Error(t, err)
+
+		tokens, err := restored.Tokens(ctx)
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		require.NotNil(t, tokens[0].RevokedAt)
+	})
+
+	t.Run("replay_keeps_update_times", func(t *testing.T) {
+		dir := t.TempDir()
+
+		l, err := Open(dir, Options{Fsync: FsyncAlways})
+		require.NoError(t, err)
+		repo := NewRepository(memory.NewMapStorage(), l)
+
+		value := 1.5
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 2))
+		require.NoError(t, repo.StoreGauge(ctx, "temp", 36.6))
+		require.NoError(t, repo.StoreBatch(ctx, []entity.MetricDTO{{Name: "load", MetricType: entity.GaugeType, Gauge: &value}}))
+		require.NoError(t, repo.ResetCounter(ctx, "requests"))
+		require.NoError(t, repo.Close())
+		written := time.Now()
+		time.Sleep(10 * time.Millisecond)
+
+		l, err = Open(dir, Options{})
+		require.NoError(t, err)
+		restored := memory.NewMapStorage()
+		repo = NewRepository(restored, l)
+		defer repo.Close()
+		require.NoError(t, repo.Replay(ctx, 0))
+
+		metrics, err := restored.Metrics(ctx)
+		require.NoError(t, err)
+		require.Len(t, metrics, 3)
+		for _, m := range metrics {
+			require.False(t, m.UpdatedAt.After(written), "%s is updated on replay", m.Name)
+		}
+	})
+
+	t.Run("checkpoint_skips_captured_writes", func(t *testing.T) {
+		dir := t.TempDir()
+
+		l, err := Open(dir, Options{Fsync: FsyncAlways})
+		require.NoError(t, err)
+		inner := memory.NewMapStorage()
+		repo := NewRepository(inner, l)
+
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 2))
+
+		var captured int64
+		seq, err := repo.Checkpoint(func() error {
+			captured, err = inner.Counter(ctx, "requests")
+			return err
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), captured)
+
+		require.NoError(t, repo.StoreCounter(ctx, "requests", 3))
+		require.NoError(t, repo.Truncate(seq))
+		require.NoError(t, repo.Close())
+
+		l, err = Open(dir, Options{})
+		require.NoError(t, err)
+		restored := memory.NewMapStorage()
+		require.NoError(t, restored.StoreCounter(ctx, "requests", captured))
+		repo = NewRepository(restored, l)
+		defer repo.Close()
+		require.NoError(t, repo.Replay(ctx, seq))
+
+		counter, err := restored.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Equal(t, int64(5), counter)
+	})
+}
@@ -0,0 +1,49 @@
+// Package requestid keeps identifier of the request in context to correlate logs and error responses
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Header carries request ID, client provided value is kept if it is valid
+const Header = "X-Request-ID"
+
+const (
+	idBytes  = 8
+	maxIDLen = 64
+)
+
+type requestIDKey struct{}
+
+// With returns context carrying request ID
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// From returns request ID from context, empty if absent
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// New returns random hex request ID
+func New() string {
+	buf := make([]byte, idBytes)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Valid reports whether client provided request ID is safe to log and echo
+func Valid(id string) bool {
+	if id == "" || len(id) > maxIDLen {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
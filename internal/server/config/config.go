@@ -11,6 +11,7 @@ import (
 
 	"github.com/caarlos0/env/v10"
 
+	"github.com/arxon31/metrics-collector/internal/repository/wal"
 	"github.com/arxon31/metrics-collector/pkg/logger"
 )
 
@@ -20,14 +21,61 @@ var (
 	fileStoragePath = flag.String("f", "/tmp/metrics-db.json", "file storage path")
 	restore         = flag.Bool("r", true, "restore from file-db")
 	dbstring        = flag.String("d", "", "database connection string")
-	hashKey         = flag.String("k", "", "key for hash counting")
-	cryptoKeyPath   = flag.String("crypto-key", "", "key to decrypt all sending data")
+	storage         = flag.String("storage", "", "storage DSN: memory://, file:///path/metrics.db, redis://... or postgres://..., defaults to -d or memory")
+	dbMaxConns      = flag.Int("db-max-conns", 0, "max number of database connections, 0 means greater of 4 and number of CPUs")
+	dbMinConns      = flag.Int("db-min-conns", 0, "number of database connections kept open when idle")
+	dbConnLifetime  = flag.Int("db-max-conn-lifetime", 3600, "seconds after which a database connection is closed and reopened")
+	dbConnIdleTime  = flag.Int("db-max-conn-idle-time", 1800, "seconds after which an idle database connection is closed")
+	cacheFlush      = flag.Int("cache-flush-interval", 0, "seconds between flushes of write-back cache in front of persistent storage, 0 disables cache, use only with a single server instance and not with redis storage")
+	cacheMaxPending = flag.Int("cache-max-pending", 10000, "number of pending metrics flushing write-back cache before interval elapses")
+	hashKey         = flag.String("k", "", "key for hash counting, comma separated id:key list enables rotation, the first key signs responses")
+	cryptoKeyPath   = flag.String("crypto-key", "", "directory with payload decryption keys, private.pem is current and private.<key id>.pem are retired keys, empty disables decryption")
 	configFilePath  = flag.String("c", "", "config file path")
+	alertRulesPath  = flag.String("alert-rules", "", "alert rules YAML file path")
+	metricTTL       = flag.Int("metric-ttl", 0, "default metric TTL in seconds, 0 disables expiration")
+	ttlOverrides    = flag.String("metric-ttl-overrides", "", "comma separated pattern=duration metric TTL overrides")
+	adminToken      = flag.String("admin-token", "", "bearer token for admin endpoints, empty disables them")
+	auditLogPath    = flag.String("audit-log", "", "audit trail file path")
+	tenantQuota     = flag.Int("tenant-quota", 0, "max number of metrics per tenant, 0 means unlimited")
+	quotaOverrides  = flag.String("tenant-quota-overrides", "", "comma separated tenant=limit quota overrides")
+	authTokens      = flag.String("auth-tokens", "", "comma separated token=role static bearer tokens, roles are reader, writer and admin")
+	jwtSecret       = flag.String("jwt-secret", "", "HS256 secret to verify bearer JWT")
+	jwtPublicKey    = flag.String("jwt-public-key", "", "RS256 public key PEM file path to verify bearer JWT")
+	jwtIssuer       = flag.String("jwt-issuer", "", "required JWT issuer")
+	jwtAudience     = flag.String("jwt-audience", "", "required JWT audience")
+	rateLimitRPS    = flag.Float64("rate-limit-rps", 0, "requests per second allowed for a single client, 0 disables rate limiting")
+	rateLimitBurst  = flag.Int("rate-limit-burst", 0, "max burst of requests for a single client, defaults to rps")
+	maxBodySize     = flag.Int64("max-body-size", 10<<20, "max request body size in bytes both as sent and decompressed, 0 means unlimited")
+	maxRestoreSize  = flag.Int64("max-restore-size", 1<<30, "max size in bytes of snapshot uploaded to restore, 0 means unlimited")
+	maxBatchSize    = flag.Int("max-batch-size", 10000, "max number of metrics in a batch, 0 means unlimited")
+	hashSkew        = flag.Int("hash-skew", 300, "allowed difference in seconds between signature timestamp and server time")
+	hashAllowLegacy = flag.Bool("hash-allow-legacy", false, "accept and log deprecated body only signatures without timestamp and nonce")
+	hashStrict      = flag.Bool("hash-strict", false, "reject unsigned write requests when hash key is set")
+	tlsCert         = flag.String("tls-cert", "", "TLS certificate PEM file path, enables https, reloaded on change")
+	tlsKey          = flag.String("tls-key", "", "TLS private key PEM file path")
+	tlsClientCA     = flag.String("tls-client-ca", "", "CA bundle PEM file path to require and verify client certificates")
+	tlsMinVersion   = flag.String("tls-min-version", "1.2", "minimal TLS version, 1.2 or 1.3")
+	tlsCiphers      = flag.String("tls-ciphers", "", "comma separated TLS 1.2 cipher suite names, empty means Go defaults")
+	idempotencyTTL  = flag.Int("idempotency-ttl", 86400, "seconds to remember Idempotency-Key of batch updates, 0 disables idempotency")
+	idempotencyKeys = flag.Int("idempotency-max-keys", 100000, "max number of idempotency keys kept in memory, 0 means unlimited")
+	walDir          = flag.String("wal-dir", "", "write-ahead log directory of in-memory storage, empty disables it unless store interval is 0")
+	walFsync        = flag.String("wal-fsync", "interval", "write-ahead log fsync policy: always, interval or never")
+	walSegmentSize  = flag.Int64("wal-segment-size", 64<<20, "write-ahead log segment size in bytes")
+	snapCompression = flag.String("snapshot-compression", "none", "file storage snapshot compression: none, gzip or zstd")
+	snapKeep        = flag.Int("snapshot-keep", 2, "number of previous file storage snapshots kept to restore from if the newest one is broken")
 )
 
 const (
-	storeIntervalEnv = "STORE_INTERVAL"
-	restoreEnv       = "RESTORE"
+	storeIntervalEnv   = "STORE_INTERVAL"
+	metricTTLEnv       = "METRIC_TTL"
+	restoreEnv         = "RESTORE"
+	hashSkewEnv        = "HASH_SKEW"
+	hashAllowLegacyEnv = "HASH_ALLOW_LEGACY"
+	hashStrictEnv      = "HASH_STRICT"
+	idempotencyTTLEnv  = "IDEMPOTENCY_TTL"
+	dbConnLifetimeEnv  = "DB_MAX_CONN_LIFETIME"
+	dbConnIdleTimeEnv  = "DB_MAX_CONN_IDLE_TIME"
+	cacheFlushEnv      = "CACHE_FLUSH_INTERVAL"
 )
 
 type Config struct {
@@ -36,8 +84,47 @@ type Config struct {
 	FileStoragePath string `env:"FILE_STORAGE_PATH" ,json:"store_file"`
 	Restore         bool   `env:"RESTORE"`
 	DBString        string `env:"DATABASE_DSN" ,json:"database_dsn"`
+	Storage         string `env:"STORAGE" json:"storage"`
+	DBMaxConns      int32  `env:"DB_MAX_CONNS" json:"db_max_conns"`
+	DBMinConns      int32  `env:"DB_MIN_CONNS" json:"db_min_conns"`
+	DBConnLifetime  time.Duration
+	DBConnIdleTime  time.Duration
+	CacheFlush      time.Duration
+	CacheMaxPending int    `env:"CACHE_MAX_PENDING" json:"cache_max_pending"`
 	HashKey         string `env:"KEY" ,json:"hash_key"`
 	CryptoKey       string `env:"CRYPTO_KEY" ,json:"crypto_key"`
+	AlertRulesPath  string `env:"ALERT_RULES" json:"alert_rules"`
+	MetricTTL       time.Duration
+	TTLOverrides    string  `env:"METRIC_TTL_OVERRIDES" json:"metric_ttl_overrides"`
+	AdminToken      string  `env:"ADMIN_TOKEN" json:"admin_token"`
+	AuditLogPath    string  `env:"AUDIT_LOG" json:"audit_log"`
+	TenantQuota     int     `env:"TENANT_QUOTA" json:"tenant_quota"`
+	QuotaOverrides  string  `env:"TENANT_QUOTA_OVERRIDES" json:"tenant_quota_overrides"`
+	AuthTokens      string  `env:"AUTH_TOKENS" json:"auth_tokens"`
+	JWTSecret       string  `env:"JWT_SECRET" json:"jwt_secret"`
+	JWTPublicKey    string  `env:"JWT_PUBLIC_KEY" json:"jwt_public_key"`
+	JWTIssuer       string  `env:"JWT_ISSUER" json:"jwt_issuer"`
+	JWTAudience     string  `env:"JWT_AUDIENCE" json:"jwt_audience"`
+	RateLimitRPS    float64 `env:"RATE_LIMIT_RPS" json:"rate_limit_rps"`
+	RateLimitBurst  int     `env:"RATE_LIMIT_BURST" json:"rate_limit_burst"`
+	MaxBodySize     int64   `env:"MAX_BODY_SIZE" json:"max_body_size"`
+	MaxRestoreSize  int64   `env:"MAX_RESTORE_SIZE" json:"max_restore_size"`
+	MaxBatchSize    int     `env:"MAX_BATCH_SIZE" json:"max_batch_size"`
+	HashSkew        time.Duration
+	HashAllowLegacy bool
+	HashStrict      bool
+	TLSCert         string `env:"TLS_CERT" json:"tls_cert"`
+	TLSKey          string `env:"TLS_KEY" json:"tls_key"`
+	TLSClientCA     string `env:"TLS_CLIENT_CA" json:"tls_client_ca"`
+	TLSMinVersion   string `env:"TLS_MIN_VERSION" json:"tls_min_version"`
+	TLSCiphers      string `env:"TLS_CIPHERS" json:"tls_ciphers"`
+	IdempotencyTTL  time.Duration
+	IdempotencyKeys int    `env:"IDEMPOTENCY_MAX_KEYS" json:"idempotency_max_keys"`
+	WALDir          string `env:"WAL_DIR" json:"wal_dir"`
+	WALFsync        string `env:"WAL_FSYNC" json:"wal_fsync"`
+	WALSegmentSize  int64  `env:"WAL_SEGMENT_SIZE" json:"wal_segment_size"`
+	SnapCompression string `env:"SNAPSHOT_COMPRESSION" json:"snapshot_compression"`
+	SnapKeep        int    `env:"SNAPSHOT_KEEP" json:"snapshot_keep"`
 }
 
 // NewServerConfig creates new server config
@@ -69,6 +156,27 @@ func NewServerConfig() (*Config, error) {
 		config.DBString = *dbstring
 	}
 
+	if config.Storage == "" {
+		config.Storage = *storage
+	}
+
+	// database connection string is kept as a shortcut for postgres storage
+	if config.Storage == "" {
+		config.Storage = config.DBString
+	}
+
+	if config.CacheMaxPending == 0 {
+		config.CacheMaxPending = *cacheMaxPending
+	}
+
+	if config.DBMaxConns == 0 {
+		config.DBMaxConns = int32(*dbMaxConns)
+	}
+
+	if config.DBMinConns == 0 {
+		config.DBMinConns = int32(*dbMinConns)
+	}
+
 	if config.HashKey == "" {
 		config.HashKey = *hashKey
 	}
@@ -77,6 +185,114 @@ func NewServerConfig() (*Config, error) {
 		config.CryptoKey = *cryptoKeyPath
 	}
 
+	if config.AlertRulesPath == "" {
+		config.AlertRulesPath = *alertRulesPath
+	}
+
+	if config.TTLOverrides == "" {
+		config.TTLOverrides = *ttlOverrides
+	}
+
+	if config.AdminToken == "" {
+		config.AdminToken = *adminToken
+	}
+
+	if config.AuditLogPath == "" {
+		config.AuditLogPath = *auditLogPath
+	}
+
+	if config.TenantQuota == 0 {
+		config.TenantQuota = *tenantQuota
+	}
+
+	if config.QuotaOverrides == "" {
+		config.QuotaOverrides = *quotaOverrides
+	}
+
+	if config.AuthTokens == "" {
+		config.AuthTokens = *authTokens
+	}
+
+	if config.JWTSecret == "" {
+		config.JWTSecret = *jwtSecret
+	}
+
+	if config.JWTPublicKey == "" {
+		config.JWTPublicKey = *jwtPublicKey
+	}
+
+	if config.JWTIssuer == "" {
+		config.JWTIssuer = *jwtIssuer
+	}
+
+	if config.JWTAudience == "" {
+		config.JWTAudience = *jwtAudience
+	}
+
+	if config.RateLimitRPS == 0 {
+		config.RateLimitRPS = *rateLimitRPS
+	}
+
+	if config.RateLimitBurst == 0 {
+		config.RateLimitBurst = *rateLimitBurst
+	}
+
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = *maxBodySize
+	}
+
+	if config.MaxRestoreSize == 0 {
+		config.MaxRestoreSize = *maxRestoreSize
+	}
+
+	if config.MaxBatchSize == 0 {
+		config.MaxBatchSize = *maxBatchSize
+	}
+
+	if config.TLSCert == "" {
+		config.TLSCert = *tlsCert
+	}
+
+	if config.TLSKey == "" {
+		config.TLSKey = *tlsKey
+	}
+
+	if config.TLSClientCA == "" {
+		config.TLSClientCA = *tlsClientCA
+	}
+
+	if config.TLSMinVersion == "" {
+		config.TLSMinVersion = *tlsMinVersion
+	}
+
+	if config.TLSCiphers == "" {
+		config.TLSCiphers = *tlsCiphers
+	}
+
+	if config.IdempotencyKeys == 0 {
+		config.IdempotencyKeys = *idempotencyKeys
+	}
+
+	if config.WALDir == "" {
+		config.WALDir = *walDir
+	}
+
+	if config.WALFsync == "" {
+		config.WALFsync = *walFsync
+	}
+
+	if config.WALSegmentSize == 0 {
+		config.WALSegmentSize = *walSegmentSize
+	}
+
+	if config.SnapCompression == "" {
+		config.SnapCompression = *snapCompression
+	}
+
+	if config.SnapKeep == 0 {
+		config.SnapKeep = *snapKeep
+	}
+
 	config.Restore = *restore
 	restoreString, isRestoreExist := os.LookupEnv(restoreEnv)
 	if isRestoreExist {
@@ -97,6 +313,92 @@ func NewServerConfig() (*Config, error) {
 		config.StoreInterval = time.Duration(storeIntervalInt) * time.Second
 	}
 
+	// synchronous storing is served by write-ahead log synced on every write instead of dumping whole storage
+	if config.StoreInterval == 0 && config.WALDir == "" && config.FileStoragePath != "" {
+		config.WALDir = config.FileStoragePath + ".wal"
+		config.WALFsync = wal.FsyncAlways
+	}
+
+	config.MetricTTL = time.Duration(*metricTTL) * time.Second
+	metricTTLString, isMetricTTLExist := os.LookupEnv(metricTTLEnv)
+	if isMetricTTLExist {
+		metricTTLInt, err := strconv.Atoi(metricTTLString)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse metric ttl due to error: %v", err)
+		}
+		config.MetricTTL = time.Duration(metricTTLInt) * time.Second
+	}
+
+	config.HashSkew = time.Duration(*hashSkew) * time.Second
+	hashSkewString, isHashSkewExist := os.LookupEnv(hashSkewEnv)
+	if isHashSkewExist {
+		hashSkewInt, err := strconv.Atoi(hashSkewString)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse hash skew due to error: %v", err)
+		}
+		config.HashSkew = time.Duration(hashSkewInt) * time.Second
+	}
+
+	config.HashAllowLegacy = *hashAllowLegacy
+	hashAllowLegacyString, isHashAllowLegacyExist := os.LookupEnv(hashAllowLegacyEnv)
+	if isHashAllowLegacyExist {
+		hashAllowLegacyBool, err := strconv.ParseBool(hashAllowLegacyString)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse hash allow legacy due to error: %v", err)
+		}
+		config.HashAllowLegacy = hashAllowLegacyBool
+	}
+
+	config.HashStrict = *hashStrict
+	hashStrictString, isHashStrictExist := os.LookupEnv(hashStrictEnv)
+	if isHashStrictExist {
+		hashStrictBool, err := strconv.ParseBool(hashStrictString)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse hash strict due to error: %v", err)
+		}
+		config.HashStrict = hashStrictBool
+	}
+
+	config.IdempotencyTTL = time.Duration(*idempotencyTTL) * time.Second
+	idempotencyTTLString, isIdempotencyTTLExist := os.LookupEnv(idempotencyTTLEnv)
+	if isIdempotencyTTLExist {
+		idempotencyTTLInt, err := strconv.Atoi(idempotencyTTLString)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse idempotency ttl due to error: %v", err)
+		}
+		config.IdempotencyTTL = time.Duration(idempotencyTTLInt) * time.Second
+	}
+
+	config.DBConnLifetime = time.Duration(*dbConnLifetime) * time.Second
+	dbConnLifetimeString, isDBConnLifetimeExist := os.LookupEnv(dbConnLifetimeEnv)
+	if isDBConnLifetimeExist {
+		dbConnLifetimeInt, err := strconv.Atoi(dbConnLifetimeString)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse db max conn lifetime due to error: %v", err)
+		}
+		config.DBConnLifetime = time.Duration(dbConnLifetimeInt) * time.Second
+	}
+
+	config.DBConnIdleTime = time.Duration(*dbConnIdleTime) * time.Second
+	dbConnIdleTimeString, isDBConnIdleTimeExist := os.LookupEnv(dbConnIdleTimeEnv)
+	if isDBConnIdleTimeExist {
+		dbConnIdleTimeInt, err := strconv.Atoi(dbConnIdleTimeString)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse db max conn idle time due to error: %v", err)
+		}
+		config.DBConnIdleTime = time.Duration(dbConnIdleTimeInt) * time.Second
+	}
+
+	config.CacheFlush = time.Duration(*cacheFlush) * time.Second
+	cacheFlushString, isCacheFlushExist := os.LookupEnv(cacheFlushEnv)
+	if isCacheFlushExist {
+		cacheFlushInt, err := strconv.Atoi(cacheFlushString)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse cache flush interval due to error: %v", err)
+		}
+		config.CacheFlush = time.Duration(cacheFlushInt) * time.Second
+	}
+
 	return &config, nil
 }
 
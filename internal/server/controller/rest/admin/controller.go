@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+)
+
+const (
+	deleteMetricURL  = "/value/{type}/{name}"
+	deleteMetricsURL = "/value/"
+	resetCounterURL  = "/reset/counter/{name}"
+)
+
+//go:generate moq -out deleterService_moq_test.go . deleterService
+type deleterService interface {
+	DeleteMetric(ctx context.Context, metricType, name string) error
+	DeleteMetrics(ctx context.Context, selector entity.MetricSelector) ([]entity.MetricDTO, error)
+	ResetCounter(ctx context.Context, name string) error
+}
+
+type admin struct {
+	deleter deleterService
+}
+
+// NewController initializes a new admin controller.
+func NewController(deleter deleterService) *admin {
+	return &admin{
+		deleter: deleter,
+	}
+}
+
+// Register registers the admin endpoints on the provided chi Router.
+func (a *admin) Register(h chi.Router) {
+	h.Delete(deleteMetricURL, a.deleteMetric)
+	h.Delete(deleteMetricsURL, a.deleteMetrics)
+	h.Post(resetCounterURL, a.resetCounter)
+}
+
+func (a *admin) deleteMetric(w http.ResponseWriter, r *http.Request) {
+	metricType := chi.URLParam(r, "type")
+	name := chi.URLParam(r, "name")
+
+	if metricType != entity.GaugeType && metricType != entity.CounterType {
+		resterrs.Write(w, r, resterrs.ForMetric(name, resterrs.ErrUnexpectedType))
+		return
+	}
+
+	err := a.deleter.DeleteMetric(r.Context(), metricType, name)
+	if err != nil {
+		resterrs.Write(w, r, resterrs.ForMetric(name, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a *admin) deleteMetrics(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	selector := entity.MetricSelector{
+		Type:  query.Get("type"),
+		Glob:  query.Get("glob"),
+		Regex: query.Get("regex"),
+	}
+
+	deleted, err := a.deleter.DeleteMetrics(r.Context(), selector)
+	if err != nil {
+		if errors.Is(err, entity.ErrMetricType) ||
+			errors.Is(err, entity.ErrSelectorEmpty) ||
+			errors.Is(err, entity.ErrSelectorAmbiguous) ||
+			errors.Is(err, entity.ErrSelectorPattern) {
+			err = fmt.Errorf("%w: %w", resterrs.ErrUnexpectedFilter, err)
+		}
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(deleted)
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+func (a *admin) resetCounter(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	err := a.deleter.ResetCounter(r.Context(), name)
+	if err != nil {
+		resterrs.Write(w, r, resterrs.ForMetric(name, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,147 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	repo "github.com/arxon31/metrics-collector/internal/repository/repoerr"
+)
+
+func newRouter(deleter deleterService) *chi.Mux {
+	mux := chi.NewRouter()
+	NewController(deleter).Register(mux)
+	return mux
+}
+
+func TestAdmin_NewController(t *testing.T) {
+	a := NewController(&deleterServiceMock{})
+	require.IsType(t, &admin{}, a)
+}
+
+func TestAdmin_DeleteMetric(t *testing.T) {
+	t.Run("delete_metric_success", func(t *testing.T) {
+		deleter := &deleterServiceMock{
+			DeleteMetricFunc: func(ctx context.Context, metricType, name string) error {
+				return nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/value/gauge/Alloc", nil)
+		rr := httptest.NewRecorder()
+		newRouter(deleter).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Len(t, deleter.DeleteMetricCalls(), 1)
+		require.Equal(t, entity.GaugeType, deleter.DeleteMetricCalls()[0].MetricType)
+		require.Equal(t, entity.Alloc, deleter.DeleteMetricCalls()[0].Name)
+	})
+
+	t.Run("delete_metric_not_found", func(t *testing.T) {
+		deleter := &deleterServiceMock{
+			DeleteMetricFunc: func(ctx context.Context, metricType, name string) error {
+				return repo.ErrMetricNotFound
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/value/counter/PollCount", nil)
+		rr := httptest.NewRecorder()
+		newRouter(deleter).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("delete_metric_bad_type", func(t *testing.T) {
+		deleter := &deleterServiceMock{}
+
+		req := httptest.NewRequest(http.MethodDelete, "/value/histogram/Alloc", nil)
+		rr := httptest.NewRecorder()
+		newRouter(deleter).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Empty(t, deleter.DeleteMetricCalls())
+	})
+}
+
+func TestAdmin_DeleteMetrics(t *testing.T) {
+	t.Run("delete_metrics_by_glob", func(t *testing.T) {
+		deleter := &deleterServiceMock{
+			DeleteMetricsFunc: func(ctx context.Context, selector entity.MetricSelector) ([]entity.MetricDTO, error) {
+				return []entity.MetricDTO{{Name: entity.HeapAlloc, MetricType: entity.GaugeType}}, nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/value/?glob=Heap*&type=gauge", nil)
+		rr := httptest.NewRecorder()
+		newRouter(deleter).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `[{"id":"HeapAlloc","type":"gauge"}]`, rr.Body.String())
+		require.Equal(t, entity.MetricSelector{Type: entity.GaugeType, Glob: "Heap*"}, deleter.DeleteMetricsCalls()[0].Selector)
+	})
+
+	t.Run("delete_metrics_bad_selector", func(t *testing.T) {
+		deleter := &deleterServiceMock{
+			DeleteMetricsFunc: func(ctx context.Context, selector entity.MetricSelector) ([]entity.MetricDTO, error) {
+				return nil, entity.ErrSelectorEmpty
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/value/", nil)
+		rr := httptest.NewRecorder()
+		newRouter(deleter).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("delete_metrics_fail", func(t *testing.T) {
+		deleter := &deleterServiceMock{
+			DeleteMetricsFunc: func(ctx context.Context, selector entity.MetricSelector) ([]entity.MetricDTO, error) {
+				return nil, errors.New("some error")
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/value/?regex=^Heap", nil)
+		rr := httptest.NewRecorder()
+		newRouter(deleter).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestAdmin_ResetCounter(t *testing.T) {
+	t.Run("reset_counter_success", func(t *testing.T) {
+		deleter := &deleterServiceMock{
+			ResetCounterFunc: func(ctx context.Context, name string) error {
+				return nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/reset/counter/PollCount", nil)
+		rr := httptest.NewRecorder()
+		newRouter(deleter).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, entity.PollCount, deleter.ResetCounterCalls()[0].Name)
+	})
+
+	t.Run("reset_counter_not_found", func(t *testing.T) {
+		deleter := &deleterServiceMock{
+			ResetCounterFunc: func(ctx context.Context, name string) error {
+				return repo.ErrMetricNotFound
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/reset/counter/PollCount", nil)
+		rr := httptest.NewRecorder()
+		newRouter(deleter).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
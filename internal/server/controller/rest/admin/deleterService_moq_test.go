@@ -0,0 +1,188 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package admin
+
+import (
+	"context"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"sync"
+)
+
+// Ensure, that deleterServiceMock does implement deleterService.
+// If this is not the case, regenerate this file with moq.
+var _ deleterService = &deleterServiceMock{}
+
+// deleterServiceMock is a mock implementation of deleterService.
+//
+//	func TestSomethingThatUsesdeleterService(t *testing.T) {
+//
+//		// make and configure a mocked deleterService
+//		mockeddeleterService := &deleterServiceMock{
+//			DeleteMetricFunc: func(ctx context.Context, metricType string, name string) error {
+//				panic("mock out the DeleteMetric method")
+//			},
+//			DeleteMetricsFunc: func(ctx context.Context, selector entity.MetricSelector) ([]entity.MetricDTO, error) {
+//				panic("mock out the DeleteMetrics method")
+//			},
+//			ResetCounterFunc: func(ctx context.Context, name string) error {
+//				panic("mock out the ResetCounter method")
+//			},
+//		}
+//
+//		// use mockeddeleterService in code that requires deleterService
+//		// and then make assertions.
+//
+//	}
+type deleterServiceMock struct {
+	// DeleteMetricFunc mocks the DeleteMetric method.
+	DeleteMetricFunc func(ctx context.Context, metricType string, name string) error
+
+	// DeleteMetricsFunc mocks the DeleteMetrics method.
+	DeleteMetricsFunc func(ctx context.Context, selector entity.MetricSelector) ([]entity.MetricDTO, error)
+
+	// ResetCounterFunc mocks the ResetCounter method.
+	ResetCounterFunc func(ctx context.Context, name string) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// DeleteMetric holds details about calls to the DeleteMetric method.
+		DeleteMetric []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// MetricType is the metricType argument value.
+			MetricType string
+			// Name is the name argument value.
+			Name string
+		}
+		// DeleteMetrics holds details about calls to the DeleteMetrics method.
+		DeleteMetrics []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Selector is the selector argument value.
+			Selector entity.MetricSelector
+		}
+		// ResetCounter holds details about calls to the ResetCounter method.
+		ResetCounter []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Name is the name argument value.
+			Name string
+		}
+	}
+	lockDeleteMetric  sync.RWMutex
+	lockDeleteMetrics sync.RWMutex
+	lockResetCounter  sync.RWMutex
+}
+
+// DeleteMetric calls DeleteMetricFunc.
+func (mock *deleterServiceMock) DeleteMetric(ctx context.Context, metricType string, name string) error {
+	if mock.DeleteMetricFunc == nil {
+		panic("deleterServiceMock.DeleteMetricFunc: method is nil but deleterService.DeleteMetric was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		MetricType string
+		Name       string
+	}{
+		Ctx:        ctx,
+		MetricType: metricType,
+		Name:       name,
+	}
+	mock.lockDeleteMetric.Lock()
+	mock.calls.DeleteMetric = append(mock.calls.DeleteMetric, callInfo)
+	mock.lockDeleteMetric.Unlock()
+	return mock.DeleteMetricFunc(ctx, metricType, name)
+}
+
+// DeleteMetricCalls gets all the calls that were made to DeleteMetric.
+// Check the length with:
+//
+//	len(mockeddeleterService.DeleteMetricCalls())
+func (mock *deleterServiceMock) DeleteMetricCalls() []struct {
+	Ctx        context.Context
+	MetricType string
+	Name       string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		MetricType string
+		Name       string
+	}
+	mock.lockDeleteMetric.RLock()
+	calls = mock.calls.DeleteMetric
+	mock.lockDeleteMetric.RUnlock()
+	return calls
+}
+
+// DeleteMetrics calls DeleteMetricsFunc.
+func (mock *deleterServiceMock) DeleteMetrics(ctx context.Context, selector entity.MetricSelector) ([]entity.MetricDTO, error) {
+	if mock.DeleteMetricsFunc == nil {
+		panic("deleterServiceMock.DeleteMetricsFunc: method is nil but deleterService.DeleteMetrics was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Selector entity.MetricSelector
+	}{
+		Ctx:      ctx,
+		Selector: selector,
+	}
+	mock.lockDeleteMetrics.Lock()
+	mock.calls.DeleteMetrics = append(mock.calls.DeleteMetrics, callInfo)
+	mock.lockDeleteMetrics.Unlock()
+	return mock.DeleteMetricsFunc(ctx, selector)
+}
+
+// DeleteMetricsCalls gets all the calls that were made to DeleteMetrics.
+// Check the length with:
+//
+//	len(mockeddeleterService.DeleteMetricsCalls())
+func (mock *deleterServiceMock) DeleteMetricsCalls() []struct {
+	Ctx      context.Context
+	Selector entity.MetricSelector
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Selector entity.MetricSelector
+	}
+	mock.lockDeleteMetrics.RLock()
+	calls = mock.calls.DeleteMetrics
+	mock.lockDeleteMetrics.RUnlock()
+	return calls
+}
+
+// ResetCounter calls ResetCounterFunc.
+func (mock *deleterServiceMock) ResetCounter(ctx context.Context, name string) error {
+	if mock.ResetCounterFunc == nil {
+		panic("deleterServiceMock.ResetCounterFunc: method is nil but deleterService.ResetCounter was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Name string
+	}{
+		Ctx:  ctx,
+		Name: name,
+	}
+	mock.lockResetCounter.Lock()
+	mock.calls.ResetCounter = append(mock.calls.ResetCounter, callInfo)
+	mock.lockResetCounter.Unlock()
+	return mock.ResetCounterFunc(ctx, name)
+}
+
+// ResetCounterCalls gets all the calls that were made to ResetCounter.
+// Check the length with:
+//
+//	len(mockeddeleterService.ResetCounterCalls())
+func (mock *deleterServiceMock) ResetCounterCalls() []struct {
+	Ctx  context.Context
+	Name string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Name string
+	}
+	mock.lockResetCounter.RLock()
+	calls = mock.calls.ResetCounter
+	mock.lockResetCounter.RUnlock()
+	return calls
+}
@@ -0,0 +1,68 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package alerts
+
+import (
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"sync"
+)
+
+// Ensure, that alerterServiceMock does implement alerterService.
+// If this is not the case, regenerate this file with moq.
+var _ alerterService = &alerterServiceMock{}
+
+// alerterServiceMock is a mock implementation of alerterService.
+//
+//	func TestSomethingThatUsesalerterService(t *testing.T) {
+//
+//		// make and configure a mocked alerterService
+//		mockedalerterService := &alerterServiceMock{
+//			AlertsFunc: func() []entity.Alert {
+//				panic("mock out the Alerts method")
+//			},
+//		}
+//
+//		// use mockedalerterService in code that requires alerterService
+//		// and then make assertions.
+//
+//	}
+type alerterServiceMock struct {
+	// AlertsFunc mocks the Alerts method.
+	AlertsFunc func() []entity.Alert
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Alerts holds details about calls to the Alerts method.
+		Alerts []struct {
+		}
+	}
+	lockAlerts sync.RWMutex
+}
+
+// Alerts calls AlertsFunc.
+func (mock *alerterServiceMock) Alerts() []entity.Alert {
+	if mock.AlertsFunc == nil {
+		panic("alerterServiceMock.AlertsFunc: method is nil but alerterService.Alerts was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockAlerts.Lock()
+	mock.calls.Alerts = append(mock.calls.Alerts, callInfo)
+	mock.lockAlerts.Unlock()
+	return mock.AlertsFunc()
+}
+
+// AlertsCalls gets all the calls that were made to Alerts.
+// Check the length with:
+//
+//	len(mockedalerterService.AlertsCalls())
+func (mock *alerterServiceMock) AlertsCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockAlerts.RLock()
+	calls = mock.calls.Alerts
+	mock.lockAlerts.RUnlock()
+	return calls
+}
@@ -0,0 +1,48 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+)
+
+const (
+	getAlertsURL = "/api/v1/alerts"
+)
+
+//go:generate moq -out alerterService_moq_test.go . alerterService
+type alerterService interface {
+	Alerts() []entity.Alert
+}
+
+type alerts struct {
+	alerter alerterService
+}
+
+// NewController initializes a new alerts controller.
+func NewController(alerter alerterService) *alerts {
+	return &alerts{
+		alerter: alerter,
+	}
+}
+
+// Register registers the alerts endpoints on the provided chi Router.
+func (a *alerts) Register(h chi.Router) {
+	h.Get(getAlertsURL, a.getAlerts)
+}
+
+func (a *alerts) getAlerts(w http.ResponseWriter, r *http.Request) {
+	resp, err := json.Marshal(a.alerter.Alerts())
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
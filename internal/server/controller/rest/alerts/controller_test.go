@@ -0,0 +1,62 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+func TestAlerts_NewController(t *testing.T) {
+	a := NewController(&alerterServiceMock{})
+	require.IsType(t, &alerts{}, a)
+}
+
+func TestAlerts_GetAlerts(t *testing.T) {
+	t.Run("get_alerts_success", func(t *testing.T) {
+		expected := []entity.Alert{
+			{
+				Rule:   "high_alloc",
+				Metric: entity.Alloc,
+				State:  entity.AlertFiring,
+				Value:  42,
+			},
+		}
+		alerter := &alerterServiceMock{
+			AlertsFunc: func() []entity.Alert {
+				return expected
+			},
+		}
+
+		a := NewController(alerter)
+		req := httptest.NewRequest(http.MethodGet, getAlertsURL, nil)
+		rr := httptest.NewRecorder()
+		a.getAlerts(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		var actual []entity.Alert
+		err := json.Unmarshal(rr.Body.Bytes(), &actual)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("get_alerts_empty", func(t *testing.T) {
+		alerter := &alerterServiceMock{
+			AlertsFunc: func() []entity.Alert {
+				return []entity.Alert{}
+			},
+		}
+
+		a := NewController(alerter)
+		req := httptest.NewRequest(http.MethodGet, getAlertsURL, nil)
+		rr := httptest.NewRecorder()
+		a.getAlerts(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, "[]", rr.Body.String())
+	})
+}
@@ -0,0 +1,183 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package backup
+
+import (
+	"context"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"io"
+	"sync"
+)
+
+// Ensure, that backupServiceMock does implement backupService.
+// If this is not the case, regenerate this file with moq.
+var _ backupService = &backupServiceMock{}
+
+// backupServiceMock is a mock implementation of backupService.
+//
+//	func TestSomethingThatUsesbackupService(t *testing.T) {
+//
+//		// make and configure a mocked backupService
+//		mockedbackupService := &backupServiceMock{
+//			DumpFunc: func(ctx context.Context) error {
+//				panic("mock out the Dump method")
+//			},
+//			ExportFunc: func(ctx context.Context, w io.Writer) error {
+//				panic("mock out the Export method")
+//			},
+//			ImportFunc: func(ctx context.Context, r io.Reader, mode string) (entity.RestoreSummary, error) {
+//				panic("mock out the Import method")
+//			},
+//		}
+//
+//		// use mockedbackupService in code that requires backupService
+//		// and then make assertions.
+//
+//	}
+type backupServiceMock struct {
+	// DumpFunc mocks the Dump method.
+	DumpFunc func(ctx context.Context) error
+
+	// ExportFunc mocks the Export method.
+	ExportFunc func(ctx context.Context, w io.Writer) error
+
+	// ImportFunc mocks the Import method.
+	ImportFunc func(ctx context.Context, r io.Reader, mode string) (entity.RestoreSummary, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Dump holds details about calls to the Dump method.
+		Dump []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Export holds details about calls to the Export method.
+		Export []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// W is the w argument value.
+			W io.Writer
+		}
+		// Import holds details about calls to the Import method.
+		Import []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// R is the r argument value.
+			R io.Reader
+			// Mode is the mode argument value.
+			Mode string
+		}
+	}
+	lockDump   sync.RWMutex
+	lockExport sync.RWMutex
+	lockImport sync.RWMutex
+}
+
+// Dump calls DumpFunc.
+func (mock *backupServiceMock) Dump(ctx context.Context) error {
+	if mock.DumpFunc == nil {
+		panic("backupServiceMock.DumpFunc: method is nil but backupService.Dump was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockDump.Lock()
+	mock.calls.Dump = append(mock.calls.Dump, callInfo)
+	mock.lockDump.Unlock()
+	return mock.DumpFunc(ctx)
+}
+
+// DumpCalls gets all the calls that were made to Dump.
+// Check the length with:
+//
+//	len(mockedbackupService.DumpCalls())
+func (mock *backupServiceMock) DumpCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockDump.RLock()
+	calls = mock.calls.Dump
+	mock.lockDump.RUnlock()
+	return calls
+}
+
+// Export calls ExportFunc.
+func (mock *backupServiceMock) Export(ctx context.Context, w io.Writer) error {
+	if mock.ExportFunc == nil {
+		panic("backupServiceMock.ExportFunc: method is nil but backupService.Export was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		W   io.Writer
+	}{
+		Ctx: ctx,
+		W:   w,
+	}
+	mock.lockExport.Lock()
+	mock.calls.Export = append(mock.calls.Export, callInfo)
+	mock.lockExport.Unlock()
+	return mock.ExportFunc(ctx, w)
+}
+
+// ExportCalls gets all the calls that were made to Export.
+// Check the length with:
+//
+//	len(mockedbackupService.ExportCalls())
+func (mock *backupServiceMock) ExportCalls() []struct {
+	Ctx context.Context
+	W   io.Writer
+} {
+	var calls []struct {
+		Ctx context.Context
+		W   io.Writer
+	}
+	mock.lockExport.RLock()
+	calls = mock.calls.Export
+	mock.lockExport.RUnlock()
+	return calls
+}
+
+// Import calls ImportFunc.
+func (mock *backupServiceMock) Import(ctx context.Context, r io.Reader, mode string) (entity.RestoreSummary, error) {
+	if mock.ImportFunc == nil {
+		panic("backupServiceMock.ImportFunc: method is nil but backupService.Import was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		R    io.Reader
+		Mode string
+	}{
+		Ctx:  ctx,
+		R:    r,
+		Mode: mode,
+	}
+	mock.lockImport.Lock()
+	mock.calls.Import = append(mock.calls.Import, callInfo)
+	mock.lockImport.Unlock()
+	return mock.ImportFunc(ctx, r, mode)
+}
+
+// ImportCalls gets all the calls that were made to Import.
+// Check the length with:
+//
+//	len(mockedbackupService.ImportCalls())
+func (mock *backupServiceMock) ImportCalls() []struct {
+	Ctx  context.Context
+	R    io.Reader
+	Mode string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		R    io.Reader
+		Mode string
+	}
+	mock.lockImport.RLock()
+	calls = mock.calls.Import
+	mock.lockImport.RUnlock()
+	return calls
+}
@@ -0,0 +1,108 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+	backups "github.com/arxon31/metrics-collector/internal/server/service/backup"
+)
+
+// SnapshotURL streams snapshots which may be too large to buffer, its responses are not signed
+const SnapshotURL = "/admin/snapshot"
+
+// RestoreURL accepts snapshots which are larger than other requests, it has its own body size limit
+const RestoreURL = "/admin/restore"
+
+//go:generate moq -out backupService_moq_test.go . backupService
+type backupService interface {
+	Dump(ctx context.Context) error
+	Export(ctx context.Context, w io.Writer) error
+	Import(ctx context.Context, r io.Reader, mode string) (entity.RestoreSummary, error)
+}
+
+type backup struct {
+	backups backupService
+}
+
+// NewController initializes a new snapshot and restore controller.
+func NewController(backups backupService) *backup {
+	return &backup{
+		backups: backups,
+	}
+}
+
+// Register registers the snapshot endpoints on the provided chi Router.
+func (b *backup) Register(h chi.Router) {
+	h.Post(SnapshotURL, b.dump)
+	h.Get(SnapshotURL, b.download)
+	h.Post(RestoreURL, b.restore)
+}
+
+// dump writes file storage snapshot now
+func (b *backup) dump(w http.ResponseWriter, r *http.Request) {
+	err := b.backups.Dump(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
+
+// download streams snapshot of current state in the file storage format
+func (b *backup) download(w http.ResponseWriter, r *http.Request) {
+	filename := fmt.Sprintf("metrics-snapshot-%s.json", time.Now().UTC().Format("20060102T150405Z"))
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	err := b.backups.Export(r.Context(), w)
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		writeError(w, r, err)
+	}
+}
+
+// restore loads uploaded snapshot, mode query parameter is merge (default) or replace
+func (b *backup) restore(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = entity.RestoreMerge
+	}
+
+	summary, err := b.backups.Import(r.Context(), r.Body, mode)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(summary)
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, entity.ErrRestoreMode), errors.Is(err, backups.ErrInvalidSnapshot):
+		err = resterrs.WithStatus(http.StatusBadRequest, resterrs.CodeBadRequest, err)
+	case errors.Is(err, backups.ErrDumpDisabled):
+		err = resterrs.WithStatus(http.StatusNotImplemented, resterrs.CodeNotImplemented, err)
+	}
+	resterrs.Write(w, r, err)
+}
@@ -0,0 +1,121 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+	backups "github.com/arxon31/metrics-collector/internal/server/service/backup"
+)
+
+func newRouter(service backupService) *chi.Mux {
+	mux := chi.NewRouter()
+	NewController(service).Register(mux)
+	return mux
+}
+
+func TestBackup_Dump(t *testing.T) {
+	t.Run("dump_success", func(t *testing.T) {
+		service := &backupServiceMock{
+			DumpFunc: func(ctx context.Context) error { return nil },
+		}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, SnapshotURL, nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Len(t, service.DumpCalls(), 1)
+	})
+
+	t.Run("dump_disabled", func(t *testing.T) {
+		service := &backupServiceMock{
+			DumpFunc: func(ctx context.Context) error { return backups.ErrDumpDisabled },
+		}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, SnapshotURL, nil))
+
+		require.Equal(t, http.StatusNotImplemented, rr.Code)
+	})
+}
+
+func TestBackup_Download(t *testing.T) {
+	t.Run("download_success", func(t *testing.T) {
+		service := &backupServiceMock{
+			ExportFunc: func(ctx context.Context, w io.Writer) error {
+				_, err := io.WriteString(w, "snapshot")
+				return err
+			},
+		}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, SnapshotURL, nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "snapshot", rr.Body.String())
+		require.Contains(t, rr.Header().Get("Content-Disposition"), "attachment")
+	})
+
+	t.Run("download_failure", func(t *testing.T) {
+		service := &backupServiceMock{
+			ExportFunc: func(ctx context.Context, w io.Writer) error { return errors.New("connection refused") },
+		}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, SnapshotURL, nil))
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Empty(t, rr.Header().Get("Content-Disposition"))
+	})
+}
+
+func TestBackup_Restore(t *testing.T) {
+	t.Run("merge_by_default", func(t *testing.T) {
+		service := &backupServiceMock{
+			ImportFunc: func(ctx context.Context, r io.Reader, mode string) (entity.RestoreSummary, error) {
+				return entity.RestoreSummary{Mode: mode, Metrics: 2}, nil
+			},
+		}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, RestoreURL, strings.NewReader("{}")))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, entity.RestoreMerge, service.ImportCalls()[0].Mode)
+
+		var summary entity.RestoreSummary
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &summary))
+		require.Equal(t, 2, summary.Metrics)
+	})
+
+	for _, err := range []error{entity.ErrRestoreMode, fmt.Errorf("%w: checksum mismatch", backups.ErrInvalidSnapshot)} {
+		t.Run(err.Error(), func(t *testing.T) {
+			service := &backupServiceMock{
+				ImportFunc: func(ctx context.Context, r io.Reader, mode string) (entity.RestoreSummary, error) {
+					return entity.RestoreSummary{}, err
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			newRouter(service).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, RestoreURL+"?mode=replace", strings.NewReader("{}")))
+
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			require.Equal(t, entity.RestoreReplace, service.ImportCalls()[0].Mode)
+
+			var resp resterrs.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, resterrs.CodeBadRequest, resp.Code)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package cache
+
+import (
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"sync"
+)
+
+// Ensure, that cacheServiceMock does implement cacheService.
+// If this is not the case, regenerate this file with moq.
+var _ cacheService = &cacheServiceMock{}
+
+// cacheServiceMock is a mock implementation of cacheService.
+//
+//	func TestSomethingThatUsescacheService(t *testing.T) {
+//
+//		// make and configure a mocked cacheService
+//		mockedcacheService := &cacheServiceMock{
+//			StatsFunc: func() entity.CacheStats {
+//				panic("mock out the Stats method")
+//			},
+//		}
+//
+//		// use mockedcacheService in code that requires cacheService
+//		// and then make assertions.
+//
+//	}
+type cacheServiceMock struct {
+	// StatsFunc mocks the Stats method.
+	StatsFunc func() entity.CacheStats
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Stats holds details about calls to the Stats method.
+		Stats []struct {
+		}
+	}
+	lockStats sync.RWMutex
+}
+
+// Stats calls StatsFunc.
+func (mock *cacheServiceMock) Stats() entity.CacheStats {
+	if mock.StatsFunc == nil {
+		panic("cacheServiceMock.StatsFunc: method is nil but cacheService.Stats was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockStats.Lock()
+	mock.calls.Stats = append(mock.calls.Stats, callInfo)
+	mock.lockStats.Unlock()
+	return mock.StatsFunc()
+}
+
+// StatsCalls gets all the calls that were made to Stats.
+// Check the length with:
+//
+//	len(mockedcacheService.StatsCalls())
+func (mock *cacheServiceMock) StatsCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockStats.RLock()
+	calls = mock.calls.Stats
+	mock.lockStats.RUnlock()
+	return calls
+}
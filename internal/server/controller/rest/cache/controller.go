@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+)
+
+const cacheURL = "/admin/cache"
+
+//go:generate moq -out cacheService_moq_test.go . cacheService
+type cacheService interface {
+	Stats() entity.CacheStats
+}
+
+type cache struct {
+	cache cacheService
+}
+
+// statsResponse describes write-back cache, lags are in seconds
+type statsResponse struct {
+	Pending      int        `json:"pending"`
+	Lag          float64    `json:"lag_seconds"`
+	LastFlush    *time.Time `json:"last_flush,omitempty"`
+	LastFlushLag float64    `json:"last_flush_lag_seconds"`
+	Flushes      int64      `json:"flushes"`
+	FlushErrors  int64      `json:"flush_errors"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// NewController initializes a new write-back cache statistics controller.
+func NewController(cacheService cacheService) *cache {
+	return &cache{
+		cache: cacheService,
+	}
+}
+
+// Register registers the cache endpoints on the provided chi Router.
+func (c *cache) Register(h chi.Router) {
+	h.Get(cacheURL, c.stats)
+}
+
+// stats writes pending writes and flush lag of cache
+func (c *cache) stats(w http.ResponseWriter, r *http.Request) {
+	stats := c.cache.Stats()
+
+	resp := statsResponse{
+		Pending:      stats.Pending,
+		Lag:          stats.Lag.Seconds(),
+		LastFlushLag: stats.LastFlushLag.Seconds(),
+		Flushes:      stats.Flushes,
+		FlushErrors:  stats.FlushErrors,
+		LastError:    stats.LastError,
+	}
+	if !stats.LastFlush.IsZero() {
+		resp.LastFlush = &stats.LastFlush
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
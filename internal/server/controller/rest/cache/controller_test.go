@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+func newRouter(service cacheService) *chi.Mux {
+	mux := chi.NewRouter()
+	NewController(service).Register(mux)
+	return mux
+}
+
+func TestCache_Stats(t *testing.T) {
+	t.Run("stats_success", func(t *testing.T) {
+		lastFlush := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		service := &cacheServiceMock{
+			StatsFunc: func() entity.CacheStats {
+				return entity.CacheStats{
+					Pending:      3,
+					Lag:          1500 * time.Millisecond,
+					LastFlush:    lastFlush,
+					LastFlushLag: 2 * time.Second,
+					Flushes:      7,
+					FlushErrors:  1,
+				}
+			},
+		}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, cacheURL, nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		var resp statsResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Equal(t, 3, resp.Pending)
+		require.Equal(t, 1.5, resp.Lag)
+		require.Equal(t, 2.0, resp.LastFlushLag)
+		require.Equal(t, int64(7), resp.Flushes)
+		require.Equal(t, int64(1), resp.FlushErrors)
+		require.NotNil(t, resp.LastFlush)
+		require.True(t, lastFlush.Equal(*resp.LastFlush))
+	})
+
+	t.Run("never_flushed", func(t *testing.T) {
+		service := &cacheServiceMock{
+			StatsFunc: func() entity.CacheStats { return entity.CacheStats{} },
+		}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, cacheURL, nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NotContains(t, rr.Body.String(), "last_flush\"")
+	})
+}
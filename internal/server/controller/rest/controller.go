@@ -2,50 +2,174 @@ package rest
 
 import (
 	"context"
-	"crypto/rsa"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/arxon31/metrics-collector/internal/server/controller/rest/middlewares"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/encrypting"
 	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/idempotency"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/admin"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/alerts"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/backup"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/cache"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/encryption"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/exposition"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/metadata"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/tokens"
 	v1 "github.com/arxon31/metrics-collector/internal/server/controller/rest/v1"
 	v2 "github.com/arxon31/metrics-collector/internal/server/controller/rest/v2"
 	v3 "github.com/arxon31/metrics-collector/internal/server/controller/rest/v3"
+	"github.com/arxon31/metrics-collector/internal/signing"
 )
 
 type storageService interface {
 	SaveGaugeMetric(ctx context.Context, metric entity.MetricDTO) error
 	SaveCounterMetric(ctx context.Context, metric entity.MetricDTO) error
-	SaveBatchMetrics(ctx context.Context, metrics []entity.MetricDTO) error
+	SaveBatchMetrics(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error)
+	SaveMetadata(ctx context.Context, metadata entity.Metadata) error
 }
 
 type providerService interface {
 	GetGaugeValue(ctx context.Context, name string) (float64, error)
 	GetCounterValue(ctx context.Context, name string) (int64, error)
 	GetMetrics(ctx context.Context) ([]entity.MetricDTO, error)
+	GetMetadata(ctx context.Context) ([]entity.Metadata, error)
 }
 
 type pingerService interface {
 	PingDB() error
 }
 
-func NewController(handler *chi.Mux, storage storageService, provider providerService, pinger pingerService, hashKey string, cryptoKey *rsa.PrivateKey) http.Handler {
-	hashingMw := middlewares.NewHashingMiddleware(hashKey)
-	compressingMw := middlewares.NewCompressingMiddleware()
+type alerterService interface {
+	Alerts() []entity.Alert
+}
+
+type deleterService interface {
+	DeleteMetric(ctx context.Context, metricType, name string) error
+	DeleteMetrics(ctx context.Context, selector entity.MetricSelector) ([]entity.MetricDTO, error)
+	ResetCounter(ctx context.Context, name string) error
+}
+
+type tenantService interface {
+	CreateToken(ctx context.Context, tenant string) (entity.APIToken, string, error)
+	Tokens(ctx context.Context) ([]entity.APIToken, error)
+	RevokeToken(ctx context.Context, id string) error
+	ResolveToken(ctx context.Context, token string) (string, error)
+}
+
+type backupService interface {
+	Dump(ctx context.Context) error
+	Export(ctx context.Context, w io.Writer) error
+	Import(ctx context.Context, r io.Reader, mode string) (entity.RestoreSummary, error)
+}
+
+type cacheService interface {
+	Stats() entity.CacheStats
+}
+
+type idempotencyStore interface {
+	Begin(ctx context.Context, tenant, key, fingerprint string) (*idempotency.Response, error)
+	Complete(ctx context.Context, tenant, key string, resp idempotency.Response) error
+	Release(ctx context.Context, tenant, key string) error
+}
+
+type authenticator interface {
+	Enabled() bool
+	Authenticate(token string) (auth.Identity, error)
+}
+
+// Signature configures verification of signed requests
+type Signature struct {
+	Keys        *signing.Keyring
+	Skew        time.Duration
+	AllowLegacy bool
+	Strict      bool
+}
+
+// Idempotency configures replaying responses of retried batches, nil Store disables it
+type Idempotency struct {
+	Store idempotencyStore
+	// Wait limits how long a duplicate waits for the original request being processed
+	Wait time.Duration
+}
+
+// Limits restricts rate and size of client requests, zero values mean unlimited.
+// MaxRestoreSize replaces MaxBodySize for snapshots uploaded to restore.
+type Limits struct {
+	RPS            float64
+	Burst          int
+	MaxBodySize    int64
+	MaxRestoreSize int64
+	MaxBatchSize   int
+}
+
+func NewController(handler *chi.Mux, storage storageService, provider providerService, pinger pingerService, alerter alerterService, deleter deleterService, tenants tenantService, backups backupService, caches cacheService, authenticator authenticator, limits Limits, signature Signature, cryptoKeys *encrypting.Keyring, idempotent Idempotency) http.Handler {
+	hashingMw := middlewares.NewHashingMiddleware(signature.Keys, signature.Skew, signature.AllowLegacy, signature.Strict, backup.SnapshotURL)
+	compressingMw := middlewares.NewCompressingMiddleware(limits.MaxBodySize)
+	bodyLimitMw := middlewares.NewBodyLimitMiddleware(limits.MaxBodySize, map[string]int64{backup.RestoreURL: limits.MaxRestoreSize})
+	decryptingMw := middlewares.NewDecryptingMiddleware(cryptoKeys)
+	requestIDMw := middlewares.NewRequestIDMiddleware()
 	loggingMw := middlewares.NewLoggingMiddleware()
+	rateLimitMw := middlewares.NewRateLimitMiddleware(limits.RPS, limits.Burst)
+	clientCertMw := middlewares.NewClientCertMiddleware()
+	authMw := middlewares.NewAuthMiddleware(authenticator)
+	tenantMw := middlewares.NewTenantMiddleware(tenants)
+
+	handler.Use(requestIDMw.WithRequestID, loggingMw.WithLog, bodyLimitMw.WithBodyLimit, clientCertMw.WithClientCert, hashingMw.WithHash, decryptingMw.WithDecrypt, compressingMw.WithCompress)
 
-	handler.Use(loggingMw.WithLog, hashingMw.WithHash, compressingMw.WithCompress)
+	// rate limit follows authentication so clients are keyed by verified identity, not by headers they send
+	readers := handler.With(authMw.Require(auth.RoleReader), tenantMw.WithTenant, rateLimitMw.WithRateLimit)
+	writers := handler.With(authMw.Require(auth.RoleWriter), tenantMw.WithTenant, rateLimitMw.WithRateLimit)
+	admins := handler.With(authMw.Require(auth.RoleAdmin), rateLimitMw.WithRateLimit)
 
 	sprint1 := v1.NewController(storage, provider)
-	sprint1.Register(handler)
+	sprint1.Register(readers, writers)
 
 	sprint2 := v2.NewController(storage, provider)
-	sprint2.Register(handler)
+	sprint2.Register(readers, writers)
+
+	v3Opts := []v3.Option{v3.WithMaxBatchSize(limits.MaxBatchSize)}
+	if idempotent.Store != nil {
+		v3Opts = append(v3Opts, v3.WithIdempotency(idempotent.Store, idempotent.Wait))
+	}
+
+	sprint3 := v3.NewController(storage, provider, pinger, v3Opts...)
+	sprint3.Register(readers, writers)
+
+	metadataRegistry := metadata.NewController(storage, provider)
+	metadataRegistry.Register(readers, writers)
+
+	prometheus := exposition.NewController(provider)
+	prometheus.Register(readers)
+
+	// rules are evaluated against default tenant, its alerts must not be visible to readers of other tenants
+	alerting := alerts.NewController(alerter)
+	alerting.Register(admins)
+
+	administration := admin.NewController(deleter)
+	administration.Register(admins)
+
+	apiTokens := tokens.NewController(tenants)
+	apiTokens.Register(admins)
+
+	snapshots := backup.NewController(backups)
+	snapshots.Register(admins)
+
+	if caches != nil {
+		writeBack := cache.NewController(caches)
+		writeBack.Register(admins)
+	}
 
-	sprint3 := v3.NewController(storage, provider, pinger)
-	sprint3.Register(handler)
+	if cryptoKeys != nil {
+		encryptionKey := encryption.NewController(cryptoKeys)
+		encryptionKey.Register(readers)
+	}
 
 	return handler
 }
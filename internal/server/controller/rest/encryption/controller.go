@@ -0,0 +1,61 @@
+package encryption
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/arxon31/metrics-collector/internal/encrypting"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+)
+
+const publicKeyURL = "/api/v1/encryption-key"
+
+//go:generate moq -out keyProvider_moq_test.go . keyProvider
+type keyProvider interface {
+	PublicKey() encrypting.PublicKey
+}
+
+// publicKeyResponse publishes current payload encryption key
+type publicKeyResponse struct {
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+}
+
+type encryption struct {
+	keys keyProvider
+}
+
+// NewController initializes a new encryption key controller.
+func NewController(keys keyProvider) *encryption {
+	return &encryption{
+		keys: keys,
+	}
+}
+
+// Register registers the public key endpoint on the provided chi Router,
+// the key is not secret so it is published to every client allowed to read.
+func (e *encryption) Register(h chi.Router) {
+	h.Get(publicKeyURL, e.publicKey)
+}
+
+func (e *encryption) publicKey(w http.ResponseWriter, r *http.Request) {
+	key := e.keys.PublicKey()
+
+	pem, err := encrypting.EncodePublicKey(key.Key)
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(publicKeyResponse{KeyID: key.ID, PublicKey: string(pem)})
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
@@ -0,0 +1,44 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/encrypting"
+)
+
+func TestEncryption_NewController(t *testing.T) {
+	e := NewController(&keyProviderMock{})
+	require.IsType(t, &encryption{}, e)
+}
+
+func TestEncryption_PublicKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	keys := &keyProviderMock{
+		PublicKeyFunc: func() encrypting.PublicKey {
+			return encrypting.PublicKey{ID: encrypting.KeyID(&privateKey.PublicKey), Key: &privateKey.PublicKey}
+		},
+	}
+
+	e := NewController(keys)
+	req := httptest.NewRequest(http.MethodGet, publicKeyURL, nil)
+	rr := httptest.NewRecorder()
+	e.publicKey(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp publicKeyResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, encrypting.KeyID(&privateKey.PublicKey), resp.KeyID)
+
+	publicKey, err := encrypting.ParsePublicKey([]byte(resp.PublicKey))
+	require.NoError(t, err)
+	require.True(t, publicKey.Equal(&privateKey.PublicKey))
+}
@@ -0,0 +1,68 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package encryption
+
+import (
+	"github.com/arxon31/metrics-collector/internal/encrypting"
+	"sync"
+)
+
+// Ensure, that keyProviderMock does implement keyProvider.
+// If this is not the case, regenerate this file with moq.
+var _ keyProvider = &keyProviderMock{}
+
+// keyProviderMock is a mock implementation of keyProvider.
+//
+//	func TestSomethingThatUseskeyProvider(t *testing.T) {
+//
+//		// make and configure a mocked keyProvider
+//		mockedkeyProvider := &keyProviderMock{
+//			PublicKeyFunc: func() encrypting.PublicKey {
+//				panic("mock out the PublicKey method")
+//			},
+//		}
+//
+//		// use mockedkeyProvider in code that requires keyProvider
+//		// and then make assertions.
+//
+//	}
+type keyProviderMock struct {
+	// PublicKeyFunc mocks the PublicKey method.
+	PublicKeyFunc func() encrypting.PublicKey
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// PublicKey holds details about calls to the PublicKey method.
+		PublicKey []struct {
+		}
+	}
+	lockPublicKey sync.RWMutex
+}
+
+// PublicKey calls PublicKeyFunc.
+func (mock *keyProviderMock) PublicKey() encrypting.PublicKey {
+	if mock.PublicKeyFunc == nil {
+		panic("keyProviderMock.PublicKeyFunc: method is nil but keyProvider.PublicKey was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockPublicKey.Lock()
+	mock.calls.PublicKey = append(mock.calls.PublicKey, callInfo)
+	mock.lockPublicKey.Unlock()
+	return mock.PublicKeyFunc()
+}
+
+// PublicKeyCalls gets all the calls that were made to PublicKey.
+// Check the length with:
+//
+//	len(mockedkeyProvider.PublicKeyCalls())
+func (mock *keyProviderMock) PublicKeyCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockPublicKey.RLock()
+	calls = mock.calls.PublicKey
+	mock.lockPublicKey.RUnlock()
+	return calls
+}
@@ -0,0 +1,121 @@
+package exposition
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+)
+
+const (
+	prometheusURL         = "/metrics"
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+//go:generate moq -out providerService_moq_test.go . providerService
+type providerService interface {
+	GetMetrics(ctx context.Context) ([]entity.MetricDTO, error)
+	GetMetadata(ctx context.Context) ([]entity.Metadata, error)
+}
+
+type exposition struct {
+	provider providerService
+}
+
+// NewController initializes a new Prometheus exposition controller.
+func NewController(provider providerService) *exposition {
+	return &exposition{
+		provider: provider,
+	}
+}
+
+// Register registers the exposition endpoints on the provided chi Router.
+func (e *exposition) Register(h chi.Router) {
+	h.Get(prometheusURL, e.getPrometheusMetrics)
+}
+
+func (e *exposition) getPrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	ms, err := e.provider.GetMetrics(r.Context())
+	if err != nil {
+		resterrs.WriteText(w, r, err)
+		return
+	}
+
+	mds, err := e.provider.GetMetadata(r.Context())
+	if err != nil {
+		resterrs.WriteText(w, r, err)
+		return
+	}
+
+	help := make(map[string]string, len(mds))
+	for _, md := range mds {
+		help[md.Name] = helpText(md)
+	}
+
+	sort.Slice(ms, func(i, j int) bool {
+		if ms[i].Name == ms[j].Name {
+			return ms[i].MetricType < ms[j].MetricType
+		}
+		return ms[i].Name < ms[j].Name
+	})
+
+	var buf bytes.Buffer
+	for _, m := range ms {
+		var value string
+		switch {
+		case m.MetricType == entity.GaugeType && m.Gauge != nil:
+			value = strconv.FormatFloat(*m.Gauge, 'g', -1, 64)
+		case m.MetricType == entity.CounterType && m.Counter != nil:
+			value = strconv.FormatInt(*m.Counter, 10)
+		default:
+			continue
+		}
+
+		name := sanitizeName(m.Name)
+		if text, ok := help[m.Name]; ok && text != "" {
+			fmt.Fprintf(&buf, "# HELP %s %s\n", name, text)
+		}
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", name, m.MetricType)
+		fmt.Fprintf(&buf, "%s %s\n", name, value)
+	}
+
+	w.Header().Set("Content-Type", prometheusContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func helpText(md entity.Metadata) string {
+	text := md.Description
+	if md.Unit != "" {
+		if text != "" {
+			text += " "
+		}
+		text += "(" + md.Unit + ")"
+	}
+	text = strings.ReplaceAll(text, `\`, `\\`)
+	return strings.ReplaceAll(text, "\n", `\n`)
+}
+
+// sanitizeName replaces characters not allowed in Prometheus metric names
+func sanitizeName(name string) string {
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			return r
+		case r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,73 @@
+package exposition
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+func TestExposition_NewController(t *testing.T) {
+	e := NewController(&providerServiceMock{})
+	require.IsType(t, &exposition{}, e)
+}
+
+func TestExposition_GetPrometheusMetrics(t *testing.T) {
+	t.Run("get_prometheus_metrics_success", func(t *testing.T) {
+		gaugeVal := 20.5
+		counterVal := int64(3)
+
+		provider := &providerServiceMock{
+			GetMetricsFunc: func(ctx context.Context) ([]entity.MetricDTO, error) {
+				return []entity.MetricDTO{
+					{Name: entity.PollCount, MetricType: entity.CounterType, Counter: &counterVal},
+					{Name: entity.Alloc, MetricType: entity.GaugeType, Gauge: &gaugeVal},
+					{Name: "custom.metric", MetricType: entity.GaugeType, Gauge: &gaugeVal},
+				}, nil
+			},
+			GetMetadataFunc: func(ctx context.Context) ([]entity.Metadata, error) {
+				return []entity.Metadata{
+					{Name: entity.Alloc, Unit: entity.UnitBytes, Description: "Bytes of allocated heap objects"},
+					{Name: entity.PollCount, Description: "Number of polls"},
+				}, nil
+			},
+		}
+
+		e := NewController(provider)
+		req := httptest.NewRequest(http.MethodGet, prometheusURL, nil)
+		rr := httptest.NewRecorder()
+		e.getPrometheusMetrics(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, prometheusContentType, rr.Header().Get("Content-Type"))
+		require.Equal(t, `# HELP Alloc Bytes of allocated heap objects (bytes)
+# TYPE Alloc gauge
+Alloc 20.5
+# HELP PollCount Number of polls
+# TYPE PollCount counter
+PollCount 3
+# TYPE custom_metric gauge
+custom_metric 20.5
+`, rr.Body.String())
+	})
+
+	t.Run("get_prometheus_metrics_fail", func(t *testing.T) {
+		provider := &providerServiceMock{
+			GetMetricsFunc: func(ctx context.Context) ([]entity.MetricDTO, error) {
+				return nil, errors.New("some error")
+			},
+		}
+
+		e := NewController(provider)
+		req := httptest.NewRequest(http.MethodGet, prometheusURL, nil)
+		rr := httptest.NewRecorder()
+		e.getPrometheusMetrics(rr, req)
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
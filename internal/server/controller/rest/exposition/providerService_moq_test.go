@@ -0,0 +1,120 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package exposition
+
+import (
+	"context"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"sync"
+)
+
+// Ensure, that providerServiceMock does implement providerService.
+// If this is not the case, regenerate this file with moq.
+var _ providerService = &providerServiceMock{}
+
+// providerServiceMock is a mock implementation of providerService.
+//
+//	func TestSomethingThatUsesproviderService(t *testing.T) {
+//
+//		// make and configure a mocked providerService
+//		mockedproviderService := &providerServiceMock{
+//			GetMetadataFunc: func(ctx context.Context) ([]entity.Metadata, error) {
+//				panic("mock out the GetMetadata method")
+//			},
+//			GetMetricsFunc: func(ctx context.Context) ([]entity.MetricDTO, error) {
+//				panic("mock out the GetMetrics method")
+//			},
+//		}
+//
+//		// use mockedproviderService in code that requires providerService
+//		// and then make assertions.
+//
+//	}
+type providerServiceMock struct {
+	// GetMetadataFunc mocks the GetMetadata method.
+	GetMetadataFunc func(ctx context.Context) ([]entity.Metadata, error)
+
+	// GetMetricsFunc mocks the GetMetrics method.
+	GetMetricsFunc func(ctx context.Context) ([]entity.MetricDTO, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// GetMetadata holds details about calls to the GetMetadata method.
+		GetMetadata []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// GetMetrics holds details about calls to the GetMetrics method.
+		GetMetrics []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+	}
+	lockGetMetadata sync.RWMutex
+	lockGetMetrics  sync.RWMutex
+}
+
+// GetMetadata calls GetMetadataFunc.
+func (mock *providerServiceMock) GetMetadata(ctx context.Context) ([]entity.Metadata, error) {
+	if mock.GetMetadataFunc == nil {
+		panic("providerServiceMock.GetMetadataFunc: method is nil but providerService.GetMetadata was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetMetadata.Lock()
+	mock.calls.GetMetadata = append(mock.calls.GetMetadata, callInfo)
+	mock.lockGetMetadata.Unlock()
+	return mock.GetMetadataFunc(ctx)
+}
+
+// GetMetadataCalls gets all the calls that were made to GetMetadata.
+// Check the length with:
+//
+//	len(mockedproviderService.GetMetadataCalls())
+func (mock *providerServiceMock) GetMetadataCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetMetadata.RLock()
+	calls = mock.calls.GetMetadata
+	mock.lockGetMetadata.RUnlock()
+	return calls
+}
+
+// GetMetrics calls GetMetricsFunc.
+func (mock *providerServiceMock) GetMetrics(ctx context.Context) ([]entity.MetricDTO, error) {
+	if mock.GetMetricsFunc == nil {
+		panic("providerServiceMock.GetMetricsFunc: method is nil but providerService.GetMetrics was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetMetrics.Lock()
+	mock.calls.GetMetrics = append(mock.calls.GetMetrics, callInfo)
+	mock.lockGetMetrics.Unlock()
+	return mock.GetMetricsFunc(ctx)
+}
+
+// GetMetricsCalls gets all the calls that were made to GetMetrics.
+// Check the length with:
+//
+//	len(mockedproviderService.GetMetricsCalls())
+func (mock *providerServiceMock) GetMetricsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetMetrics.RLock()
+	calls = mock.calls.GetMetrics
+	mock.lockGetMetrics.RUnlock()
+	return calls
+}
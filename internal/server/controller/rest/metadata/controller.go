@@ -0,0 +1,108 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+)
+
+const (
+	metadataListURL = "/api/v1/metadata"
+	metadataURL     = "/api/v1/metadata/{name}"
+)
+
+//go:generate moq -out storageService_moq_test.go . storageService
+type storageService interface {
+	SaveMetadata(ctx context.Context, metadata entity.Metadata) error
+}
+
+//go:generate moq -out providerService_moq_test.go . providerService
+type providerService interface {
+	GetMetadata(ctx context.Context) ([]entity.Metadata, error)
+}
+
+type metadata struct {
+	store    storageService
+	provider providerService
+}
+
+// NewController initializes a new metadata controller.
+func NewController(store storageService, provider providerService) *metadata {
+	return &metadata{
+		store:    store,
+		provider: provider,
+	}
+}
+
+// Register registers the metadata endpoints on the provided chi Router.
+func (m *metadata) Register(readers, writers chi.Router) {
+	readers.Get(metadataListURL, m.getMetadataList)
+	readers.Get(metadataURL, m.getMetadata)
+	writers.Put(metadataURL, m.putMetadata)
+}
+
+func (m *metadata) putMetadata(w http.ResponseWriter, r *http.Request) {
+	var md entity.Metadata
+
+	if err := json.NewDecoder(r.Body).Decode(&md); err != nil {
+		resterrs.Write(w, r, resterrs.ErrUnexpectedFormat)
+		return
+	}
+	defer r.Body.Close()
+
+	md.Name = chi.URLParam(r, "name")
+
+	err := m.store.SaveMetadata(r.Context(), md)
+	if err != nil {
+		resterrs.Write(w, r, resterrs.ForMetric(md.Name, err))
+		return
+	}
+
+	m.writeJSON(w, r, md)
+}
+
+func (m *metadata) getMetadataList(w http.ResponseWriter, r *http.Request) {
+	mds, err := m.provider.GetMetadata(r.Context())
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	m.writeJSON(w, r, mds)
+}
+
+func (m *metadata) getMetadata(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	mds, err := m.provider.GetMetadata(r.Context())
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	for _, md := range mds {
+		if md.Name == name {
+			m.writeJSON(w, r, md)
+			return
+		}
+	}
+
+	resterrs.Write(w, r, resterrs.ForMetric(name, resterrs.ErrMetricNotFound))
+}
+
+func (m *metadata) writeJSON(w http.ResponseWriter, r *http.Request, v any) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
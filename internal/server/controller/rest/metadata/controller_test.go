@@ -0,0 +1,121 @@
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+func newRouter(store storageService, provider providerService) *chi.Mux {
+	mux := chi.NewRouter()
+	NewController(store, provider).Register(mux, mux)
+	return mux
+}
+
+func TestMetadata_NewController(t *testing.T) {
+	m := NewController(&storageServiceMock{}, &providerServiceMock{})
+	require.IsType(t, &metadata{}, m)
+}
+
+func TestMetadata_PutMetadata(t *testing.T) {
+	t.Run("put_metadata_success", func(t *testing.T) {
+		store := &storageServiceMock{
+			SaveMetadataFunc: func(ctx context.Context, metadata entity.Metadata) error {
+				return nil
+			},
+		}
+
+		body := bytes.NewBufferString(`{"name":"ignored","type":"gauge","unit":"bytes","description":"heap","owner":"team"}`)
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/metadata/Alloc", body)
+		rr := httptest.NewRecorder()
+		newRouter(store, &providerServiceMock{}).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, entity.Metadata{
+			Name:        entity.Alloc,
+			Type:        entity.GaugeType,
+			Unit:        entity.UnitBytes,
+			Description: "heap",
+			Owner:       "team",
+		}, store.SaveMetadataCalls()[0].Metadata)
+	})
+
+	t.Run("put_metadata_bad_type", func(t *testing.T) {
+		store := &storageServiceMock{
+			SaveMetadataFunc: func(ctx context.Context, metadata entity.Metadata) error {
+				return entity.ErrMetadataType
+			},
+		}
+
+		body := bytes.NewBufferString(`{"type":"histogram"}`)
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/metadata/Alloc", body)
+		rr := httptest.NewRecorder()
+		newRouter(store, &providerServiceMock{}).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("put_metadata_bad_body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/metadata/Alloc", bytes.NewBufferString("{"))
+		rr := httptest.NewRecorder()
+		newRouter(&storageServiceMock{}, &providerServiceMock{}).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("put_metadata_fail", func(t *testing.T) {
+		store := &storageServiceMock{
+			SaveMetadataFunc: func(ctx context.Context, metadata entity.Metadata) error {
+				return errors.New("some error")
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/metadata/Alloc", bytes.NewBufferString(`{}`))
+		rr := httptest.NewRecorder()
+		newRouter(store, &providerServiceMock{}).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestMetadata_GetMetadata(t *testing.T) {
+	provider := &providerServiceMock{
+		GetMetadataFunc: func(ctx context.Context) ([]entity.Metadata, error) {
+			return []entity.Metadata{{Name: entity.Alloc, Unit: entity.UnitBytes}}, nil
+		},
+	}
+
+	t.Run("get_metadata_list", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/metadata", nil)
+		rr := httptest.NewRecorder()
+		newRouter(&storageServiceMock{}, provider).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `[{"name":"Alloc","unit":"bytes"}]`, rr.Body.String())
+	})
+
+	t.Run("get_metadata_by_name", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/metadata/Alloc", nil)
+		rr := httptest.NewRecorder()
+		newRouter(&storageServiceMock{}, provider).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `{"name":"Alloc","unit":"bytes"}`, rr.Body.String())
+	})
+
+	t.Run("get_metadata_not_found", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/metadata/Unknown", nil)
+		rr := httptest.NewRecorder()
+		newRouter(&storageServiceMock{}, provider).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,76 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package metadata
+
+import (
+	"context"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"sync"
+)
+
+// Ensure, that providerServiceMock does implement providerService.
+// If this is not the case, regenerate this file with moq.
+var _ providerService = &providerServiceMock{}
+
+// providerServiceMock is a mock implementation of providerService.
+//
+//	func TestSomethingThatUsesproviderService(t *testing.T) {
+//
+//		// make and configure a mocked providerService
+//		mockedproviderService := &providerServiceMock{
+//			GetMetadataFunc: func(ctx context.Context) ([]entity.Metadata, error) {
+//				panic("mock out the GetMetadata method")
+//			},
+//		}
+//
+//		// use mockedproviderService in code that requires providerService
+//		// and then make assertions.
+//
+//	}
+type providerServiceMock struct {
+	// GetMetadataFunc mocks the GetMetadata method.
+	GetMetadataFunc func(ctx context.Context) ([]entity.Metadata, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// GetMetadata holds details about calls to the GetMetadata method.
+		GetMetadata []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+	}
+	lockGetMetadata sync.RWMutex
+}
+
+// GetMetadata calls GetMetadataFunc.
+func (mock *providerServiceMock) GetMetadata(ctx context.Context) ([]entity.Metadata, error) {
+	if mock.GetMetadataFunc == nil {
+		panic("providerServiceMock.GetMetadataFunc: method is nil but providerService.GetMetadata was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetMetadata.Lock()
+	mock.calls.GetMetadata = append(mock.calls.GetMetadata, callInfo)
+	mock.lockGetMetadata.Unlock()
+	return mock.GetMetadataFunc(ctx)
+}
+
+// GetMetadataCalls gets all the calls that were made to GetMetadata.
+// Check the length with:
+//
+//	len(mockedproviderService.GetMetadataCalls())
+func (mock *providerServiceMock) GetMetadataCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetMetadata.RLock()
+	calls = mock.calls.GetMetadata
+	mock.lockGetMetadata.RUnlock()
+	return calls
+}
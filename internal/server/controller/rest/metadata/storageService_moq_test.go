@@ -0,0 +1,82 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package metadata
+
+import (
+	"context"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"sync"
+)
+
+// Ensure, that storageServiceMock does implement storageService.
+// If this is not the case, regenerate this file with moq.
+var _ storageService = &storageServiceMock{}
+
+// storageServiceMock is a mock implementation of storageService.
+//
+//	func TestSomethingThatUsesstorageService(t *testing.T) {
+//
+//		// make and configure a mocked storageService
+//		mockedstorageService := &storageServiceMock{
+//			SaveMetadataFunc: func(ctx context.Context, metadata entity.Metadata) error {
+//				panic("mock out the SaveMetadata method")
+//			},
+//		}
+//
+//		// use mockedstorageService in code that requires storageService
+//		// and then make assertions.
+//
+//	}
+type storageServiceMock struct {
+	// SaveMetadataFunc mocks the SaveMetadata method.
+	SaveMetadataFunc func(ctx context.Context, metadata entity.Metadata) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// SaveMetadata holds details about calls to the SaveMetadata method.
+		SaveMetadata []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Metadata is the metadata argument value.
+			Metadata entity.Metadata
+		}
+	}
+	lockSaveMetadata sync.RWMutex
+}
+
+// SaveMetadata calls SaveMetadataFunc.
+func (mock *storageServiceMock) SaveMetadata(ctx context.Context, metadata entity.Metadata) error {
+	if mock.SaveMetadataFunc == nil {
+		panic("storageServiceMock.SaveMetadataFunc: method is nil but storageService.SaveMetadata was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Metadata entity.Metadata
+	}{
+		Ctx:      ctx,
+		Metadata: metadata,
+	}
+	mock.lockSaveMetadata.Lock()
+	mock.calls.SaveMetadata = append(mock.calls.SaveMetadata, callInfo)
+	mock.lockSaveMetadata.Unlock()
+	return mock.SaveMetadataFunc(ctx, metadata)
+}
+
+// SaveMetadataCalls gets all the calls that were made to SaveMetadata.
+// Check the length with:
+//
+//	len(mockedstorageService.SaveMetadataCalls())
+func (mock *storageServiceMock) SaveMetadataCalls() []struct {
+	Ctx      context.Context
+	Metadata entity.Metadata
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Metadata entity.Metadata
+	}
+	mock.lockSaveMetadata.RLock()
+	calls = mock.calls.SaveMetadata
+	mock.lockSaveMetadata.RUnlock()
+	return calls
+}
@@ -0,0 +1,83 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+	"github.com/arxon31/metrics-collector/pkg/logger"
+)
+
+const bearerPrefix = "Bearer "
+
+// TenantHeader selects tenant namespace for admin requests
+const TenantHeader = "X-Tenant"
+
+type authenticator interface {
+	Enabled() bool
+	Authenticate(token string) (auth.Identity, error)
+}
+
+type authMiddleware struct {
+	authenticator authenticator
+}
+
+func NewAuthMiddleware(authenticator authenticator) *authMiddleware {
+	return &authMiddleware{
+		authenticator: authenticator,
+	}
+}
+
+// Require middleware allows only requests authenticated with a role granting required one.
+// Reader and writer endpoints are open if authentication is disabled, admin endpoints are closed.
+func (a *authMiddleware) Require(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if role != auth.RoleAdmin && !a.authenticator.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok {
+				resterrs.WriteUnauthorized(w, r, "bearer token is required")
+				return
+			}
+
+			identity, err := a.authenticator.Authenticate(token)
+			if err != nil {
+				logger.Logger.Errorln("authentication failed for:", r.RemoteAddr, err)
+				resterrs.WriteUnauthorized(w, r, err.Error())
+				return
+			}
+
+			if !auth.Allows(identity.Role, role) {
+				logger.Logger.Errorln("access denied for:", identity.Subject, r.RemoteAddr)
+				resterrs.WriteForbidden(w, r, "role "+identity.Role+" is not allowed", role)
+				return
+			}
+
+			ctx := auth.WithRole(r.Context(), identity.Role)
+			ctx = auth.WithSubject(ctx, identity.Subject+"@"+r.RemoteAddr)
+			if identity.Tenant != "" {
+				ctx = auth.WithTenant(ctx, identity.Tenant)
+			}
+			// admin acts on default tenant unless X-Tenant header is provided
+			if tenant := r.Header.Get(TenantHeader); tenant != "" && identity.Role == auth.RoleAdmin {
+				ctx = auth.WithTenant(ctx, tenant)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(header, bearerPrefix))
+	return token, token != ""
+}
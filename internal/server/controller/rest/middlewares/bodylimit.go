@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+)
+
+type bodyLimitKey struct{}
+
+type bodyLimitMiddleware struct {
+	maxBodySize int64
+	paths       map[string]int64
+}
+
+// NewBodyLimitMiddleware creates middleware limiting request bodies to maxBodySize bytes as they are read from the wire,
+// paths overrides the limit for requests to exact paths, zero limit means unlimited
+func NewBodyLimitMiddleware(maxBodySize int64, paths map[string]int64) *bodyLimitMiddleware {
+	return &bodyLimitMiddleware{
+		maxBodySize: maxBodySize,
+		paths:       paths,
+	}
+}
+
+// WithBodyLimit middleware stops reading body after the limit regardless of Content-Length, so chunked bodies
+// can not exhaust memory of middlewares buffering them. It must be the outermost middleware reading the body,
+// the limit is put to request context for the inner ones.
+func (b *bodyLimitMiddleware) WithBodyLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := b.maxBodySize
+		if pathLimit, ok := b.paths[r.URL.Path]; ok {
+			limit = pathLimit
+		}
+		r = r.WithContext(context.WithValue(r.Context(), bodyLimitKey{}, limit))
+
+		if limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.ContentLength > limit {
+			resterrs.Write(w, r, resterrs.WithStatus(http.StatusRequestEntityTooLarge, resterrs.CodeTooLarge, errBodyTooLarge))
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bodyLimit returns body size limit of request set by body limit middleware, or fallback if there is none
+func bodyLimit(r *http.Request, fallback int64) int64 {
+	if limit, ok := r.Context().Value(bodyLimitKey{}).(int64); ok {
+		return limit
+	}
+	return fallback
+}
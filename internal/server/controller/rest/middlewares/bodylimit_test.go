@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/signing"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	keys, err := signing.ParseKeyring("secret")
+	require.NoError(t, err)
+
+	hashing := NewHashingMiddleware(keys, time.Minute, true, false)
+	compressing := NewCompressingMiddleware(16)
+	h := NewBodyLimitMiddleware(16, map[string]int64{"/admin/restore": 64}).WithBodyLimit(hashing.WithHash(compressing.WithCompress(okHandler)))
+
+	do := func(path string, body []byte, contentLength int64) int {
+		req := httptest.NewRequest(http.MethodPost, path, io.NopCloser(bytes.NewReader(body)))
+		req.ContentLength = contentLength
+		req.Header.Set(signing.HashHeader, signing.SignBody("secret", body))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	body := bytes.Repeat([]byte("a"), 32)
+
+	t.Run("content_length_over_limit", func(t *testing.T) {
+		require.Equal(t, http.StatusRequestEntityTooLarge, do("/updates/", body, int64(len(body))))
+	})
+
+	t.Run("chunked_body_over_limit", func(t *testing.T) {
+		require.Equal(t, http.StatusRequestEntityTooLarge, do("/updates/", body, -1))
+	})
+
+	t.Run("body_within_limit", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, do("/updates/", body[:16], -1))
+	})
+
+	t.Run("path_with_own_limit", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, do("/admin/restore", body, -1))
+		require.Equal(t, http.StatusRequestEntityTooLarge, do("/admin/restore", bytes.Repeat(body, 3), -1))
+	})
+}
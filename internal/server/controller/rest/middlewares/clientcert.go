@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"crypto/x509"
+	"net/http"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+)
+
+const certSubjectPrefix = "cert:"
+
+type clientCertMiddleware struct{}
+
+func NewClientCertMiddleware() *clientCertMiddleware {
+	return &clientCertMiddleware{}
+}
+
+// WithClientCert middleware maps verified client certificate to agent identity.
+// Agent ID header is replaced with certificate name so it can not be spoofed, subject is used unless request is authenticated by token.
+func (c *clientCertMiddleware) WithClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		name := certName(r.TLS.VerifiedChains[0][0])
+		if name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r.Header.Set(auth.AgentIDHeader, name)
+		ctx := auth.WithSubject(r.Context(), certSubjectPrefix+name)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// certName returns common name of certificate, then the first DNS or URI subject alternative name
+func certName(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+)
+
+func TestClientCertMiddleware(t *testing.T) {
+	var agentID, subject string
+	h := NewClientCertMiddleware().WithClientCert(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agentID = r.Header.Get(auth.AgentIDHeader)
+		subject = auth.Subject(r.Context())
+	}))
+
+	agentURI, err := url.Parse("spiffe://metrics/agent-3")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want string
+	}{
+		{name: "common_name", cert: &x509.Certificate{Subject: pkix.Name{CommonName: "agent-1"}, DNSNames: []string{"host-1"}}, want: "agent-1"},
+		{name: "dns_san", cert: &x509.Certificate{DNSNames: []string{"agent-2"}}, want: "agent-2"},
+		{name: "uri_san", cert: &x509.Certificate{URIs: []*url.URL{agentURI}}, want: "spiffe://metrics/agent-3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/updates/", nil)
+			req.Header.Set(auth.AgentIDHeader, "spoofed")
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tt.cert}}}
+
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			require.Equal(t, tt.want, agentID)
+			require.Equal(t, "cert:"+tt.want, subject)
+		})
+	}
+
+	t.Run("without_certificate", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", nil)
+		req.Header.Set(auth.AgentIDHeader, "agent-4")
+
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		require.Equal(t, "agent-4", agentID)
+		require.Equal(t, "anonymous", subject)
+	})
+}
@@ -1,15 +1,20 @@
 package middlewares
 
 import (
+	"bytes"
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
 	"github.com/arxon31/metrics-collector/pkg/logger"
 )
 
+var errBodyTooLarge = errors.New("request body is too large")
+
 var compressibleTypes = map[string]bool{
 	"text/html":        true,
 	"application/json": true,
@@ -17,10 +22,15 @@ var compressibleTypes = map[string]bool{
 }
 
 type compressingMiddleware struct {
+	maxBodySize int64
 }
 
-func NewCompressingMiddleware() *compressingMiddleware {
-	return &compressingMiddleware{}
+// NewCompressingMiddleware creates middleware rejecting request bodies larger than maxBodySize after decompression,
+// limit set by body limit middleware takes precedence, zero maxBodySize means unlimited
+func NewCompressingMiddleware(maxBodySize int64) *compressingMiddleware {
+	return &compressingMiddleware{
+		maxBodySize: maxBodySize,
+	}
 }
 
 // WithCompress middleware compresses and decompresses responses
@@ -39,11 +49,17 @@ func (c *compressingMiddleware) WithCompress(next http.Handler) http.Handler {
 			w.Header().Set("Content-Encoding", "gzip")
 
 		}
+		maxBodySize := bodyLimit(r, c.maxBodySize)
+		if maxBodySize > 0 && r.ContentLength > maxBodySize {
+			resterrs.Write(writer, r, resterrs.WithStatus(http.StatusRequestEntityTooLarge, resterrs.CodeTooLarge, errBodyTooLarge))
+			return
+		}
+
 		if strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
 			gzipReader, err := gzip.NewReader(r.Body)
 			if err != nil {
 				logger.Logger.Error(err)
-				http.Error(w, fmt.Sprintf("can not create gzip reader: %s", err), http.StatusInternalServerError)
+				resterrs.Write(writer, r, resterrs.WithStatus(http.StatusBadRequest, resterrs.CodeBadRequest, fmt.Errorf("can not create gzip reader: %w", err)))
 				return
 			}
 			defer gzipReader.Close()
@@ -52,6 +68,28 @@ func (c *compressingMiddleware) WithCompress(next http.Handler) http.Handler {
 
 		}
 
+		if maxBodySize > 0 {
+			// body is read before handler so gzip bombs are stopped after maxBodySize decompressed bytes
+			buf, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+			var maxBytes *http.MaxBytesError
+			if errors.As(err, &maxBytes) {
+				logger.Logger.Errorln("request body exceeds limit from:", r.RemoteAddr)
+				resterrs.Write(writer, r, resterrs.WithStatus(http.StatusRequestEntityTooLarge, resterrs.CodeTooLarge, errBodyTooLarge))
+				return
+			}
+			if err != nil {
+				logger.Logger.Error(err)
+				resterrs.Write(writer, r, resterrs.WithStatus(http.StatusBadRequest, resterrs.CodeBadRequest, fmt.Errorf("can not read body: %w", err)))
+				return
+			}
+			if int64(len(buf)) > maxBodySize {
+				logger.Logger.Errorln("request body exceeds limit from:", r.RemoteAddr)
+				resterrs.Write(writer, r, resterrs.WithStatus(http.StatusRequestEntityTooLarge, resterrs.CodeTooLarge, errBodyTooLarge))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(buf))
+		}
+
 		next.ServeHTTP(writer, r)
 	})
 }
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressingMiddleware_MaxBodySize(t *testing.T) {
+	h := NewCompressingMiddleware(4096).WithCompress(okHandler)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(make([]byte, 1<<20))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.Less(t, buf.Len(), 4096, "compressed body fits the limit")
+
+	req := httptest.NewRequest(http.MethodPost, "/updates/", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(make([]byte, 512)))
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+}
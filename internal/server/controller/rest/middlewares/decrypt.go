@@ -2,67 +2,71 @@ package middlewares
 
 import (
 	"bytes"
-	"crypto/rand"
-	"crypto/rsa"
+	"errors"
 	"io"
 	"net/http"
 
+	"github.com/arxon31/metrics-collector/internal/encrypting"
 	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
 	"github.com/arxon31/metrics-collector/pkg/logger"
 )
 
+var (
+	errEncryptionDisabled = errors.New("payload encryption is not configured")
+	errUnknownEncryption  = errors.New("unknown encryption key id")
+	errDecrypt            = errors.New("can not decrypt payload")
+)
+
 type decryptingMiddleware struct {
-	cryptoKey *rsa.PrivateKey
+	keys *encrypting.Keyring
 }
 
-func NewDecryptingMiddleware(cryptoKey *rsa.PrivateKey) *decryptingMiddleware {
+// NewDecryptingMiddleware creates middleware decrypting payloads with keys from keyring, nil keyring disables decryption
+func NewDecryptingMiddleware(keys *encrypting.Keyring) *decryptingMiddleware {
 	return &decryptingMiddleware{
-		cryptoKey: cryptoKey,
+		keys: keys,
 	}
 }
 
+// WithDecrypt middleware decrypts body of requests tagged with encryption key ID, other requests are passed as is.
+// It must be placed after signature verification and before decompression.
 func (d *decryptingMiddleware) WithDecrypt(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyID := r.Header.Get(encrypting.KeyIDHeader)
+		if keyID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if d.keys == nil {
+			resterrs.Write(w, r, resterrs.WithStatus(http.StatusBadRequest, resterrs.CodeBadRequest, errEncryptionDisabled))
+			return
+		}
+
+		key, ok := d.keys.Key(keyID)
+		if !ok {
+			logger.Logger.Errorln("payload encrypted with unknown key from:", r.RemoteAddr, keyID)
+			resterrs.Write(w, r, resterrs.WithStatus(http.StatusBadRequest, resterrs.CodeBadRequest, errUnknownEncryption))
+			return
+		}
+
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
 			logger.Logger.Error(err)
-			http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+			resterrs.Write(w, r, err)
 			return
 		}
 
-		decryptedBody, err := d.decrypt(body)
+		decryptedBody, err := encrypting.Decrypt(key, body)
 		if err != nil {
-			logger.Logger.Error(err)
-			http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+			logger.Logger.Errorln("can not decrypt payload from:", r.RemoteAddr, err)
+			resterrs.Write(w, r, resterrs.WithStatus(http.StatusBadRequest, resterrs.CodeBadRequest, errDecrypt))
 			return
 		}
 
 		r.Body = io.NopCloser(bytes.NewReader(decryptedBody))
+		r.ContentLength = int64(len(decryptedBody))
 
 		next.ServeHTTP(w, r)
 	})
 }
-
-func (d *decryptingMiddleware) decrypt(body []byte) ([]byte, error) {
-	chunkSize := d.cryptoKey.Size() - 11
-
-	chunks := len(body) / chunkSize
-
-	var decryptedData bytes.Buffer
-
-	for i := 0; i <= chunks; i++ {
-		start := i * chunkSize
-		end := start + chunkSize
-		if end > len(body) {
-			end = len(body)
-		}
-
-		decryptedChunk, err := rsa.DecryptPKCS1v15(rand.Reader, d.cryptoKey, body[start:end])
-		if err != nil {
-			return nil, err
-		}
-		decryptedData.Write(decryptedChunk)
-	}
-
-	return decryptedData.Bytes(), nil
-}
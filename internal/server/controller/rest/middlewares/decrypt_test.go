@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/encrypting"
+)
+
+func TestDecryptingMiddleware(t *testing.T) {
+	service := encrypting.NewService(t.TempDir())
+	require.NoError(t, service.GenerateIfNotExist())
+	keys, err := service.LoadKeyring()
+	require.NoError(t, err)
+
+	var body []byte
+	h := NewDecryptingMiddleware(keys).WithDecrypt(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+
+	payload := []byte(`[{"id":"PollCount","type":"counter","delta":1}]`)
+	public := keys.PublicKey()
+	encrypted, err := encrypting.Encrypt(public.Key, payload)
+	require.NoError(t, err)
+
+	do := func(keyID string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body))
+		if keyID != "" {
+			req.Header.Set(encrypting.KeyIDHeader, keyID)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("decrypts_tagged_payload", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, do(public.ID, encrypted).Code)
+		require.Equal(t, payload, body)
+	})
+
+	t.Run("plain_payload_is_passed", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, do("", payload).Code)
+		require.Equal(t, payload, body)
+	})
+
+	t.Run("unknown_key_id", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, do("0011223344556677", encrypted).Code)
+	})
+
+	t.Run("corrupt_payload", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, do(public.ID, payload).Code)
+	})
+
+	t.Run("encryption_disabled", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(encrypted))
+		req.Header.Set(encrypting.KeyIDHeader, public.ID)
+		NewDecryptingMiddleware(nil).WithDecrypt(okHandler).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
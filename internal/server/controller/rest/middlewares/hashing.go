@@ -2,95 +2,224 @@ package middlewares
 
 import (
 	"bytes"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
+	"errors"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+	"github.com/arxon31/metrics-collector/internal/signing"
 	"github.com/arxon31/metrics-collector/pkg/logger"
 )
 
-const hashHeader = "HashSHA256"
+const maxNonces = 1 << 20
+
+var (
+	errUnknownKeyID = errors.New("unknown signature key id")
+	errBadTimestamp = errors.New("signature timestamp is outside of allowed skew")
+	errNoNonce      = errors.New("signature nonce is required")
+	errBadSignature = errors.New("signs is not equal")
+	errLegacySign   = errors.New("signature without key id, timestamp and nonce is not allowed")
+	errNoTimestamp  = errors.New("signature timestamp is required")
+	errUnsigned     = errors.New("request signature is required")
+)
 
 type hashingMiddleware struct {
-	key string
+	keys        *signing.Keyring
+	skew        time.Duration
+	allowLegacy bool
+	strict      bool
+	nonces      *signing.NonceCache
+	streamed    map[string]bool
+	now         func() time.Time
 }
 
-func NewHashingMiddleware(key string) *hashingMiddleware {
+// NewHashingMiddleware creates middleware verifying request signatures made by any key from keyring.
+// Signed timestamp must be within skew from server time, nonces are remembered for twice the skew to reject replays.
+// Deprecated body only signatures are accepted and logged if allowLegacy is set, unsigned writes are rejected if strict is set.
+// Responses to streamed paths are not buffered and therefore not signed, requests to them are still verified.
+func NewHashingMiddleware(keys *signing.Keyring, skew time.Duration, allowLegacy, strict bool, streamed ...string) *hashingMiddleware {
+	paths := make(map[string]bool, len(streamed))
+	for _, path := range streamed {
+		paths[path] = true
+	}
+
 	return &hashingMiddleware{
-		key: key,
+		keys:        keys,
+		skew:        skew,
+		allowLegacy: allowLegacy,
+		strict:      strict,
+		nonces:      signing.NewNonceCache(2*skew, maxNonces),
+		streamed:    paths,
+		now:         time.Now,
 	}
 }
 
+// hashingResponseWriter buffers response so signature can be set in headers before the body is sent
 type hashingResponseWriter struct {
 	http.ResponseWriter
-	key string
+	status int
+	body   bytes.Buffer
+}
+
+func (w *hashingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *hashingResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(data)
 }
 
-// WithHash middleware adds sha256 hash to request body if key is not empty
+// WithHash middleware verifies signature of request body and signs every response if keys are provided.
+// It must wrap compressing middleware so both signatures cover the body exactly as it is sent over the wire.
 func (h *hashingMiddleware) WithHash(next http.Handler) http.Handler {
-	if h.key == "" {
+	if h.keys.Empty() {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			next.ServeHTTP(w, r)
 		})
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get(hashHeader) == "" {
-			logger.Logger.Debug("body after no hashing: ", r.Body)
-			next.ServeHTTP(w, r)
+		if h.streamed[r.URL.Path] {
+			h.serve(w, r, next)
 			return
 		}
 
-		buf, err := io.ReadAll(r.Body)
-		if err != nil {
-			logger.Logger.Error(err)
-			http.Error(w, "can not read body", http.StatusInternalServerError)
-			return
+		hw := &hashingResponseWriter{ResponseWriter: w}
+		key, legacy := h.serve(hw, r, next)
+		h.sign(hw, r, key, legacy)
+	})
+}
+
+// serve verifies request and calls next handler, it returns key for signing the response
+func (h *hashingMiddleware) serve(w http.ResponseWriter, r *http.Request, next http.Handler) (key signing.Key, legacy bool) {
+	key, _ = h.keys.Current()
+
+	if r.Header.Get(signing.HashHeader) == "" {
+		if h.strict && isWrite(r.Method) {
+			logger.Logger.Errorln("unsigned request rejected from:", r.RemoteAddr)
+			resterrs.Write(w, r, resterrs.WithStatus(http.StatusForbidden, resterrs.CodeForbidden, errUnsigned))
+			return key, false
 		}
+		next.ServeHTTP(w, r)
+		return key, false
+	}
 
-		sign, err := countHash(buf, h.key)
-		if err != nil {
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		logger.Logger.Error(err)
+		resterrs.Write(w, r, err)
+		return key, false
+	}
+
+	verifiedBy, err := h.verify(r, buf)
+	if err != nil {
+		if errors.Is(err, signing.ErrNonceCacheFull) {
 			logger.Logger.Error(err)
-			http.Error(w, "can not count hash for body", http.StatusInternalServerError)
-			return
-		}
-		signFromReq := r.Header.Get(hashHeader)
-		if sign != signFromReq {
-			logger.Logger.Error("signs is not equal")
-			http.Error(w, "signs is not equal", http.StatusForbidden)
-			return
+			resterrs.Write(w, r, resterrs.WithStatus(http.StatusServiceUnavailable, resterrs.CodeUnavailable, err))
+			return key, false
 		}
+		logger.Logger.Errorln("signature verification failed for:", r.RemoteAddr, err)
+		resterrs.Write(w, r, resterrs.WithStatus(http.StatusForbidden, resterrs.CodeForbidden, err))
+		return key, false
+	}
 
-		hw := &hashingResponseWriter{
-			ResponseWriter: w,
-			key:            h.key,
-		}
+	r.Body = io.NopCloser(bytes.NewReader(buf))
 
-		r.Body = io.NopCloser(bytes.NewReader(buf))
+	next.ServeHTTP(w, r)
 
-		next.ServeHTTP(hw, r)
-	})
+	// response is signed with the key of request so clients holding a rotated out key can still verify it
+	return verifiedBy, r.Header.Get(signing.TimestampHeader) == ""
 }
 
-func (w *hashingResponseWriter) Write(data []byte) (size int, err error) {
-	hash, err := countHash(data, w.key)
-	if err != nil {
-		return 0, err
+// sign sets signature headers and sends buffered response
+func (h *hashingMiddleware) sign(w *hashingResponseWriter, r *http.Request, key signing.Key, legacy bool) {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	body := w.body.Bytes()
+
+	header := w.ResponseWriter.Header()
+	if legacy {
+		header.Set(signing.HashHeader, signing.SignBody(key.Secret, body))
+	} else {
+		timestamp := strconv.FormatInt(h.now().Unix(), 10)
+		nonce := r.Header.Get(signing.NonceHeader)
+		header.Set(signing.TimestampHeader, timestamp)
+		if nonce != "" {
+			header.Set(signing.NonceHeader, nonce)
+		}
+		header.Set(signing.HashHeader, signing.SignResponse(key.Secret, status, r.Method, r.URL.RequestURI(), timestamp, nonce, body))
+	}
+	if key.ID != "" {
+		header.Set(signing.KeyIDHeader, key.ID)
 	}
 
-	w.ResponseWriter.Header().Add("HashSHA256", hash)
+	w.ResponseWriter.WriteHeader(status)
+	w.ResponseWriter.Write(body)
+}
 
-	return w.ResponseWriter.Write(data)
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
 }
 
-func countHash(data []byte, key string) (hash string, err error) {
-	var sign []byte
+func (h *hashingMiddleware) verify(r *http.Request, body []byte) (signing.Key, error) {
+	signFromReq := r.Header.Get(signing.HashHeader)
+	keyID := r.Header.Get(signing.KeyIDHeader)
+	timestamp := r.Header.Get(signing.TimestampHeader)
+
+	if keyID == "" && timestamp == "" {
+		if !h.allowLegacy {
+			return signing.Key{}, errLegacySign
+		}
+		for _, key := range h.keys.Keys() {
+			if signing.Equal(signing.SignBody(key.Secret, body), signFromReq) {
+				logger.Logger.Warnf("accepted deprecated body only signature of %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+				return key, nil
+			}
+		}
+		return signing.Key{}, errBadSignature
+	}
+
+	key, ok := h.keys.Key(keyID)
+	if !ok {
+		return signing.Key{}, errUnknownKeyID
+	}
+
+	if timestamp == "" {
+		return signing.Key{}, errNoTimestamp
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return signing.Key{}, errBadTimestamp
+	}
+	now := h.now()
+	if math.Abs(now.Sub(time.Unix(unix, 0)).Seconds()) > h.skew.Seconds() {
+		return signing.Key{}, errBadTimestamp
+	}
+
+	nonce := r.Header.Get(signing.NonceHeader)
+	if nonce == "" {
+		return signing.Key{}, errNoNonce
+	}
 
-	h := hmac.New(sha256.New, []byte(key))
-	h.Write(data)
-	sign = h.Sum(nil)
+	sign := signing.Sign(key.Secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	if !signing.Equal(sign, signFromReq) {
+		return signing.Key{}, errBadSignature
+	}
 
-	return base64.StdEncoding.EncodeToString(sign), nil
+	// nonce is remembered only for valid signatures so forged requests can not fill the cache
+	return key, h.nonces.Add(key.ID+":"+nonce, now)
 }
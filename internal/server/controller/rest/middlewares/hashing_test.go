@@ -0,0 +1,188 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/signing"
+)
+
+func TestHashingMiddleware(t *testing.T) {
+	keys, err := signing.ParseKeyring("v2:new,v1:old")
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewHashingMiddleware(keys, time.Minute, false, false)
+	m.now = func() time.Time { return now }
+	h := m.WithHash(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	body := []byte(`[{"id":"PollCount","type":"counter","delta":1}]`)
+
+	newRequest := func(keyID, secret string, at time.Time, nonce string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body))
+		ts := strconv.FormatInt(at.Unix(), 10)
+		req.Header.Set(signing.KeyIDHeader, keyID)
+		req.Header.Set(signing.TimestampHeader, ts)
+		req.Header.Set(signing.NonceHeader, nonce)
+		req.Header.Set(signing.HashHeader, signing.Sign(secret, http.MethodPost, "/updates/", ts, nonce, body))
+		return req
+	}
+
+	do := func(req *http.Request) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("previous_key_is_accepted", func(t *testing.T) {
+		rr := do(newRequest("v1", "old", now, "n1"))
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "v1", rr.Header().Get(signing.KeyIDHeader), "response is signed with key of request")
+
+		ts := rr.Header().Get(signing.TimestampHeader)
+		sign := signing.SignResponse("old", http.StatusOK, http.MethodPost, "/updates/", ts, "n1", rr.Body.Bytes())
+		require.Equal(t, sign, rr.Header().Get(signing.HashHeader))
+	})
+
+	t.Run("replay_is_rejected", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, do(newRequest("v2", "new", now, "n2")).Code)
+
+		rr := do(newRequest("v2", "new", now, "n2"))
+		require.Equal(t, http.StatusForbidden, rr.Code)
+
+		ts := rr.Header().Get(signing.TimestampHeader)
+		sign := signing.SignResponse("new", http.StatusForbidden, http.MethodPost, "/updates/", ts, "n2", rr.Body.Bytes())
+		require.Equal(t, sign, rr.Header().Get(signing.HashHeader), "error response is signed")
+	})
+
+	t.Run("unknown_key_id", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, do(newRequest("v0", "new", now, "n3")).Code)
+	})
+
+	t.Run("timestamp_outside_skew", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, do(newRequest("v2", "new", now.Add(-2*time.Minute), "n4")).Code)
+	})
+
+	t.Run("signature_covers_path", func(t *testing.T) {
+		req := newRequest("v2", "new", now, "n5")
+		req.URL.Path = "/update/"
+		require.Equal(t, http.StatusForbidden, do(req).Code)
+	})
+
+	t.Run("signature_covers_query", func(t *testing.T) {
+		req := newRequest("v2", "new", now, "n6")
+		req.URL.RawQuery = "tenant=other"
+		require.Equal(t, http.StatusForbidden, do(req).Code)
+	})
+
+	t.Run("legacy_signature_is_rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body))
+		req.Header.Set(signing.HashHeader, signing.SignBody("new", body))
+		require.Equal(t, http.StatusForbidden, do(req).Code)
+	})
+
+	t.Run("legacy_signature_is_accepted_if_allowed", func(t *testing.T) {
+		h := NewHashingMiddleware(keys, time.Minute, true, false).WithHash(okHandler)
+
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body))
+		req.Header.Set(signing.HashHeader, signing.SignBody("old", body))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestHashingMiddleware_Strict(t *testing.T) {
+	keys, err := signing.ParseKeyring("secret")
+	require.NoError(t, err)
+
+	h := NewHashingMiddleware(keys, time.Minute, false, true).WithHash(okHandler)
+
+	t.Run("unsigned_write_is_rejected", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader([]byte("[]"))))
+		require.Equal(t, http.StatusForbidden, rr.Code)
+		require.NotEmpty(t, rr.Header().Get(signing.HashHeader))
+	})
+
+	t.Run("unsigned_read_is_signed", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		ts := rr.Header().Get(signing.TimestampHeader)
+		sign := signing.SignResponse("secret", http.StatusOK, http.MethodGet, "/", ts, "", nil)
+		require.Equal(t, sign, rr.Header().Get(signing.HashHeader))
+	})
+}
+
+func TestHashingMiddleware_SignsWireBytes(t *testing.T) {
+	keys, err := signing.ParseKeyring("secret")
+	require.NoError(t, err)
+
+	hashing := NewHashingMiddleware(keys, time.Minute, false, true)
+	compressing := NewCompressingMiddleware(0)
+	h := hashing.WithHash(compressing.WithCompress(okHandler))
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(`[]`))
+	zw.Close()
+
+	do := func(signed []byte) int {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(compressed.Bytes()))
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		nonce, err := signing.NewNonce()
+		require.NoError(t, err)
+		req.Header.Set("Content-Encoding", "gzip")
+		req.Header.Set(signing.TimestampHeader, ts)
+		req.Header.Set(signing.NonceHeader, nonce)
+		req.Header.Set(signing.HashHeader, signing.Sign("secret", http.MethodPost, "/updates/", ts, nonce, signed))
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	require.Equal(t, http.StatusOK, do(compressed.Bytes()))
+	require.Equal(t, http.StatusForbidden, do([]byte(`[]`)), "decompressed body is not what was sent")
+}
+
+func TestHashingMiddleware_Streamed(t *testing.T) {
+	keys, err := signing.ParseKeyring("secret")
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	h := NewHashingMiddleware(keys, time.Minute, false, true, "/admin/snapshot").WithHash(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("chunk"))
+		require.Equal(t, "chunk", rr.Body.String(), "streamed response is not buffered")
+		w.Write([]byte("chunk"))
+	}))
+
+	t.Run("response_is_not_signed", func(t *testing.T) {
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/snapshot", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "chunkchunk", rr.Body.String())
+		require.Empty(t, rr.Header().Get(signing.HashHeader))
+	})
+
+	t.Run("request_is_verified", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/snapshot", nil)
+		req.Header.Set(signing.HashHeader, "forged")
+		req.Header.Set(signing.TimestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+		req.Header.Set(signing.NonceHeader, "n1")
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/arxon31/metrics-collector/internal/requestid"
 	"github.com/arxon31/metrics-collector/pkg/logger"
 )
 
@@ -39,6 +40,7 @@ func (l *loggingMiddleware) WithLog(next http.Handler) http.Handler {
 		duration := time.Since(start)
 
 		logger.Logger.Infoln(
+			"request_id", requestid.From(r.Context()),
 			"uri", r.RequestURI,
 			"method", r.Method,
 			"execution_time", duration,
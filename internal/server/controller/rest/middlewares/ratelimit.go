@@ -0,0 +1,139 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+	"github.com/arxon31/metrics-collector/pkg/logger"
+)
+
+var errRateLimited = errors.New("too many requests")
+
+const (
+	bucketIdleTTL = 10 * time.Minute
+	sweepEvery    = time.Minute
+)
+
+type bucket struct {
+	tokens float64
+	seenAt time.Time
+}
+
+type rateLimitMiddleware struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+}
+
+// NewRateLimitMiddleware creates token bucket limiter refilled with rps tokens per second up to burst, zero rps disables limiting
+func NewRateLimitMiddleware(rps float64, burst int) *rateLimitMiddleware {
+	if burst < 1 {
+		burst = int(math.Ceil(rps))
+	}
+
+	return &rateLimitMiddleware{
+		rate:    rps,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// WithRateLimit middleware rejects requests exceeding client rate with 429 Too Many Requests.
+// It must run after auth and tenant middlewares: client is identified by verified bearer token, then by tenant
+// of verified api token, unauthenticated clients are identified by IP address.
+func (l *rateLimitMiddleware) WithRateLimit(next http.Handler) http.Handler {
+	if l.rate <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := l.clientKey(r)
+
+		ok, retryAfter := l.take(key)
+		if !ok {
+			logger.Logger.Errorln("rate limit exceeded for:", r.RemoteAddr)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			resterrs.Write(w, r, resterrs.WithStatus(http.StatusTooManyRequests, resterrs.CodeRateLimited, errRateLimited))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take removes a token from client bucket, if bucket is empty returns time until the next token
+func (l *rateLimitMiddleware) take(key string) (bool, time.Duration) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, seenAt: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.seenAt).Seconds()*l.rate)
+	b.seenAt = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// sweep drops buckets of clients which were idle long enough to refill, must be called under lock
+func (l *rateLimitMiddleware) sweep(now time.Time) {
+	if now.Sub(l.sweptAt) < sweepEvery {
+		return
+	}
+	l.sweptAt = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.seenAt) > bucketIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *rateLimitMiddleware) clientKey(r *http.Request) string {
+	if auth.Role(r.Context()) != "" {
+		if token, ok := bearerToken(r); ok {
+			return "token:" + hashKey(token)
+		}
+	}
+	if tenant := auth.Tenant(r.Context()); tenant != auth.DefaultTenant {
+		return "tenant:" + tenant
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// hashKey prevents keeping raw tokens in limiter memory
+func hashKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestRateLimitMiddleware(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimitMiddleware(1, 2)
+	l.now = func() time.Time { return now }
+	h := l.WithRateLimit(okHandler)
+
+	do := func(remoteAddr, tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Agent-ID", remoteAddr)
+		if tenant != "" {
+			req = req.WithContext(auth.WithTenant(req.Context(), tenant))
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, do("10.0.0.1:1000", "").Code)
+	require.Equal(t, http.StatusOK, do("10.0.0.1:1001", "").Code)
+
+	rr := do("10.0.0.1:1002", "")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.Equal(t, "1", rr.Header().Get("Retry-After"))
+
+	require.Equal(t, http.StatusOK, do("10.0.0.2:1000", "").Code, "other client has its own bucket")
+	require.Equal(t, http.StatusOK, do("10.0.0.1:1003", "tenant-1").Code, "verified tenant is keyed by its name")
+
+	now = now.Add(time.Second)
+	require.Equal(t, http.StatusOK, do("10.0.0.1:1004", "").Code, "bucket is refilled")
+}
+
+func TestRateLimitMiddleware_IgnoresUnverifiedTokens(t *testing.T) {
+	l := NewRateLimitMiddleware(1, 1)
+	h := l.WithRateLimit(okHandler)
+
+	do := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", nil)
+		req.RemoteAddr = "10.0.0.1:1000"
+		req.Header.Set(auth.APITokenHeader, token)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	require.Equal(t, http.StatusOK, do("a"))
+	require.Equal(t, http.StatusTooManyRequests, do("b"), "rotating unverified tokens does not give new buckets")
+}
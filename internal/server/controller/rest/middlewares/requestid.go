@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/arxon31/metrics-collector/internal/requestid"
+)
+
+type requestIDMiddleware struct{}
+
+func NewRequestIDMiddleware() *requestIDMiddleware {
+	return &requestIDMiddleware{}
+}
+
+// WithRequestID middleware keeps valid client request ID or generates a new one and echoes it in response
+func (m *requestIDMiddleware) WithRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.With(r.Context(), id)))
+	})
+}
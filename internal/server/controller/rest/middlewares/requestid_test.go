@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/requestid"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := NewRequestIDMiddleware().WithRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestid.From(r.Context())
+	}))
+
+	t.Run("keeps_client_id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(requestid.Header, "agent-1.42")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, "agent-1.42", seen)
+		require.Equal(t, "agent-1.42", rr.Header().Get(requestid.Header))
+	})
+
+	t.Run("replaces_invalid_id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(requestid.Header, "bad id\n")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		require.NotEqual(t, "bad id\n", seen)
+		require.True(t, requestid.Valid(seen))
+		require.Equal(t, seen, rr.Header().Get(requestid.Header))
+	})
+}
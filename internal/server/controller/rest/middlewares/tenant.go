@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+	"github.com/arxon31/metrics-collector/pkg/logger"
+)
+
+type tokenResolver interface {
+	ResolveToken(ctx context.Context, token string) (string, error)
+}
+
+type tenantMiddleware struct {
+	resolver tokenResolver
+}
+
+func NewTenantMiddleware(resolver tokenResolver) *tenantMiddleware {
+	return &tenantMiddleware{
+		resolver: resolver,
+	}
+}
+
+// WithTenant middleware puts tenant owning api token to the request context, requests without token use default tenant.
+// Bearer token is treated as api token only if it was not used for authentication.
+func (t *tenantMiddleware) WithTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(auth.APITokenHeader)
+		if token == "" && auth.Role(r.Context()) == "" {
+			token, _ = bearerToken(r)
+		}
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tenant, err := t.resolver.ResolveToken(r.Context(), token)
+		if err != nil {
+			if errors.Is(err, entity.ErrUnknownToken) {
+				logger.Logger.Errorln("unknown api token from:", r.RemoteAddr)
+				resterrs.WriteUnauthorized(w, r, err.Error())
+				return
+			}
+			logger.Logger.Error(err)
+			resterrs.Write(w, r, err)
+			return
+		}
+
+		ctx := auth.WithTenant(r.Context(), tenant)
+		if auth.Role(ctx) == "" {
+			ctx = auth.WithSubject(ctx, tenant+"@"+r.RemoteAddr)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
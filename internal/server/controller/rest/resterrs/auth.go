@@ -0,0 +1,39 @@
+package resterrs
+
+import (
+	"net/http"
+)
+
+// WriteUnauthorized writes 401 Unauthorized envelope with bearer challenge
+func WriteUnauthorized(w http.ResponseWriter, r *http.Request, reason string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+	Write(w, r, &StatusError{
+		Status:  http.StatusUnauthorized,
+		Code:    CodeUnauthorized,
+		Err:     ErrUnauthorized,
+		Details: details("reason", reason),
+	})
+}
+
+// WriteForbidden writes 403 Forbidden envelope with role required by endpoint
+func WriteForbidden(w http.ResponseWriter, r *http.Request, reason, requiredRole string) {
+	Write(w, r, &StatusError{
+		Status:  http.StatusForbidden,
+		Code:    CodeForbidden,
+		Err:     ErrForbidden,
+		Details: details("reason", reason, "required_role", requiredRole),
+	})
+}
+
+func details(kv ...string) map[string]string {
+	d := make(map[string]string)
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i+1] != "" {
+			d[kv[i]] = kv[i+1]
+		}
+	}
+	if len(d) == 0 {
+		return nil
+	}
+	return d
+}
@@ -0,0 +1,26 @@
+package resterrs
+
+import "github.com/arxon31/metrics-collector/internal/entity"
+
+// BatchItem is outcome of a single batch item in response
+type BatchItem struct {
+	Index      int    `json:"index"`
+	Name       string `json:"id"`
+	MetricType string `json:"type"`
+	Status     string `json:"status"`
+	Code       string `json:"code,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// BatchItems converts batch results to response items, reasons of rejected items are mapped as errors
+func BatchItems(results []entity.BatchResult) []BatchItem {
+	items := make([]BatchItem, len(results))
+	for i, r := range results {
+		items[i] = BatchItem{Index: i, Name: r.Name, MetricType: r.MetricType, Status: r.Status}
+		if r.Err != nil {
+			_, resp := Map(r.Err)
+			items[i].Code, items[i].Reason = resp.Code, r.Err.Error()
+		}
+	}
+	return items
+}
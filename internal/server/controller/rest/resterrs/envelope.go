@@ -0,0 +1,225 @@
+package resterrs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+	"github.com/arxon31/metrics-collector/internal/requestid"
+	"github.com/arxon31/metrics-collector/pkg/logger"
+)
+
+// Codes of error envelope, clients should branch on them instead of messages
+const (
+	CodeBadRequest     = "bad_request"
+	CodeInvalidMetric  = "invalid_metric"
+	CodeNotFound       = "not_found"
+	CodeTypeConflict   = "type_conflict"
+	CodeQuotaExceeded  = "quota_exceeded"
+	CodeTooLarge       = "payload_too_large"
+	CodeUnauthorized   = "unauthorized"
+	CodeForbidden      = "forbidden"
+	CodeRateLimited    = "rate_limited"
+	CodeNotImplemented = "not_implemented"
+	CodeUnavailable    = "unavailable"
+	CodeInternal       = "internal_error"
+
+	CodeIdempotencyConflict = "idempotency_conflict"
+	CodeIdempotencyMismatch = "idempotency_mismatch"
+)
+
+// Response is error envelope returned by every API
+type Response struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	Metric    string            `json:"metric,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	Items     []BatchItem       `json:"items,omitempty"`
+}
+
+// StatusError is an error with explicit status and code for failures the mapper does not know about
+type StatusError struct {
+	Status  int
+	Code    string
+	Err     error
+	Details map[string]string
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// WithStatus wraps err to be written with status and code
+func WithStatus(status int, code string, err error) error {
+	return &StatusError{Status: status, Code: code, Err: err}
+}
+
+type metricError struct {
+	name string
+	err  error
+}
+
+func (e *metricError) Error() string {
+	return e.name + ": " + e.err.Error()
+}
+
+func (e *metricError) Unwrap() error {
+	return e.err
+}
+
+// ForMetric attaches metric name to err, it is reported in metric field of envelope
+func ForMetric(name string, err error) error {
+	if name == "" {
+		return err
+	}
+	return &metricError{name: name, err: err}
+}
+
+var validationErrors = []error{
+	entity.ErrMetricName,
+	entity.ErrMetricType,
+	entity.ErrCounterValue,
+	entity.ErrGaugeValue,
+	entity.ErrNoValue,
+	entity.ErrMultipleValues,
+	entity.ErrMetadataType,
+	entity.ErrSelectorEmpty,
+	entity.ErrSelectorAmbiguous,
+	entity.ErrSelectorPattern,
+	entity.ErrTenantName,
+	ErrUnexpectedValue,
+	ErrUnexpectedType,
+	ErrUnexpectedFilter,
+}
+
+// Map maps err to status code and envelope, unknown errors are internal and their text is not exposed
+func Map(err error) (int, Response) {
+	var resp Response
+
+	var withMetric *metricError
+	if errors.As(err, &withMetric) {
+		resp.Metric = withMetric.name
+		err = withMetric.err
+	}
+
+	var batchErr *entity.BatchError
+	var statusErr *StatusError
+	var conflict *entity.TypeConflictError
+	var maxBytes *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &batchErr):
+		// status is chosen by the first rejected item, every item is listed
+		status, batchResp := Map(batchErr.Unwrap())
+		batchResp.Message, batchResp.Items = batchErr.Error(), BatchItems(batchErr.Results)
+		return status, batchResp
+	case errors.As(err, &statusErr):
+		resp.Code, resp.Message, resp.Details = statusErr.Code, statusErr.Err.Error(), statusErr.Details
+		return statusErr.Status, resp
+	case errors.As(err, &conflict):
+		resp.Code, resp.Message, resp.Metric = CodeTypeConflict, entity.ErrTypeConflict.Error(), conflict.Name
+		resp.Details = map[string]string{"existing_type": conflict.Existing, "requested_type": conflict.Requested}
+		return http.StatusConflict, resp
+	case errors.Is(err, entity.ErrTypeConflict):
+		resp.Code, resp.Message = CodeTypeConflict, err.Error()
+		return http.StatusConflict, resp
+	case errors.Is(err, repoerr.ErrMetricNotFound), errors.Is(err, ErrMetricNotFound):
+		resp.Code, resp.Message = CodeNotFound, ErrMetricNotFound.Error()
+		return http.StatusNotFound, resp
+	case errors.Is(err, entity.ErrTokenNotFound):
+		resp.Code, resp.Message = CodeNotFound, entity.ErrTokenNotFound.Error()
+		return http.StatusNotFound, resp
+	case errors.Is(err, entity.ErrUnknownToken):
+		resp.Code, resp.Message = CodeUnauthorized, entity.ErrUnknownToken.Error()
+		return http.StatusUnauthorized, resp
+	case errors.Is(err, entity.ErrQuotaExceeded):
+		resp.Code, resp.Message = CodeQuotaExceeded, err.Error()
+		return http.StatusForbidden, resp
+	case errors.As(err, &maxBytes):
+		resp.Code, resp.Message = CodeTooLarge, "request body is too large"
+		return http.StatusRequestEntityTooLarge, resp
+	case errors.Is(err, ErrBatchTooLarge):
+		resp.Code, resp.Message = CodeTooLarge, err.Error()
+		return http.StatusRequestEntityTooLarge, resp
+	case errors.Is(err, ErrUnexpectedFormat):
+		resp.Code, resp.Message = CodeBadRequest, err.Error()
+		return http.StatusBadRequest, resp
+	case isValidation(err):
+		resp.Code, resp.Message = CodeInvalidMetric, err.Error()
+		return http.StatusBadRequest, resp
+	case errors.Is(err, context.DeadlineExceeded):
+		resp.Code, resp.Message = CodeUnavailable, "storage timeout"
+		return http.StatusServiceUnavailable, resp
+	case errors.Is(err, repoerr.ErrUnavailable):
+		resp.Code, resp.Message = CodeUnavailable, repoerr.ErrUnavailable.Error()
+		return http.StatusServiceUnavailable, resp
+	default:
+		resp.Code, resp.Message = CodeInternal, ErrInternalServer.Error()
+		return http.StatusInternalServerError, resp
+	}
+}
+
+func isValidation(err error) bool {
+	for _, target := range validationErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes err as JSON envelope, plain text is written only if client asks for it
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	write(w, r, err, !prefersText(r))
+}
+
+// WriteText writes err as plain text for text API, JSON envelope is written only if client asks for it
+func WriteText(w http.ResponseWriter, r *http.Request, err error) {
+	write(w, r, err, acceptsJSON(r))
+}
+
+func write(w http.ResponseWriter, r *http.Request, err error, asJSON bool) {
+	status, resp := Map(err)
+	resp.RequestID = requestid.From(r.Context())
+
+	if status >= http.StatusInternalServerError {
+		logger.Logger.Errorw("request failed", "request_id", resp.RequestID, "error", err)
+	}
+
+	h := w.Header()
+	// Content-Encoding is kept, body is still written through compressing writer
+	h.Del("Content-Length")
+	h.Set("X-Content-Type-Options", "nosniff")
+
+	if !asJSON {
+		h.Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		msg := resp.Message
+		if resp.Metric != "" {
+			msg += ": " + resp.Metric
+		}
+		w.Write([]byte(msg + "\n"))
+		return
+	}
+
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func acceptsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+func prefersText(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/plain") && !acceptsJSON(r)
+}
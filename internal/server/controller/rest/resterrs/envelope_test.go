@@ -0,0 +1,82 @@
+package resterrs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+	"github.com/arxon31/metrics-collector/internal/requestid"
+)
+
+func TestMap(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		metric string
+	}{
+		{"not_found", ForMetric("Alloc", repoerr.ErrMetricNotFound), http.StatusNotFound, CodeNotFound, "Alloc"},
+		{"validation", fmt.Errorf("Alloc:%w", entity.ErrGaugeValue), http.StatusBadRequest, CodeInvalidMetric, ""},
+		{"format", ErrUnexpectedFormat, http.StatusBadRequest, CodeBadRequest, ""},
+		{"conflict", &entity.TypeConflictError{Name: "Alloc", Existing: "gauge", Requested: "counter"}, http.StatusConflict, CodeTypeConflict, "Alloc"},
+		{"quota", fmt.Errorf("acme: %w", entity.ErrQuotaExceeded), http.StatusForbidden, CodeQuotaExceeded, ""},
+		{"too_large", fmt.Errorf("%w: 2 > 1", ErrBatchTooLarge), http.StatusRequestEntityTooLarge, CodeTooLarge, ""},
+		{"status", WithStatus(http.StatusTooManyRequests, CodeRateLimited, errors.New("slow down")), http.StatusTooManyRequests, CodeRateLimited, ""},
+		{"unavailable", fmt.Errorf("%w: database is down", repoerr.ErrUnavailable), http.StatusServiceUnavailable, CodeUnavailable, ""},
+		{"storage", ForMetric("Alloc", errors.New("connection refused")), http.StatusInternalServerError, CodeInternal, "Alloc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := Map(tt.err)
+			require.Equal(t, tt.status, status)
+			require.Equal(t, tt.code, resp.Code)
+			require.Equal(t, tt.metric, resp.Metric)
+		})
+	}
+}
+
+func TestMap_HidesInternalError(t *testing.T) {
+	_, resp := Map(errors.New("pq: password authentication failed"))
+	require.Equal(t, ErrInternalServer.Error(), resp.Message)
+}
+
+func TestWrite(t *testing.T) {
+	newRequest := func(accept string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", accept)
+		return r.WithContext(requestid.With(r.Context(), "req-1"))
+	}
+
+	t.Run("json_by_default", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		Write(rr, newRequest(""), ForMetric("Alloc", repoerr.ErrMetricNotFound))
+
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"code":"not_found","message":"metric not found","metric":"Alloc","request_id":"req-1"}`, rr.Body.String())
+	})
+
+	t.Run("text_if_asked", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		Write(rr, newRequest("text/plain"), ForMetric("Alloc", repoerr.ErrMetricNotFound))
+
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Equal(t, "metric not found: Alloc\n", rr.Body.String())
+	})
+
+	t.Run("text_api_json_if_asked", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		WriteText(rr, newRequest("application/json"), ErrUnexpectedValue)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.JSONEq(t, `{"code":"invalid_metric","message":"unexpected metric value","request_id":"req-1"}`, rr.Body.String())
+	})
+}
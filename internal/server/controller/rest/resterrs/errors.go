@@ -7,6 +7,11 @@ var (
 	ErrMetricNotFound   = errors.New("metric not found")
 	ErrUnexpectedType   = errors.New("unexpected metric type")
 	ErrUnexpectedFormat = errors.New("unexpected metric format")
+	ErrUnexpectedFilter = errors.New("unexpected metric filter")
+	ErrBatchTooLarge    = errors.New("too many metrics in batch")
+
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
 
 	ErrInternalServer = errors.New("internal server error")
 )
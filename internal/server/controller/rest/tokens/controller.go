@@ -0,0 +1,107 @@
+package tokens
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+)
+
+const (
+	tokensURL      = "/admin/tokens"
+	revokeTokenURL = "/admin/tokens/{id}"
+)
+
+//go:generate moq -out tenantService_moq_test.go . tenantService
+type tenantService interface {
+	CreateToken(ctx context.Context, tenant string) (entity.APIToken, string, error)
+	Tokens(ctx context.Context) ([]entity.APIToken, error)
+	RevokeToken(ctx context.Context, id string) error
+}
+
+type createTokenRequest struct {
+	Tenant string `json:"tenant"`
+}
+
+type createTokenResponse struct {
+	entity.APIToken
+	Token string `json:"token"`
+}
+
+type tokens struct {
+	tenants tenantService
+}
+
+// NewController initializes a new tokens controller.
+func NewController(tenants tenantService) *tokens {
+	return &tokens{
+		tenants: tenants,
+	}
+}
+
+// Register registers the api tokens endpoints on the provided chi Router.
+func (t *tokens) Register(h chi.Router) {
+	h.Post(tokensURL, t.createToken)
+	h.Get(tokensURL, t.listTokens)
+	h.Delete(revokeTokenURL, t.revokeToken)
+}
+
+func (t *tokens) createToken(w http.ResponseWriter, r *http.Request) {
+	var req createTokenRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		resterrs.Write(w, r, resterrs.ErrUnexpectedFormat)
+		return
+	}
+
+	token, secret, err := t.tenants.CreateToken(r.Context(), req.Tenant)
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(createTokenResponse{APIToken: token, Token: secret})
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+}
+
+func (t *tokens) listTokens(w http.ResponseWriter, r *http.Request) {
+	list, err := t.tenants.Tokens(r.Context())
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(list)
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+func (t *tokens) revokeToken(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := t.tenants.RevokeToken(r.Context(), id)
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
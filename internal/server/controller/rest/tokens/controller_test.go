@@ -0,0 +1,134 @@
+package tokens
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+func newRouter(tenants tenantService) *chi.Mux {
+	mux := chi.NewRouter()
+	NewController(tenants).Register(mux)
+	return mux
+}
+
+func TestTokens_NewController(t *testing.T) {
+	c := NewController(&tenantServiceMock{})
+	require.IsType(t, &tokens{}, c)
+}
+
+func TestTokens_CreateToken(t *testing.T) {
+	t.Run("create_token_success", func(t *testing.T) {
+		tenants := &tenantServiceMock{
+			CreateTokenFunc: func(ctx context.Context, tenant string) (entity.APIToken, string, error) {
+				return entity.APIToken{ID: "1a2b", Tenant: tenant}, "secret", nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, tokensURL, strings.NewReader(`{"tenant":"acme"}`))
+		rr := httptest.NewRecorder()
+		newRouter(tenants).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+		require.Equal(t, "acme", tenants.CreateTokenCalls()[0].Tenant)
+
+		var resp createTokenResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Equal(t, "1a2b", resp.ID)
+		require.Equal(t, "secret", resp.Token)
+	})
+
+	t.Run("create_token_bad_tenant", func(t *testing.T) {
+		tenants := &tenantServiceMock{
+			CreateTokenFunc: func(ctx context.Context, tenant string) (entity.APIToken, string, error) {
+				return entity.APIToken{}, "", entity.ErrTenantName
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, tokensURL, strings.NewReader(`{"tenant":"a c"}`))
+		rr := httptest.NewRecorder()
+		newRouter(tenants).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("create_token_bad_body", func(t *testing.T) {
+		tenants := &tenantServiceMock{}
+
+		req := httptest.NewRequest(http.MethodPost, tokensURL, strings.NewReader(`{`))
+		rr := httptest.NewRecorder()
+		newRouter(tenants).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Empty(t, tenants.CreateTokenCalls())
+	})
+}
+
+func TestTokens_ListTokens(t *testing.T) {
+	tenants := &tenantServiceMock{
+		TokensFunc: func(ctx context.Context) ([]entity.APIToken, error) {
+			return []entity.APIToken{{ID: "1a2b", Tenant: "acme"}}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, tokensURL, nil)
+	rr := httptest.NewRecorder()
+	newRouter(tenants).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `[{"id":"1a2b","tenant":"acme","created_at":"0001-01-01T00:00:00Z"}]`, rr.Body.String())
+}
+
+func TestTokens_RevokeToken(t *testing.T) {
+	t.Run("revoke_token_success", func(t *testing.T) {
+		tenants := &tenantServiceMock{
+			RevokeTokenFunc: func(ctx context.Context, id string) error {
+				return nil
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/admin/tokens/1a2b", nil)
+		rr := httptest.NewRecorder()
+		newRouter(tenants).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "1a2b", tenants.RevokeTokenCalls()[0].ID)
+	})
+
+	t.Run("revoke_token_not_found", func(t *testing.T) {
+		tenants := &tenantServiceMock{
+			RevokeTokenFunc: func(ctx context.Context, id string) error {
+				return entity.ErrTokenNotFound
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/admin/tokens/1a2b", nil)
+		rr := httptest.NewRecorder()
+		newRouter(tenants).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("revoke_token_internal_error", func(t *testing.T) {
+		tenants := &tenantServiceMock{
+			RevokeTokenFunc: func(ctx context.Context, id string) error {
+				return errors.New("db is down")
+			},
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/admin/tokens/1a2b", nil)
+		rr := httptest.NewRecorder()
+		newRouter(tenants).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
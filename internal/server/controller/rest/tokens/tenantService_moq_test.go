@@ -0,0 +1,176 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package tokens
+
+import (
+	"context"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"sync"
+)
+
+// Ensure, that tenantServiceMock does implement tenantService.
+// If this is not the case, regenerate this file with moq.
+var _ tenantService = &tenantServiceMock{}
+
+// tenantServiceMock is a mock implementation of tenantService.
+//
+//	func TestSomethingThatUsestenantService(t *testing.T) {
+//
+//		// make and configure a mocked tenantService
+//		mockedtenantService := &tenantServiceMock{
+//			CreateTokenFunc: func(ctx context.Context, tenant string) (entity.APIToken, string, error) {
+//				panic("mock out the CreateToken method")
+//			},
+//			RevokeTokenFunc: func(ctx context.Context, id string) error {
+//				panic("mock out the RevokeToken method")
+//			},
+//			TokensFunc: func(ctx context.Context) ([]entity.APIToken, error) {
+//				panic("mock out the Tokens method")
+//			},
+//		}
+//
+//		// use mockedtenantService in code that requires tenantService
+//		// and then make assertions.
+//
+//	}
+type tenantServiceMock struct {
+	// CreateTokenFunc mocks the CreateToken method.
+	CreateTokenFunc func(ctx context.Context, tenant string) (entity.APIToken, string, error)
+
+	// RevokeTokenFunc mocks the RevokeToken method.
+	RevokeTokenFunc func(ctx context.Context, id string) error
+
+	// TokensFunc mocks the Tokens method.
+	TokensFunc func(ctx context.Context) ([]entity.APIToken, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// CreateToken holds details about calls to the CreateToken method.
+		CreateToken []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Tenant is the tenant argument value.
+			Tenant string
+		}
+		// RevokeToken holds details about calls to the RevokeToken method.
+		RevokeToken []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+		}
+		// Tokens holds details about calls to the Tokens method.
+		Tokens []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+	}
+	lockCreateToken sync.RWMutex
+	lockRevokeToken sync.RWMutex
+	lockTokens      sync.RWMutex
+}
+
+// CreateToken calls CreateTokenFunc.
+func (mock *tenantServiceMock) CreateToken(ctx context.Context, tenant string) (entity.APIToken, string, error) {
+	if mock.CreateTokenFunc == nil {
+		panic("tenantServiceMock.CreateTokenFunc: method is nil but tenantService.CreateToken was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Tenant string
+	}{
+		Ctx:    ctx,
+		Tenant: tenant,
+	}
+	mock.lockCreateToken.Lock()
+	mock.calls.CreateToken = append(mock.calls.CreateToken, callInfo)
+	mock.lockCreateToken.Unlock()
+	return mock.CreateTokenFunc(ctx, tenant)
+}
+
+// CreateTokenCalls gets all the calls that were made to CreateToken.
+// Check the length with:
+//
+//	len(mockedtenantService.CreateTokenCalls())
+func (mock *tenantServiceMock) CreateTokenCalls() []struct {
+	Ctx    context.Context
+	Tenant string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Tenant string
+	}
+	mock.lockCreateToken.RLock()
+	calls = mock.calls.CreateToken
+	mock.lockCreateToken.RUnlock()
+	return calls
+}
+
+// RevokeToken calls RevokeTokenFunc.
+func (mock *tenantServiceMock) RevokeToken(ctx context.Context, id string) error {
+	if mock.RevokeTokenFunc == nil {
+		panic("tenantServiceMock.RevokeTokenFunc: method is nil but tenantService.RevokeToken was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  string
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockRevokeToken.Lock()
+	mock.calls.RevokeToken = append(mock.calls.RevokeToken, callInfo)
+	mock.lockRevokeToken.Unlock()
+	return mock.RevokeTokenFunc(ctx, id)
+}
+
+// RevokeTokenCalls gets all the calls that were made to RevokeToken.
+// Check the length with:
+//
+//	len(mockedtenantService.RevokeTokenCalls())
+func (mock *tenantServiceMock) RevokeTokenCalls() []struct {
+	Ctx context.Context
+	ID  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  string
+	}
+	mock.lockRevokeToken.RLock()
+	calls = mock.calls.RevokeToken
+	mock.lockRevokeToken.RUnlock()
+	return calls
+}
+
+// Tokens calls TokensFunc.
+func (mock *tenantServiceMock) Tokens(ctx context.Context) ([]entity.APIToken, error) {
+	if mock.TokensFunc == nil {
+		panic("tenantServiceMock.TokensFunc: method is nil but tenantService.Tokens was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockTokens.Lock()
+	mock.calls.Tokens = append(mock.calls.Tokens, callInfo)
+	mock.lockTokens.Unlock()
+	return mock.TokensFunc(ctx)
+}
+
+// TokensCalls gets all the calls that were made to Tokens.
+// Check the length with:
+//
+//	len(mockedtenantService.TokensCalls())
+func (mock *tenantServiceMock) TokensCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockTokens.RLock()
+	calls = mock.calls.Tokens
+	mock.lockTokens.RUnlock()
+	return calls
+}
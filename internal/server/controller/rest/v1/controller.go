@@ -2,7 +2,6 @@ package v1
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/http"
 
@@ -11,7 +10,6 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"github.com/arxon31/metrics-collector/internal/entity"
-	repo "github.com/arxon31/metrics-collector/internal/repository/repoerr"
 )
 
 const (
@@ -48,14 +46,12 @@ func NewController(store storageService, provider providerService) *v1 {
 	}
 }
 
-// Register registers the v1 endpoints on the provided chi Router.
-func (v *v1) Register(h *chi.Mux) {
-	h.Route("/value", func(r chi.Router) {
-		h.Get(getGaugeMetricURL, v.getGaugeMetric)
-		h.Get(getCounterMetricURL, v.getCounterMetric)
-		h.Get(getUnimplementedURL, v.unimplementedGet)
-	})
-	h.Route("/update", func(r chi.Router) {
+// Register registers the v1 read endpoints on readers and update endpoints on writers chi Router.
+func (v *v1) Register(readers, writers chi.Router) {
+	readers.Get(getGaugeMetricURL, v.getGaugeMetric)
+	readers.Get(getCounterMetricURL, v.getCounterMetric)
+	readers.Get(getUnimplementedURL, v.unimplementedGet)
+	writers.Route("/update", func(r chi.Router) {
 		r.Post(saveCounterMetricURL, v.updateCounterMetric)
 		r.Post(saveGaugeMetricURL, v.updateGaugeMetric)
 		r.Post(saveUnimplementedURL, v.unimplementedSave)
@@ -66,11 +62,7 @@ func (v *v1) getGaugeMetric(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
 	value, err := v.provider.GetGaugeValue(r.Context(), name)
 	if err != nil {
-		if errors.Is(err, repo.ErrMetricNotFound) {
-			http.Error(w, fmt.Sprintf("%s: %s", resterrs.ErrMetricNotFound, name), http.StatusNotFound)
-			return
-		}
-		http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+		resterrs.WriteText(w, r, resterrs.ForMetric(name, err))
 		return
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -84,11 +76,7 @@ func (v *v1) getCounterMetric(w http.ResponseWriter, r *http.Request) {
 
 	value, err := v.provider.GetCounterValue(r.Context(), name)
 	if err != nil {
-		if errors.Is(err, repo.ErrMetricNotFound) {
-			http.Error(w, fmt.Sprintf("%s: %s", resterrs.ErrMetricNotFound, name), http.StatusNotFound)
-			return
-		}
-		http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+		resterrs.WriteText(w, r, resterrs.ForMetric(name, err))
 		return
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -104,7 +92,7 @@ func (v *v1) updateCounterMetric(w http.ResponseWriter, r *http.Request) {
 	var counter entity.Counter
 	val, err := counter.CounterFromString(value)
 	if err != nil {
-		http.Error(w, resterrs.ErrUnexpectedValue.Error(), http.StatusBadRequest)
+		resterrs.WriteText(w, r, resterrs.ForMetric(name, resterrs.ErrUnexpectedValue))
 		return
 	}
 
@@ -115,7 +103,7 @@ func (v *v1) updateCounterMetric(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if err != nil {
-		http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+		resterrs.WriteText(w, r, resterrs.ForMetric(name, err))
 		return
 	}
 
@@ -130,7 +118,7 @@ func (v *v1) updateGaugeMetric(w http.ResponseWriter, r *http.Request) {
 	var gauge entity.Gauge
 	val, err := gauge.GaugeFromString(value)
 	if err != nil {
-		http.Error(w, resterrs.ErrUnexpectedValue.Error(), http.StatusBadRequest)
+		resterrs.WriteText(w, r, resterrs.ForMetric(name, resterrs.ErrUnexpectedValue))
 		return
 	}
 
@@ -141,7 +129,7 @@ func (v *v1) updateGaugeMetric(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if err != nil {
-		http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+		resterrs.WriteText(w, r, resterrs.ForMetric(name, err))
 		return
 	}
 
@@ -152,7 +140,7 @@ func (v *v1) updateGaugeMetric(w http.ResponseWriter, r *http.Request) {
 func (v *v1) unimplementedSave(w http.ResponseWriter, r *http.Request) {
 	t := chi.URLParam(r, "type")
 	if t != entity.GaugeType && t != entity.CounterType {
-		http.Error(w, resterrs.ErrUnexpectedType.Error(), http.StatusNotImplemented)
+		resterrs.WriteText(w, r, resterrs.WithStatus(http.StatusNotImplemented, resterrs.CodeNotImplemented, resterrs.ErrUnexpectedType))
 		return
 	}
 }
@@ -160,7 +148,7 @@ func (v *v1) unimplementedSave(w http.ResponseWriter, r *http.Request) {
 func (v *v1) unimplementedGet(w http.ResponseWriter, r *http.Request) {
 	t := chi.URLParam(r, "type")
 	if t != entity.GaugeType && t != entity.CounterType {
-		http.Error(w, resterrs.ErrUnexpectedType.Error(), http.StatusNotImplemented)
+		resterrs.WriteText(w, r, resterrs.WithStatus(http.StatusNotImplemented, resterrs.CodeNotImplemented, resterrs.ErrUnexpectedType))
 		return
 	}
 }
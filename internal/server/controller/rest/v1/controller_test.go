@@ -6,8 +6,10 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/require"
 
+	"github.com/arxon31/metrics-collector/internal/entity"
 	repo "github.com/arxon31/metrics-collector/internal/repository/repoerr"
 )
 
@@ -89,3 +91,31 @@ func TestV1_GetCounterMetric(t *testing.T) {
 		require.Equal(t, http.StatusNotFound, rr.Code)
 	})
 }
+
+func TestV1_UpdateCounterMetric(t *testing.T) {
+	t.Run("update_counter_metric_type_conflict", func(t *testing.T) {
+		store := &storageServiceMock{
+			SaveCounterMetricFunc: func(ctx context.Context, metric entity.MetricDTO) error {
+				return &entity.TypeConflictError{Name: metric.Name, Existing: entity.GaugeType, Requested: entity.CounterType}
+			},
+		}
+
+		mux := chi.NewRouter()
+		NewController(store, &providerServiceMock{}).Register(mux, mux)
+
+		req := httptest.NewRequest(http.MethodPost, "/update/counter/Alloc/1", nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Equal(t, "metric type conflict: Alloc\n", rr.Body.String())
+
+		req = httptest.NewRequest(http.MethodPost, "/update/counter/Alloc/1", nil)
+		req.Header.Set("Accept", "application/json")
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.JSONEq(t, `{"code":"type_conflict","message":"metric type conflict","metric":"Alloc","details":{"existing_type":"gauge","requested_type":"counter"}}`, rr.Body.String())
+	})
+}
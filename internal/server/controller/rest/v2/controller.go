@@ -3,14 +3,11 @@ package v2
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
 
-	repo "github.com/arxon31/metrics-collector/internal/repository/repoerr"
-
 	"github.com/go-chi/chi/v5"
 
 	"github.com/arxon31/metrics-collector/internal/entity"
@@ -48,9 +45,9 @@ func NewController(store storageService, provider providerService) *v2 {
 }
 
 // Register registers the v2 endpoints on the provided chi Router.
-func (v *v2) Register(h *chi.Mux) {
-	h.Post(updateMetricJSONURL, v.updateJSONMetric)
-	h.Post(valueOfMetricJSONURL, v.getValueOfJSONMetric)
+func (v *v2) Register(readers, writers chi.Router) {
+	writers.Post(updateMetricJSONURL, v.updateJSONMetric)
+	readers.Post(valueOfMetricJSONURL, v.getValueOfJSONMetric)
 
 }
 
@@ -60,13 +57,13 @@ func (v *v2) updateJSONMetric(w http.ResponseWriter, r *http.Request) {
 	var m entity.MetricDTO
 
 	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
-		http.Error(w, resterrs.ErrUnexpectedFormat.Error(), http.StatusBadRequest)
+		resterrs.Write(w, r, resterrs.ErrUnexpectedFormat)
 		return
 	}
 	defer r.Body.Close()
 
 	if m.MetricType != entity.GaugeType && m.MetricType != entity.CounterType {
-		http.Error(w, resterrs.ErrUnexpectedType.Error(), http.StatusBadRequest)
+		resterrs.Write(w, r, resterrs.ForMetric(m.Name, resterrs.ErrUnexpectedType))
 		return
 	}
 
@@ -74,20 +71,22 @@ func (v *v2) updateJSONMetric(w http.ResponseWriter, r *http.Request) {
 	case entity.GaugeType:
 		err := v.store.SaveGaugeMetric(r.Context(), m)
 		if err != nil {
-			http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+			resterrs.Write(w, r, resterrs.ForMetric(m.Name, err))
 			return
 		}
 
 	case entity.CounterType:
 		err := v.store.SaveCounterMetric(r.Context(), m)
 		if err != nil {
-			http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+			resterrs.Write(w, r, resterrs.ForMetric(m.Name, err))
 			return
 		}
 
 		counterValue, err := v.provider.GetCounterValue(r.Context(), m.Name)
 		if err != nil {
-			http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+			// counter was just saved, failing to read it back is a storage failure even if it is not found
+			resterrs.Write(w, r, resterrs.ForMetric(m.Name, fmt.Errorf("read saved counter: %v", err)))
+			return
 		}
 
 		m.Counter = &counterValue
@@ -96,7 +95,7 @@ func (v *v2) updateJSONMetric(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := json.Marshal(m)
 	if err != nil {
-		http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+		resterrs.Write(w, r, err)
 		return
 	}
 
@@ -110,13 +109,13 @@ func (v *v2) getValueOfJSONMetric(w http.ResponseWriter, r *http.Request) {
 	m := entity.MetricDTO{}
 
 	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
-		http.Error(w, resterrs.ErrUnexpectedFormat.Error(), http.StatusBadRequest)
+		resterrs.Write(w, r, resterrs.ErrUnexpectedFormat)
 		return
 	}
 	defer r.Body.Close()
 
 	if m.MetricType != entity.GaugeType && m.MetricType != entity.CounterType {
-		http.Error(w, resterrs.ErrUnexpectedType.Error(), http.StatusBadRequest)
+		resterrs.Write(w, r, resterrs.ForMetric(m.Name, resterrs.ErrUnexpectedType))
 		return
 	}
 
@@ -124,22 +123,14 @@ func (v *v2) getValueOfJSONMetric(w http.ResponseWriter, r *http.Request) {
 	case entity.GaugeType:
 		val, err := v.provider.GetGaugeValue(r.Context(), m.Name)
 		if err != nil {
-			if errors.Is(err, repo.ErrMetricNotFound) {
-				http.Error(w, fmt.Sprintf("%s: %s", resterrs.ErrMetricNotFound, m.Name), http.StatusNotFound)
-				return
-			}
-			http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+			resterrs.Write(w, r, resterrs.ForMetric(m.Name, err))
 			return
 		}
 		m.Gauge = &val
 	case entity.CounterType:
 		val, err := v.provider.GetCounterValue(r.Context(), m.Name)
 		if err != nil {
-			if errors.Is(err, repo.ErrMetricNotFound) {
-				http.Error(w, fmt.Sprintf("%s: %s", resterrs.ErrMetricNotFound, m.Name), http.StatusNotFound)
-				return
-			}
-			http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+			resterrs.Write(w, r, resterrs.ForMetric(m.Name, err))
 			return
 		}
 		m.Counter = &val
@@ -147,7 +138,7 @@ func (v *v2) getValueOfJSONMetric(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := json.Marshal(m)
 	if err != nil {
-		http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+		resterrs.Write(w, r, err)
 		return
 	}
 
@@ -157,6 +157,32 @@ func TestV2_UpdateJSONMetric(t *testing.T) {
 		require.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 
+	t.Run("update_gauge_metric_type_conflict", func(t *testing.T) {
+		store := &storageServiceMock{
+			SaveGaugeMetricFunc: func(ctx context.Context, m entity.MetricDTO) error {
+				return &entity.TypeConflictError{Name: m.Name, Existing: entity.CounterType, Requested: entity.GaugeType}
+			},
+		}
+		v2 := NewController(store, &providerServiceMock{})
+
+		gaugeVal := 20.1
+		metric := entity.MetricDTO{
+			Name:       "test",
+			MetricType: "gauge",
+			Gauge:      &gaugeVal,
+		}
+
+		metricJSON, err := json.Marshal(metric)
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, updateMetricJSONURL, bytes.NewBuffer(metricJSON))
+		w := httptest.NewRecorder()
+		v2.updateJSONMetric(w, r)
+
+		require.Equal(t, http.StatusConflict, w.Code)
+		require.JSONEq(t, `{"code":"type_conflict","message":"metric type conflict","metric":"test","details":{"existing_type":"counter","requested_type":"gauge"}}`, w.Body.String())
+	})
+
 	t.Run("update_counter_metric_not_found", func(t *testing.T) {
 		store := &storageServiceMock{
 			SaveCounterMetricFunc: func(ctx context.Context, m entity.MetricDTO) error {
@@ -1,11 +1,17 @@
 package v3
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+	"github.com/arxon31/metrics-collector/pkg/logger"
 
 	"github.com/mailru/easyjson"
 
@@ -14,6 +20,14 @@ import (
 	"github.com/arxon31/metrics-collector/internal/entity"
 )
 
+const (
+	// batch modes selected by mode query parameter of saveJSONMetricsURL
+	batchModeAtomic     = "atomic"
+	batchModeBestEffort = "best-effort"
+)
+
+var errBatchMode = errors.New("batch mode must be atomic or best-effort")
+
 const (
 	saveJSONMetricsURL = "/updates/"
 	getJSONMetricsURL  = "/"
@@ -22,7 +36,7 @@ const (
 
 //go:generate moq -out storageService_moq_test.go . storageService
 type storageService interface {
-	SaveBatchMetrics(ctx context.Context, metrics []entity.MetricDTO) error
+	SaveBatchMetrics(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error)
 }
 
 //go:generate moq -out providerService_moq_test.go . providerService
@@ -30,6 +44,7 @@ type providerService interface {
 	GetGaugeValue(ctx context.Context, name string) (float64, error)
 	GetCounterValue(ctx context.Context, name string) (int64, error)
 	GetMetrics(ctx context.Context) ([]entity.MetricDTO, error)
+	GetMetadata(ctx context.Context) ([]entity.Metadata, error)
 }
 
 //go:generate moq -out pingerService_moq_test.go . pingerService
@@ -37,32 +52,65 @@ type pingerService interface {
 	PingDB() error
 }
 
+// dashboardMetric is a metric enriched with its metadata
+type dashboardMetric struct {
+	Name        string   `json:"id"`
+	MetricType  string   `json:"type"`
+	Counter     *int64   `json:"delta,omitempty"`
+	Gauge       *float64 `json:"value,omitempty"`
+	Unit        string   `json:"unit,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// batchResponse lists outcome of every metric of a batch in request order
+type batchResponse struct {
+	Accepted int                  `json:"accepted"`
+	Rejected int                  `json:"rejected"`
+	Items    []resterrs.BatchItem `json:"items"`
+}
+
+// batchRequest is a batch with its ID, the ID is used as idempotency key if header is absent
+type batchRequest struct {
+	BatchID string            `json:"batch_id"`
+	Metrics entity.MetricDTOs `json:"metrics"`
+}
+
 type v3 struct {
-	store    storageService
-	provider providerService
-	pinger   pingerService
+	store        storageService
+	provider     providerService
+	pinger       pingerService
+	maxBatchSize int
+
+	idempotency     idempotencyStore
+	idempotencyWait time.Duration
 }
 
 // NewController initializes a new v3 controller.
-func NewController(store storageService, provider providerService, pinger pingerService) *v3 {
-	return &v3{
+func NewController(store storageService, provider providerService, pinger pingerService, opts ...Option) *v3 {
+	v := &v3{
 		store:    store,
 		provider: provider,
 		pinger:   pinger,
 	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
 }
 
-// Register registers the v2 endpoints on the provided chi Router.
-func (v *v3) Register(h *chi.Mux) {
-	h.Get(pingDBURL, v.pingDB)
-	h.Get(getJSONMetricsURL, v.getJSONMetrics)
-	h.Post(saveJSONMetricsURL, v.saveJSONMetrics)
+// Register registers the v3 read endpoints on readers and update endpoints on writers chi Router.
+func (v *v3) Register(readers, writers chi.Router) {
+	readers.Get(pingDBURL, v.pingDB)
+	readers.Get(getJSONMetricsURL, v.getJSONMetrics)
+	writers.Post(saveJSONMetricsURL, v.idempotent(v.saveJSONMetrics))
 }
 
 func (v *v3) pingDB(w http.ResponseWriter, r *http.Request) {
 	err := v.pinger.PingDB()
 	if err != nil {
-		http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+		resterrs.Write(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -70,34 +118,90 @@ func (v *v3) pingDB(w http.ResponseWriter, r *http.Request) {
 }
 
 func (v *v3) saveJSONMetrics(w http.ResponseWriter, r *http.Request) {
-	ms := make(entity.MetricDTOs, 0)
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != batchModeAtomic && mode != batchModeBestEffort {
+		resterrs.Write(w, r, resterrs.WithStatus(http.StatusBadRequest, resterrs.CodeBadRequest, errBatchMode))
+		return
+	}
 
-	err := easyjson.UnmarshalFromReader(r.Body, &ms)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, resterrs.ErrUnexpectedFormat.Error(), http.StatusBadRequest)
+		resterrs.Write(w, r, resterrs.WithStatus(http.StatusBadRequest, resterrs.CodeBadRequest, err))
 		return
 	}
 	defer r.Body.Close()
 
-	err = v.store.SaveBatchMetrics(r.Context(), ms)
+	_, ms, err := decodeBatch(body)
 	if err != nil {
-		http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+		resterrs.Write(w, r, resterrs.ErrUnexpectedFormat)
+		return
+	}
+
+	if v.maxBatchSize > 0 && len(ms) > v.maxBatchSize {
+		logger.Logger.Errorf("batch of %d metrics exceeds limit %d", len(ms), v.maxBatchSize)
+		resterrs.Write(w, r, fmt.Errorf("%w: %d > %d", resterrs.ErrBatchTooLarge, len(ms), v.maxBatchSize))
+		return
+	}
+
+	results, err := v.store.SaveBatchMetrics(r.Context(), ms, mode == batchModeBestEffort)
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
+	}
+
+	resp := batchResponse{Items: resterrs.BatchItems(results)}
+	for _, item := range resp.Items {
+		if item.Status == entity.BatchAccepted {
+			resp.Accepted++
+		} else {
+			resp.Rejected++
+		}
+	}
+
+	respBody, err := json.Marshal(resp)
+	if err != nil {
+		resterrs.Write(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	w.Write(respBody)
 }
 
 func (v *v3) getJSONMetrics(w http.ResponseWriter, r *http.Request) {
 	ms, err := v.provider.GetMetrics(r.Context())
 	if err != nil {
-		http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+		resterrs.Write(w, r, err)
 		return
 	}
 
-	resp, err := json.Marshal(ms)
+	mds, err := v.provider.GetMetadata(r.Context())
 	if err != nil {
-		http.Error(w, resterrs.ErrInternalServer.Error(), http.StatusInternalServerError)
+		logger.Logger.Errorln("can not get metadata for dashboard:", err)
+	}
+
+	metadata := make(map[string]entity.Metadata, len(mds))
+	for _, md := range mds {
+		metadata[md.Name] = md
+	}
+
+	dashboard := make([]dashboardMetric, 0, len(ms))
+	for _, m := range ms {
+		md := metadata[m.Name]
+		dashboard = append(dashboard, dashboardMetric{
+			Name:        m.Name,
+			MetricType:  m.MetricType,
+			Counter:     m.Counter,
+			Gauge:       m.Gauge,
+			Unit:        md.Unit,
+			Description: md.Description,
+		})
+	}
+
+	resp, err := json.Marshal(dashboard)
+	if err != nil {
+		resterrs.Write(w, r, err)
 		return
 	}
 
@@ -105,3 +209,17 @@ func (v *v3) getJSONMetrics(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write(resp)
 }
+
+// decodeBatch decodes either a plain array of metrics or batchRequest object
+func decodeBatch(body []byte) (string, entity.MetricDTOs, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '{' {
+		var req batchRequest
+		err := json.Unmarshal(body, &req)
+		return req.BatchID, req.Metrics, err
+	}
+
+	ms := make(entity.MetricDTOs, 0)
+	err := easyjson.Unmarshal(body, &ms)
+	return "", ms, err
+}
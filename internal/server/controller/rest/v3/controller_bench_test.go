@@ -16,8 +16,8 @@ import (
 
 func BenchmarkV3_SaveJSONMetrics(b *testing.B) {
 	store := &storageServiceMock{
-		SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO) error {
-			return nil
+		SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error) {
+			return nil, nil
 		},
 	}
 	provider := &providerServiceMock{}
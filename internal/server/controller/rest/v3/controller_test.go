@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
 )
 
 func TestV3_NewController(t *testing.T) {
@@ -54,8 +56,8 @@ func TestV3_SaveJSONMetrics(t *testing.T) {
 	t.Run("save_json_metrics_success", func(t *testing.T) {
 
 		store := &storageServiceMock{
-			SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO) error {
-				return nil
+			SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error) {
+				return nil, nil
 			},
 		}
 
@@ -87,8 +89,8 @@ func TestV3_SaveJSONMetrics(t *testing.T) {
 
 	t.Run("save_json_metrics_fail", func(t *testing.T) {
 		store := &storageServiceMock{
-			SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO) error {
-				return errors.New("some error")
+			SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error) {
+				return nil, errors.New("some error")
 			},
 		}
 
@@ -118,6 +120,131 @@ func TestV3_SaveJSONMetrics(t *testing.T) {
 		require.Equal(t, http.StatusInternalServerError, rr.Code)
 	})
 
+	t.Run("save_json_metrics_type_conflict", func(t *testing.T) {
+		store := &storageServiceMock{
+			SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error) {
+				return nil, &entity.TypeConflictError{Name: "test", Existing: entity.GaugeType, Requested: entity.CounterType}
+			},
+		}
+
+		v3 := NewController(store, &providerServiceMock{}, &pingerServiceMock{})
+
+		counterVal := int64(20)
+		metricsJSON, err := json.Marshal([]entity.MetricDTO{{Name: "test", MetricType: "counter", Counter: &counterVal}})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, saveJSONMetricsURL, bytes.NewBuffer(metricsJSON))
+		rr := httptest.NewRecorder()
+		v3.saveJSONMetrics(rr, req)
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.JSONEq(t, `{"code":"type_conflict","message":"metric type conflict","metric":"test","details":{"existing_type":"gauge","requested_type":"counter"}}`, rr.Body.String())
+	})
+
+	t.Run("save_json_metrics_invalid_metric", func(t *testing.T) {
+		store := &storageServiceMock{
+			SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error) {
+				return nil, metrics[0].Validate()
+			},
+		}
+
+		v3 := NewController(store, &providerServiceMock{}, &pingerServiceMock{})
+
+		metricsJSON, err := json.Marshal([]entity.MetricDTO{{Name: "test", MetricType: entity.CounterType}})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, saveJSONMetricsURL, bytes.NewBuffer(metricsJSON))
+		rr := httptest.NewRecorder()
+		v3.saveJSONMetrics(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), `"code":"invalid_metric"`)
+	})
+
+	t.Run("save_json_metrics_best_effort", func(t *testing.T) {
+		store := &storageServiceMock{
+			SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error) {
+				require.True(t, bestEffort)
+				return []entity.BatchResult{
+					{Name: "first", MetricType: entity.GaugeType, Status: entity.BatchAccepted},
+					{Name: "second", MetricType: entity.GaugeType, Status: entity.BatchRejected, Err: fmt.Errorf("second:%w", entity.ErrGaugeValue)},
+				}, nil
+			},
+		}
+
+		v3 := NewController(store, &providerServiceMock{}, &pingerServiceMock{})
+
+		gaugeVal := 1.5
+		metricsJSON, err := json.Marshal([]entity.MetricDTO{
+			{Name: "first", MetricType: entity.GaugeType, Gauge: &gaugeVal},
+			{Name: "second", MetricType: entity.GaugeType},
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, saveJSONMetricsURL+"?mode=best-effort", bytes.NewBuffer(metricsJSON))
+		rr := httptest.NewRecorder()
+		v3.saveJSONMetrics(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `{"accepted":1,"rejected":1,"items":[
+			{"index":0,"id":"first","type":"gauge","status":"accepted"},
+			{"index":1,"id":"second","type":"gauge","status":"rejected","code":"invalid_metric","reason":"second:gauge value is empty"}
+		]}`, rr.Body.String())
+	})
+
+	t.Run("save_json_metrics_atomic_rejected", func(t *testing.T) {
+		store := &storageServiceMock{
+			SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error) {
+				require.False(t, bestEffort)
+				results := []entity.BatchResult{
+					{Name: "first", MetricType: entity.GaugeType, Status: entity.BatchSkipped},
+					{Name: "second", MetricType: entity.GaugeType, Status: entity.BatchRejected, Err: fmt.Errorf("second:%w", entity.ErrGaugeValue)},
+				}
+				return results, &entity.BatchError{Results: results}
+			},
+		}
+
+		v3 := NewController(store, &providerServiceMock{}, &pingerServiceMock{})
+
+		req := httptest.NewRequest(http.MethodPost, saveJSONMetricsURL, bytes.NewBufferString(`[]`))
+		rr := httptest.NewRecorder()
+		v3.saveJSONMetrics(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		var resp resterrs.Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Equal(t, resterrs.CodeInvalidMetric, resp.Code)
+		require.Len(t, resp.Items, 2)
+		require.Equal(t, entity.BatchSkipped, resp.Items[0].Status)
+		require.Equal(t, entity.BatchRejected, resp.Items[1].Status)
+	})
+
+	t.Run("save_json_metrics_unknown_mode", func(t *testing.T) {
+		store := &storageServiceMock{}
+		v3 := NewController(store, &providerServiceMock{}, &pingerServiceMock{})
+
+		req := httptest.NewRequest(http.MethodPost, saveJSONMetricsURL+"?mode=some", bytes.NewBufferString(`[]`))
+		rr := httptest.NewRecorder()
+		v3.saveJSONMetrics(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Empty(t, store.SaveBatchMetricsCalls())
+	})
+
+	t.Run("save_json_metrics_batch_too_large", func(t *testing.T) {
+		store := &storageServiceMock{}
+		v3 := NewController(store, &providerServiceMock{}, &pingerServiceMock{}, WithMaxBatchSize(1))
+
+		gaugeVal := 1.5
+		metricsJSON, err := json.Marshal([]entity.MetricDTO{
+			{Name: "first", MetricType: entity.GaugeType, Gauge: &gaugeVal},
+			{Name: "second", MetricType: entity.GaugeType, Gauge: &gaugeVal},
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, saveJSONMetricsURL, bytes.NewBuffer(metricsJSON))
+		rr := httptest.NewRecorder()
+		v3.saveJSONMetrics(rr, req)
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		require.Empty(t, store.SaveBatchMetricsCalls())
+	})
+
 	t.Run("save_json_metrics_bad_request", func(t *testing.T) {
 		v3 := NewController(&storageServiceMock{}, &providerServiceMock{}, &pingerServiceMock{})
 		req := httptest.NewRequest(http.MethodPost, saveJSONMetricsURL, nil)
@@ -148,6 +275,9 @@ func TestV3_GetJSONMetrics(t *testing.T) {
 			GetMetricsFunc: func(ctx context.Context) ([]entity.MetricDTO, error) {
 				return metrics, nil
 			},
+			GetMetadataFunc: func(ctx context.Context) ([]entity.Metadata, error) {
+				return nil, nil
+			},
 		}
 
 		metricsJSON, err := json.Marshal(metrics)
@@ -161,6 +291,26 @@ func TestV3_GetJSONMetrics(t *testing.T) {
 		require.Equal(t, string(metricsJSON), rr.Body.String())
 	})
 
+	t.Run("get_json_metrics_with_metadata", func(t *testing.T) {
+		gaugeVal := 20.1
+
+		provider := &providerServiceMock{
+			GetMetricsFunc: func(ctx context.Context) ([]entity.MetricDTO, error) {
+				return []entity.MetricDTO{{Name: entity.Alloc, MetricType: entity.GaugeType, Gauge: &gaugeVal}}, nil
+			},
+			GetMetadataFunc: func(ctx context.Context) ([]entity.Metadata, error) {
+				return []entity.Metadata{{Name: entity.Alloc, Unit: entity.UnitBytes, Description: "heap"}}, nil
+			},
+		}
+
+		v3 := NewController(&storageServiceMock{}, provider, &pingerServiceMock{})
+		req := httptest.NewRequest(http.MethodGet, getJSONMetricsURL, nil)
+		rr := httptest.NewRecorder()
+		v3.getJSONMetrics(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `[{"id":"Alloc","type":"gauge","value":20.1,"unit":"bytes","description":"heap"}]`, rr.Body.String())
+	})
+
 	t.Run("get_json_metrics_fail", func(t *testing.T) {
 		provider := &providerServiceMock{
 			GetMetricsFunc: func(ctx context.Context) ([]entity.MetricDTO, error) {
@@ -13,14 +13,17 @@ import (
 
 var (
 	store = &storageServiceMock{
-		SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO) error {
-			return nil
+		SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error) {
+			return nil, nil
 		},
 	}
 	provider = &providerServiceMock{
 		GetMetricsFunc: func(ctx context.Context) ([]entity.MetricDTO, error) {
 			return nil, nil
 		},
+		GetMetadataFunc: func(ctx context.Context) ([]entity.Metadata, error) {
+			return nil, nil
+		},
 	}
 	pinger = &pingerServiceMock{}
 
@@ -0,0 +1,144 @@
+package v3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/idempotency"
+	"github.com/arxon31/metrics-collector/internal/server/controller/rest/resterrs"
+	"github.com/arxon31/metrics-collector/pkg/logger"
+)
+
+const (
+	// IdempotencyKeyHeader carries client key of a batch, batch_id of the body is used if it is absent
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks response replayed for a duplicate request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLen = 255
+	inProgressPoll       = 50 * time.Millisecond
+)
+
+var errIdempotencyKey = errors.New("idempotency key must be at most 255 bytes")
+
+//go:generate moq -out idempotencyStore_moq_test.go . idempotencyStore
+type idempotencyStore interface {
+	Begin(ctx context.Context, tenant, key, fingerprint string) (*idempotency.Response, error)
+	Complete(ctx context.Context, tenant, key string, resp idempotency.Response) error
+	Release(ctx context.Context, tenant, key string) error
+}
+
+// idempotent applies request once per idempotency key, duplicates get the original response.
+// A duplicate arriving while the original is processed waits for it up to idempotencyWait.
+func (v *v3) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	if v.idempotency == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			resterrs.Write(w, r, resterrs.WithStatus(http.StatusBadRequest, resterrs.CodeBadRequest, err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			key, _, _ = decodeBatch(body)
+		}
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			resterrs.Write(w, r, resterrs.WithStatus(http.StatusBadRequest, resterrs.CodeBadRequest, errIdempotencyKey))
+			return
+		}
+
+		tenant := auth.Tenant(r.Context())
+		stored, err := v.begin(r.Context(), tenant, key, idempotency.Fingerprint(body))
+		if err != nil {
+			writeIdempotencyError(w, r, err)
+			return
+		}
+		if stored != nil {
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.Header().Set("Content-Type", stored.ContentType)
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+
+		// the outcome must be remembered even if client has gone, its retry would apply the batch again
+		ctx := context.WithoutCancel(r.Context())
+		if recorder.status >= http.StatusInternalServerError {
+			err = v.idempotency.Release(ctx, tenant, key)
+		} else {
+			err = v.idempotency.Complete(ctx, tenant, key, idempotency.Response{
+				Status:      recorder.status,
+				ContentType: recorder.Header().Get("Content-Type"),
+				Body:        recorder.body.Bytes(),
+			})
+		}
+		if err != nil {
+			logger.Logger.Errorln("can not save idempotency key:", err)
+		}
+	}
+}
+
+// begin reserves key, waiting while it is reserved by a concurrent request with the same payload
+func (v *v3) begin(ctx context.Context, tenant, key, fingerprint string) (*idempotency.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.idempotencyWait)
+	defer cancel()
+
+	for {
+		stored, err := v.idempotency.Begin(ctx, tenant, key, fingerprint)
+		if !errors.Is(err, idempotency.ErrInProgress) {
+			return stored, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(inProgressPoll):
+		}
+	}
+}
+
+func writeIdempotencyError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, idempotency.ErrInProgress):
+		w.Header().Set("Retry-After", "1")
+		resterrs.Write(w, r, resterrs.WithStatus(http.StatusConflict, resterrs.CodeIdempotencyConflict, err))
+	case errors.Is(err, idempotency.ErrMismatch):
+		resterrs.Write(w, r, resterrs.WithStatus(http.StatusUnprocessableEntity, resterrs.CodeIdempotencyMismatch, err))
+	default:
+		resterrs.Write(w, r, err)
+	}
+}
+
+// responseRecorder keeps copy of response written to client
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,212 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package v3
+
+import (
+	"context"
+	"github.com/arxon31/metrics-collector/internal/idempotency"
+	"sync"
+)
+
+// Ensure, that idempotencyStoreMock does implement idempotencyStore.
+// If this is not the case, regenerate this file with moq.
+var _ idempotencyStore = &idempotencyStoreMock{}
+
+// idempotencyStoreMock is a mock implementation of idempotencyStore.
+//
+//	func TestSomethingThatUsesidempotencyStore(t *testing.T) {
+//
+//		// make and configure a mocked idempotencyStore
+//		mockedidempotencyStore := &idempotencyStoreMock{
+//			BeginFunc: func(ctx context.Context, tenant string, key string, fingerprint string) (*idempotency.Response, error) {
+//				panic("mock out the Begin method")
+//			},
+//			CompleteFunc: func(ctx context.Context, tenant string, key string, resp idempotency.Response) error {
+//				panic("mock out the Complete method")
+//			},
+//			ReleaseFunc: func(ctx context.Context, tenant string, key string) error {
+//				panic("mock out the Release method")
+//			},
+//		}
+//
+//		// use mockedidempotencyStore in code that requires idempotencyStore
+//		// and then make assertions.
+//
+//	}
+type idempotencyStoreMock struct {
+	// BeginFunc mocks the Begin method.
+	BeginFunc func(ctx context.Context, tenant string, key string, fingerprint string) (*idempotency.Response, error)
+
+	// CompleteFunc mocks the Complete method.
+	CompleteFunc func(ctx context.Context, tenant string, key string, resp idempotency.Response) error
+
+	// ReleaseFunc mocks the Release method.
+	ReleaseFunc func(ctx context.Context, tenant string, key string) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Begin holds details about calls to the Begin method.
+		Begin []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Tenant is the tenant argument value.
+			Tenant string
+			// Key is the key argument value.
+			Key string
+			// Fingerprint is the fingerprint argument value.
+			Fingerprint string
+		}
+		// Complete holds details about calls to the Complete method.
+		Complete []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Tenant is the tenant argument value.
+			Tenant string
+			// Key is the key argument value.
+			Key string
+			// Resp is the resp argument value.
+			Resp idempotency.Response
+		}
+		// Release holds details about calls to the Release method.
+		Release []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Tenant is the tenant argument value.
+			Tenant string
+			// Key is the key argument value.
+			Key string
+		}
+	}
+	lockBegin    sync.RWMutex
+	lockComplete sync.RWMutex
+	lockRelease  sync.RWMutex
+}
+
+// Begin calls BeginFunc.
+func (mock *idempotencyStoreMock) Begin(ctx context.Context, tenant string, key string, fingerprint string) (*idempotency.Response, error) {
+	if mock.BeginFunc == nil {
+		panic("idempotencyStoreMock.BeginFunc: method is nil but idempotencyStore.Begin was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		Tenant      string
+		Key         string
+		Fingerprint string
+	}{
+		Ctx:         ctx,
+		Tenant:      tenant,
+		Key:         key,
+		Fingerprint: fingerprint,
+	}
+	mock.lockBegin.Lock()
+	mock.calls.Begin = append(mock.calls.Begin, callInfo)
+	mock.lockBegin.Unlock()
+	return mock.BeginFunc(ctx, tenant, key, fingerprint)
+}
+
+// BeginCalls gets all the calls that were made to Begin.
+// Check the length with:
+//
+//	len(mockedidempotencyStore.BeginCalls())
+func (mock *idempotencyStoreMock) BeginCalls() []struct {
+	Ctx         context.Context
+	Tenant      string
+	Key         string
+	Fingerprint string
+} {
+	var calls []struct {
+		Ctx         context.Context
+		Tenant      string
+		Key         string
+		Fingerprint string
+	}
+	mock.lockBegin.RLock()
+	calls = mock.calls.Begin
+	mock.lockBegin.RUnlock()
+	return calls
+}
+
+// Complete calls CompleteFunc.
+func (mock *idempotencyStoreMock) Complete(ctx context.Context, tenant string, key string, resp idempotency.Response) error {
+	if mock.CompleteFunc == nil {
+		panic("idempotencyStoreMock.CompleteFunc: method is nil but idempotencyStore.Complete was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Tenant string
+		Key    string
+		Resp   idempotency.Response
+	}{
+		Ctx:    ctx,
+		Tenant: tenant,
+		Key:    key,
+		Resp:   resp,
+	}
+	mock.lockComplete.Lock()
+	mock.calls.Complete = append(mock.calls.Complete, callInfo)
+	mock.lockComplete.Unlock()
+	return mock.CompleteFunc(ctx, tenant, key, resp)
+}
+
+// CompleteCalls gets all the calls that were made to Complete.
+// Check the length with:
+//
+//	len(mockedidempotencyStore.CompleteCalls())
+func (mock *idempotencyStoreMock) CompleteCalls() []struct {
+	Ctx    context.Context
+	Tenant string
+	Key    string
+	Resp   idempotency.Response
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Tenant string
+		Key    string
+		Resp   idempotency.Response
+	}
+	mock.lockComplete.RLock()
+	calls = mock.calls.Complete
+	mock.lockComplete.RUnlock()
+	return calls
+}
+
+// Release calls ReleaseFunc.
+func (mock *idempotencyStoreMock) Release(ctx context.Context, tenant string, key string) error {
+	if mock.ReleaseFunc == nil {
+		panic("idempotencyStoreMock.ReleaseFunc: method is nil but idempotencyStore.Release was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Tenant string
+		Key    string
+	}{
+		Ctx:    ctx,
+		Tenant: tenant,
+		Key:    key,
+	}
+	mock.lockRelease.Lock()
+	mock.calls.Release = append(mock.calls.Release, callInfo)
+	mock.lockRelease.Unlock()
+	return mock.ReleaseFunc(ctx, tenant, key)
+}
+
+// ReleaseCalls gets all the calls that were made to Release.
+// Check the length with:
+//
+//	len(mockedidempotencyStore.ReleaseCalls())
+func (mock *idempotencyStoreMock) ReleaseCalls() []struct {
+	Ctx    context.Context
+	Tenant string
+	Key    string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Tenant string
+		Key    string
+	}
+	mock.lockRelease.RLock()
+	calls = mock.calls.Release
+	mock.lockRelease.RUnlock()
+	return calls
+}
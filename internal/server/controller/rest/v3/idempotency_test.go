@@ -0,0 +1,135 @@
+package v3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/idempotency"
+)
+
+const counterBatch = `[{"id":"PollCount","type":"counter","delta":1}]`
+
+func TestV3_Idempotency(t *testing.T) {
+	newController := func(store idempotencyStore, save func()) *v3 {
+		storage := &storageServiceMock{
+			SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error) {
+				save()
+				return []entity.BatchResult{{Name: metrics[0].Name, MetricType: metrics[0].MetricType, Status: entity.BatchAccepted}}, nil
+			},
+		}
+		return NewController(storage, &providerServiceMock{}, &pingerServiceMock{}, WithIdempotency(store, time.Second))
+	}
+
+	post := func(v *v3, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, saveJSONMetricsURL, bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		v.idempotent(v.saveJSONMetrics)(rr, req)
+		return rr
+	}
+
+	t.Run("replay_duplicate", func(t *testing.T) {
+		var saves atomic.Int32
+		v := newController(idempotency.NewMemoryStore(time.Hour, 0), func() { saves.Add(1) })
+
+		first := post(v, "batch-1", counterBatch)
+		second := post(v, "batch-1", counterBatch)
+
+		require.Equal(t, int32(1), saves.Load())
+		require.Equal(t, http.StatusOK, second.Code)
+		require.Equal(t, first.Body.String(), second.Body.String())
+		require.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+	})
+
+	t.Run("batch_id_in_body", func(t *testing.T) {
+		var saves atomic.Int32
+		v := newController(idempotency.NewMemoryStore(time.Hour, 0), func() { saves.Add(1) })
+
+		body := `{"batch_id":"batch-1","metrics":` + counterBatch + `}`
+		post(v, "", body)
+		rr := post(v, "", body)
+
+		require.Equal(t, int32(1), saves.Load())
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("without_key", func(t *testing.T) {
+		var saves atomic.Int32
+		v := newController(idempotency.NewMemoryStore(time.Hour, 0), func() { saves.Add(1) })
+
+		post(v, "", counterBatch)
+		post(v, "", counterBatch)
+
+		require.Equal(t, int32(2), saves.Load())
+	})
+
+	t.Run("key_reused_with_another_payload", func(t *testing.T) {
+		v := newController(idempotency.NewMemoryStore(time.Hour, 0), func() {})
+
+		post(v, "batch-1", counterBatch)
+		rr := post(v, "batch-1", `[{"id":"PollCount","type":"counter","delta":2}]`)
+
+		require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("concurrent_duplicates_apply_once", func(t *testing.T) {
+		var saves atomic.Int32
+		release := make(chan struct{})
+		v := newController(idempotency.NewMemoryStore(time.Hour, 0), func() {
+			saves.Add(1)
+			<-release
+		})
+
+		var wg sync.WaitGroup
+		codes := make([]int, 2)
+		for i := range codes {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				codes[i] = post(v, "batch-1", counterBatch).Code
+			}(i)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		require.Equal(t, int32(1), saves.Load())
+		require.Equal(t, []int{http.StatusOK, http.StatusOK}, codes)
+	})
+
+	t.Run("release_on_server_error", func(t *testing.T) {
+		store := &idempotencyStoreMock{
+			BeginFunc: func(ctx context.Context, tenant, key, fingerprint string) (*idempotency.Response, error) {
+				return nil, nil
+			},
+			ReleaseFunc: func(ctx context.Context, tenant, key string) error {
+				return nil
+			},
+		}
+		storage := &storageServiceMock{
+			SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error) {
+				return nil, errors.New("some error")
+			},
+		}
+		v := NewController(storage, &providerServiceMock{}, &pingerServiceMock{}, WithIdempotency(store, time.Second))
+
+		rr := post(v, "batch-1", counterBatch)
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Len(t, store.ReleaseCalls(), 1)
+		require.Empty(t, store.CompleteCalls())
+	})
+}
@@ -0,0 +1,21 @@
+package v3
+
+import "time"
+
+type Option func(v *v3)
+
+// WithMaxBatchSize limits number of metrics in a single batch, zero means unlimited
+func WithMaxBatchSize(size int) Option {
+	return func(v *v3) {
+		v.maxBatchSize = size
+	}
+}
+
+// WithIdempotency applies batches once per idempotency key using store,
+// duplicate of a batch being processed waits for its response up to wait
+func WithIdempotency(store idempotencyStore, wait time.Duration) Option {
+	return func(v *v3) {
+		v.idempotency = store
+		v.idempotencyWait = wait
+	}
+}
@@ -5,9 +5,8 @@ package v3
 
 import (
 	"context"
-	"sync"
-
 	"github.com/arxon31/metrics-collector/internal/entity"
+	"sync"
 )
 
 // Ensure, that providerServiceMock does implement providerService.
@@ -26,6 +25,9 @@ var _ providerService = &providerServiceMock{}
 //			GetGaugeValueFunc: func(ctx context.Context, name string) (float64, error) {
 //				panic("mock out the GetGaugeValue method")
 //			},
+//			GetMetadataFunc: func(ctx context.Context) ([]entity.Metadata, error) {
+//				panic("mock out the GetMetadata method")
+//			},
 //			GetMetricsFunc: func(ctx context.Context) ([]entity.MetricDTO, error) {
 //				panic("mock out the GetMetrics method")
 //			},
@@ -42,6 +44,9 @@ type providerServiceMock struct {
 	// GetGaugeValueFunc mocks the GetGaugeValue method.
 	GetGaugeValueFunc func(ctx context.Context, name string) (float64, error)
 
+	// GetMetadataFunc mocks the GetMetadata method.
+	GetMetadataFunc func(ctx context.Context) ([]entity.Metadata, error)
+
 	// GetMetricsFunc mocks the GetMetrics method.
 	GetMetricsFunc func(ctx context.Context) ([]entity.MetricDTO, error)
 
@@ -61,6 +66,11 @@ type providerServiceMock struct {
 			// Name is the name argument value.
 			Name string
 		}
+		// GetMetadata holds details about calls to the GetMetadata method.
+		GetMetadata []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
 		// GetMetrics holds details about calls to the GetMetrics method.
 		GetMetrics []struct {
 			// Ctx is the ctx argument value.
@@ -69,6 +79,7 @@ type providerServiceMock struct {
 	}
 	lockGetCounterValue sync.RWMutex
 	lockGetGaugeValue   sync.RWMutex
+	lockGetMetadata     sync.RWMutex
 	lockGetMetrics      sync.RWMutex
 }
 
@@ -144,6 +155,38 @@ func (mock *providerServiceMock) GetGaugeValueCalls() []struct {
 	return calls
 }
 
+// GetMetadata calls GetMetadataFunc.
+func (mock *providerServiceMock) GetMetadata(ctx context.Context) ([]entity.Metadata, error) {
+	if mock.GetMetadataFunc == nil {
+		panic("providerServiceMock.GetMetadataFunc: method is nil but providerService.GetMetadata was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetMetadata.Lock()
+	mock.calls.GetMetadata = append(mock.calls.GetMetadata, callInfo)
+	mock.lockGetMetadata.Unlock()
+	return mock.GetMetadataFunc(ctx)
+}
+
+// GetMetadataCalls gets all the calls that were made to GetMetadata.
+// Check the length with:
+//
+//	len(mockedproviderService.GetMetadataCalls())
+func (mock *providerServiceMock) GetMetadataCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetMetadata.RLock()
+	calls = mock.calls.GetMetadata
+	mock.lockGetMetadata.RUnlock()
+	return calls
+}
+
 // GetMetrics calls GetMetricsFunc.
 func (mock *providerServiceMock) GetMetrics(ctx context.Context) ([]entity.MetricDTO, error) {
 	if mock.GetMetricsFunc == nil {
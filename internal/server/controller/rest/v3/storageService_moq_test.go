@@ -5,9 +5,8 @@ package v3
 
 import (
 	"context"
-	"sync"
-
 	"github.com/arxon31/metrics-collector/internal/entity"
+	"sync"
 )
 
 // Ensure, that storageServiceMock does implement storageService.
@@ -20,7 +19,7 @@ var _ storageService = &storageServiceMock{}
 //
 //		// make and configure a mocked storageService
 //		mockedstorageService := &storageServiceMock{
-//			SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO) error {
+//			SaveBatchMetricsFunc: func(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error) {
 //				panic("mock out the SaveBatchMetrics method")
 //			},
 //		}
@@ -31,7 +30,7 @@ var _ storageService = &storageServiceMock{}
 //	}
 type storageServiceMock struct {
 	// SaveBatchMetricsFunc mocks the SaveBatchMetrics method.
-	SaveBatchMetricsFunc func(ctx context.Context, metrics []entity.MetricDTO) error
+	SaveBatchMetricsFunc func(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -41,27 +40,31 @@ type storageServiceMock struct {
 			Ctx context.Context
 			// Metrics is the metrics argument value.
 			Metrics []entity.MetricDTO
+			// BestEffort is the bestEffort argument value.
+			BestEffort bool
 		}
 	}
 	lockSaveBatchMetrics sync.RWMutex
 }
 
 // SaveBatchMetrics calls SaveBatchMetricsFunc.
-func (mock *storageServiceMock) SaveBatchMetrics(ctx context.Context, metrics []entity.MetricDTO) error {
+func (mock *storageServiceMock) SaveBatchMetrics(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error) {
 	if mock.SaveBatchMetricsFunc == nil {
 		panic("storageServiceMock.SaveBatchMetricsFunc: method is nil but storageService.SaveBatchMetrics was just called")
 	}
 	callInfo := struct {
-		Ctx     context.Context
-		Metrics []entity.MetricDTO
+		Ctx        context.Context
+		Metrics    []entity.MetricDTO
+		BestEffort bool
 	}{
-		Ctx:     ctx,
-		Metrics: metrics,
+		Ctx:        ctx,
+		Metrics:    metrics,
+		BestEffort: bestEffort,
 	}
 	mock.lockSaveBatchMetrics.Lock()
 	mock.calls.SaveBatchMetrics = append(mock.calls.SaveBatchMetrics, callInfo)
 	mock.lockSaveBatchMetrics.Unlock()
-	return mock.SaveBatchMetricsFunc(ctx, metrics)
+	return mock.SaveBatchMetricsFunc(ctx, metrics, bestEffort)
 }
 
 // SaveBatchMetricsCalls gets all the calls that were made to SaveBatchMetrics.
@@ -69,12 +72,14 @@ func (mock *storageServiceMock) SaveBatchMetrics(ctx context.Context, metrics []
 //
 //	len(mockedstorageService.SaveBatchMetricsCalls())
 func (mock *storageServiceMock) SaveBatchMetricsCalls() []struct {
-	Ctx     context.Context
-	Metrics []entity.MetricDTO
+	Ctx        context.Context
+	Metrics    []entity.MetricDTO
+	BestEffort bool
 } {
 	var calls []struct {
-		Ctx     context.Context
-		Metrics []entity.MetricDTO
+		Ctx        context.Context
+		Metrics    []entity.MetricDTO
+		BestEffort bool
 	}
 	mock.lockSaveBatchMetrics.RLock()
 	calls = mock.calls.SaveBatchMetrics
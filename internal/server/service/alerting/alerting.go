@@ -0,0 +1,259 @@
+// Package alerting periodically evaluates alert rules against stored metrics and notifies webhooks
+package alerting
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/arxon31/metrics-collector/pkg/logger"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+type repo interface {
+	// Metrics returns all metrics values
+	Metrics(ctx context.Context) ([]entity.MetricDTO, error)
+}
+
+type sample struct {
+	value float64
+	at    time.Time
+	// seenAt is the last time metric was reported
+	seenAt time.Time
+}
+
+type ruleState struct {
+	rule       Rule
+	state      string
+	value      float64
+	activeAt   time.Time
+	firedAt    time.Time
+	resolvedAt time.Time
+}
+
+type service struct {
+	repo      repo
+	interval  time.Duration
+	notifier  *notifier
+	now       func() time.Time
+	startedAt time.Time
+
+	mu      sync.RWMutex
+	states  []*ruleState
+	samples map[string]sample
+}
+
+// NewService initializes a new alerting service.
+func NewService(repo repo, rules *Rules) *service {
+	states := make([]*ruleState, 0, len(rules.Rules))
+	for _, rule := range rules.Rules {
+		states = append(states, &ruleState{
+			rule:  rule,
+			state: entity.AlertInactive,
+		})
+	}
+
+	return &service{
+		repo:      repo,
+		interval:  rules.Interval,
+		notifier:  newNotifier(rules.Webhooks),
+		now:       time.Now,
+		startedAt: time.Now(),
+		states:    states,
+		samples:   make(map[string]sample),
+	}
+}
+
+// Run evaluates rules by interval until context is done
+func (s *service) Run(ctx context.Context) {
+	if len(s.states) == 0 {
+		return
+	}
+
+	go s.notifier.run(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	logger.Logger.Infof("evaluating %d alert rules every %s", len(s.states), s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.evaluate(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Alerts returns current state of all rules
+func (s *service) Alerts() []entity.Alert {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	alerts := make([]entity.Alert, 0, len(s.states))
+	for _, st := range s.states {
+		alerts = append(alerts, st.alert())
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Rule < alerts[j].Rule
+	})
+
+	return alerts
+}
+
+func (s *service) evaluate(ctx context.Context) {
+	metrics, err := s.repo.Metrics(ctx)
+	if err != nil {
+		logger.Logger.Errorln("can not get metrics for alerting:", err)
+		return
+	}
+
+	now := s.now()
+
+	s.mu.Lock()
+	previous := s.samples
+	s.samples = s.observe(metrics, previous, now)
+
+	changed := make([]entity.Alert, 0)
+	for _, st := range s.states {
+		active, value := s.check(st.rule, previous, now)
+		if st.transit(active, value, now) {
+			changed = append(changed, st.alert())
+		}
+	}
+	s.mu.Unlock()
+
+	for _, alert := range changed {
+		s.notifier.enqueue(alert)
+	}
+}
+
+func (s *service) observe(metrics []entity.MetricDTO, previous map[string]sample, now time.Time) map[string]sample {
+	samples := make(map[string]sample, len(previous))
+	for key, smp := range previous {
+		samples[key] = smp
+	}
+
+	for _, m := range metrics {
+		var value float64
+		switch {
+		case m.MetricType == entity.GaugeType && m.Gauge != nil:
+			value = *m.Gauge
+		case m.MetricType == entity.CounterType && m.Counter != nil:
+			value = float64(*m.Counter)
+		default:
+			continue
+		}
+
+		key := sampleKey(m.MetricType, m.Name)
+		smp := sample{value: value, at: now, seenAt: m.UpdatedAt}
+		if smp.seenAt.IsZero() {
+			// repository without update times, a report is noticed only by changed value
+			smp.seenAt = now
+			if prev, ok := previous[key]; ok && prev.value == value {
+				smp.seenAt = prev.seenAt
+			}
+		}
+		samples[key] = smp
+	}
+
+	return samples
+}
+
+func (s *service) check(rule Rule, previous map[string]sample, now time.Time) (active bool, value float64) {
+	switch rule.Kind {
+	case KindThreshold:
+		cur, ok := s.samples[sampleKey(entity.GaugeType, rule.Metric)]
+		if !ok || cur.at != now {
+			return false, 0
+		}
+		return operators[rule.Op](cur.value, rule.Value), cur.value
+
+	case KindRate:
+		key := sampleKey(entity.CounterType, rule.Metric)
+		cur, ok := s.samples[key]
+		prev, okPrev := previous[key]
+		if !ok || !okPrev || cur.at != now || !cur.at.After(prev.at) {
+			return false, 0
+		}
+		delta := cur.value - prev.value
+		if delta < 0 {
+			// counter was reset between samples, it has grown from zero since
+			delta = cur.value
+		}
+		rate := delta / cur.at.Sub(prev.at).Seconds()
+		return operators[rule.Op](rate, rule.Value), rate
+
+	case KindAbsence:
+		lastSeen := s.startedAt
+		for _, metricType := range []string{entity.GaugeType, entity.CounterType} {
+			if smp, ok := s.samples[sampleKey(metricType, rule.Metric)]; ok && smp.seenAt.After(lastSeen) {
+				lastSeen = smp.seenAt
+			}
+		}
+		absent := now.Sub(lastSeen)
+		return absent >= rule.Window, absent.Seconds()
+	}
+
+	return false, 0
+}
+
+// transit moves rule to the next state and reports whether notification is required
+func (st *ruleState) transit(active bool, value float64, now time.Time) bool {
+	st.value = value
+
+	if !active {
+		switch st.state {
+		case entity.AlertPending:
+			st.state = entity.AlertInactive
+		case entity.AlertFiring:
+			st.state = entity.AlertResolved
+			st.resolvedAt = now
+			return true
+		}
+		return false
+	}
+
+	switch st.state {
+	case entity.AlertInactive, entity.AlertResolved:
+		st.state = entity.AlertPending
+		st.activeAt = now
+		st.firedAt = time.Time{}
+		st.resolvedAt = time.Time{}
+	}
+
+	if st.state == entity.AlertPending && now.Sub(st.activeAt) >= st.rule.For {
+		st.state = entity.AlertFiring
+		st.firedAt = now
+		return true
+	}
+
+	return false
+}
+
+func (st *ruleState) alert() entity.Alert {
+	return entity.Alert{
+		Rule:       st.rule.Name,
+		Metric:     st.rule.Metric,
+		State:      st.state,
+		Value:      st.value,
+		ActiveAt:   timeRef(st.activeAt),
+		FiredAt:    timeRef(st.firedAt),
+		ResolvedAt: timeRef(st.resolvedAt),
+	}
+}
+
+func timeRef(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func sampleKey(metricType, name string) string {
+	return metricType + ":" + name
+}
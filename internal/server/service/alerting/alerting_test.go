@@ -0,0 +1,321 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+type repoStub struct {
+	metrics []entity.MetricDTO
+}
+
+func (r *repoStub) Metrics(_ context.Context) ([]entity.MetricDTO, error) {
+	return r.metrics, nil
+}
+
+func (r *repoStub) setGauge(name string, value float64) {
+	r.metrics = append(r.metrics[:0:0], entity.MetricDTO{Name: name, MetricType: entity.GaugeType, Gauge: &value})
+}
+
+func (r *repoStub) setCounter(name string, value int64) {
+	r.metrics = append(r.metrics[:0:0], entity.MetricDTO{Name: name, MetricType: entity.CounterType, Counter: &value})
+}
+
+// report sets update time of stored metrics as repositories do on every write
+func (r *repoStub) report(at time.Time) {
+	for i := range r.metrics {
+		r.metrics[i].UpdatedAt = at
+	}
+}
+
+type receiver struct {
+	mu     sync.Mutex
+	alerts []entity.Alert
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var alert entity.Alert
+	if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rc.mu.Lock()
+	rc.alerts = append(rc.alerts, alert)
+	rc.mu.Unlock()
+}
+
+func (rc *receiver) received() []entity.Alert {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]entity.Alert(nil), rc.alerts...)
+}
+
+// wait returns received alerts once n of them are delivered
+func (rc *receiver) wait(t *testing.T, n int) []entity.Alert {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return len(rc.received()) >= n
+	}, time.Second, 10*time.Millisecond)
+
+	received := rc.received()
+	require.Len(t, received, n)
+	return received
+}
+
+func newTestService(t *testing.T, repo repo, rules ...Rule) (*service, *receiver, *time.Time) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	s := NewService(repo, &Rules{Interval: time.Second, Webhooks: []string{srv.URL}, Rules: rules})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.startedAt = now
+	s.now = func() time.Time { return now }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.notifier.run(ctx)
+
+	return s, rc, &now
+}
+
+func TestService_Threshold(t *testing.T) {
+	repo := &repoStub{}
+	s, rc, now := newTestService(t, repo, Rule{
+		Name:   "high_alloc",
+		Metric: entity.Alloc,
+		Kind:   KindThreshold,
+		Op:     ">",
+		Value:  100,
+		For:    10 * time.Second,
+	})
+	ctx := context.Background()
+
+	repo.setGauge(entity.Alloc, 200)
+	s.evaluate(ctx)
+	require.Equal(t, entity.AlertPending, s.Alerts()[0].State)
+	require.Empty(t, rc.received())
+
+	*now = now.Add(10 * time.Second)
+	s.evaluate(ctx)
+	require.Equal(t, entity.AlertFiring, s.Alerts()[0].State)
+
+	*now = now.Add(time.Second)
+	s.evaluate(ctx)
+	rc.wait(t, 1)
+
+	repo.setGauge(entity.Alloc, 50)
+	*now = now.Add(time.Second)
+	s.evaluate(ctx)
+	require.Equal(t, entity.AlertResolved, s.Alerts()[0].State)
+
+	received := rc.wait(t, 2)
+	require.Equal(t, entity.AlertFiring, received[0].State)
+	require.Equal(t, entity.AlertResolved, received[1].State)
+	require.Equal(t, "high_alloc", received[1].Rule)
+}
+
+func TestService_PendingNotFiring(t *testing.T) {
+	repo := &repoStub{}
+	s, rc, now := newTestService(t, repo, Rule{
+		Name:   "high_alloc",
+		Metric: entity.Alloc,
+		Kind:   KindThreshold,
+		Op:     ">",
+		Value:  100,
+		For:    time.Minute,
+	})
+	ctx := context.Background()
+
+	repo.setGauge(entity.Alloc, 200)
+	s.evaluate(ctx)
+	require.Equal(t, entity.AlertPending, s.Alerts()[0].State)
+
+	repo.setGauge(entity.Alloc, 10)
+	*now = now.Add(time.Second)
+	s.evaluate(ctx)
+	require.Equal(t, entity.AlertInactive, s.Alerts()[0].State)
+	require.Empty(t, rc.received())
+}
+
+func TestService_Rate(t *testing.T) {
+	repo := &repoStub{}
+	s, rc, now := newTestService(t, repo, Rule{
+		Name:   "poll_rate",
+		Metric: entity.PollCount,
+		Kind:   KindRate,
+		Op:     ">",
+		Value:  5,
+	})
+	ctx := context.Background()
+
+	repo.setCounter(entity.PollCount, 10)
+	s.evaluate(ctx)
+	require.Equal(t, entity.AlertInactive, s.Alerts()[0].State)
+
+	repo.setCounter(entity.PollCount, 110)
+	*now = now.Add(10 * time.Second)
+	s.evaluate(ctx)
+
+	alert := s.Alerts()[0]
+	require.Equal(t, entity.AlertFiring, alert.State)
+	require.Equal(t, float64(10), alert.Value)
+	rc.wait(t, 1)
+
+	// counter reset between samples is not a negative rate
+	repo.setCounter(entity.PollCount, 30)
+	*now = now.Add(10 * time.Second)
+	s.evaluate(ctx)
+
+	alert = s.Alerts()[0]
+	require.Equal(t, float64(3), alert.Value)
+}
+
+func TestService_Absence(t *testing.T) {
+	repo := &repoStub{}
+	s, rc, now := newTestService(t, repo, Rule{
+		Name:   "no_polls",
+		Metric: entity.PollCount,
+		Kind:   KindAbsence,
+		Window: 30 * time.Second,
+	})
+	ctx := context.Background()
+
+	repo.setCounter(entity.PollCount, 1)
+	s.evaluate(ctx)
+	require.Equal(t, entity.AlertInactive, s.Alerts()[0].State)
+
+	*now = now.Add(31 * time.Second)
+	s.evaluate(ctx)
+	require.Equal(t, entity.AlertFiring, s.Alerts()[0].State)
+
+	repo.setCounter(entity.PollCount, 2)
+	*now = now.Add(time.Second)
+	s.evaluate(ctx)
+	require.Equal(t, entity.AlertResolved, s.Alerts()[0].State)
+	rc.wait(t, 2)
+}
+
+func TestService_AbsenceOfConstantGauge(t *testing.T) {
+	repo := &repoStub{}
+	s, rc, now := newTestService(t, repo, Rule{
+		Name:   "no_alloc",
+		Metric: entity.Alloc,
+		Kind:   KindAbsence,
+		Window: 30 * time.Second,
+	})
+	ctx := context.Background()
+
+	repo.setGauge(entity.Alloc, 100)
+	for i := 0; i < 5; i++ {
+		repo.report(*now)
+		*now = now.Add(20 * time.Second)
+		s.evaluate(ctx)
+		require.Equal(t, entity.AlertInactive, s.Alerts()[0].State, "same value reported again is not absent")
+	}
+	require.Empty(t, rc.received())
+
+	*now = now.Add(20 * time.Second)
+	s.evaluate(ctx)
+	require.Equal(t, entity.AlertFiring, s.Alerts()[0].State)
+}
+
+func TestService_SlowWebhook(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	repo := &repoStub{}
+	s := NewService(repo, &Rules{Interval: time.Second, Webhooks: []string{srv.URL}, Rules: []Rule{{
+		Name:   "high_alloc",
+		Metric: entity.Alloc,
+		Kind:   KindThreshold,
+		Op:     ">",
+		Value:  100,
+	}}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.notifier.run(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			repo.setGauge(entity.Alloc, float64(200*(i%2)))
+			s.evaluate(ctx)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("evaluation must not wait for webhook")
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	t.Run("empty_path", func(t *testing.T) {
+		rules, err := LoadRules("")
+		require.NoError(t, err)
+		require.Empty(t, rules.Rules)
+		require.Equal(t, defaultEvalInterval, rules.Interval)
+	})
+
+	t.Run("valid_file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.yaml")
+		err := os.WriteFile(path, []byte(`
+interval: 5s
+webhooks:
+  - http://localhost:9000/hook
+rules:
+  - name: high_alloc
+    metric: Alloc
+    kind: threshold
+    op: ">"
+    value: 1000
+    for: 30s
+  - name: no_polls
+    metric: PollCount
+    kind: absence
+    window: 1m
+`), 0600)
+		require.NoError(t, err)
+
+		rules, err := LoadRules(path)
+		require.NoError(t, err)
+		require.Equal(t, 5*time.Second, rules.Interval)
+		require.Equal(t, []string{"http://localhost:9000/hook"}, rules.Webhooks)
+		require.Len(t, rules.Rules, 2)
+		require.Equal(t, 30*time.Second, rules.Rules[0].For)
+		require.Equal(t, time.Minute, rules.Rules[1].Window)
+	})
+
+	t.Run("unknown_kind", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.yaml")
+		err := os.WriteFile(path, []byte(`
+rules:
+  - name: broken
+    metric: Alloc
+    kind: median
+`), 0600)
+		require.NoError(t, err)
+
+		_, err = LoadRules(path)
+		require.ErrorIs(t, err, errRuleKind)
+	})
+}
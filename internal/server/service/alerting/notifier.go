@@ -0,0 +1,87 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/arxon31/metrics-collector/pkg/logger"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+const (
+	notifyTimeout = 5 * time.Second
+	// notifyQueueSize bounds alerts waiting for delivery, alerts beyond it are dropped
+	notifyQueueSize = 100
+)
+
+type notifier struct {
+	client   *http.Client
+	webhooks []string
+	queue    chan entity.Alert
+}
+
+func newNotifier(webhooks []string) *notifier {
+	return &notifier{
+		client:   &http.Client{Timeout: notifyTimeout},
+		webhooks: webhooks,
+		queue:    make(chan entity.Alert, notifyQueueSize),
+	}
+}
+
+// enqueue schedules alert delivery without blocking rules evaluation
+func (n *notifier) enqueue(alert entity.Alert) {
+	if len(n.webhooks) == 0 {
+		return
+	}
+
+	select {
+	case n.queue <- alert:
+	default:
+		logger.Logger.Errorln("alert notification queue is full, dropping alert:", alert.Rule, alert.State)
+	}
+}
+
+// run delivers queued alerts until context is done
+func (n *notifier) run(ctx context.Context) {
+	for {
+		select {
+		case alert := <-n.queue:
+			n.notify(ctx, alert)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// notify posts alert to every configured webhook
+func (n *notifier) notify(ctx context.Context, alert entity.Alert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		logger.Logger.Errorln("can not marshal alert:", err)
+		return
+	}
+
+	for _, url := range n.webhooks {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			logger.Logger.Errorln("can not create webhook request:", err)
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := n.client.Do(req)
+		if err != nil {
+			logger.Logger.Errorln("can not send alert to webhook:", url, err)
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			logger.Logger.Errorln("webhook responded with unexpected status:", url, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	KindThreshold = "threshold"
+	KindRate      = "rate"
+	KindAbsence   = "absence"
+)
+
+const defaultEvalInterval = 10 * time.Second
+
+var (
+	errRuleName   = errors.New("rule name is empty")
+	errRuleMetric = errors.New("rule metric is empty")
+	errRuleKind   = errors.New("unknown rule kind")
+	errRuleOp     = errors.New("unknown rule operator")
+	errRuleWindow = errors.New("absence window must be positive")
+)
+
+// Rules is a set of alert rules with notification settings
+type Rules struct {
+	Interval time.Duration `yaml:"interval"`
+	Webhooks []string      `yaml:"webhooks"`
+	Rules    []Rule        `yaml:"rules"`
+}
+
+// Rule describes a single alert condition
+//
+// threshold compares gauge value with Value,
+// rate compares per second increase of counter with Value,
+// absence fires when metric was not updated during Window.
+type Rule struct {
+	Name   string        `yaml:"name"`
+	Metric string        `yaml:"metric"`
+	Kind   string        `yaml:"kind"`
+	Op     string        `yaml:"op"`
+	Value  float64       `yaml:"value"`
+	Window time.Duration `yaml:"window"`
+	For    time.Duration `yaml:"for"`
+}
+
+// LoadRules reads alert rules from YAML file, empty path means no rules
+func LoadRules(path string) (*Rules, error) {
+	rules := &Rules{Interval: defaultEvalInterval}
+	if path == "" {
+		return rules, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules file: %w", err)
+	}
+
+	err = yaml.Unmarshal(data, rules)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal rules file: %w", err)
+	}
+
+	if rules.Interval <= 0 {
+		rules.Interval = defaultEvalInterval
+	}
+
+	for _, rule := range rules.Rules {
+		if err = rule.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rules, nil
+}
+
+func (r *Rule) validate() error {
+	if r.Name == "" {
+		return errRuleName
+	}
+	if r.Metric == "" {
+		return fmt.Errorf("%s:%w", r.Name, errRuleMetric)
+	}
+
+	switch r.Kind {
+	case KindThreshold, KindRate:
+		if _, ok := operators[r.Op]; !ok {
+			return fmt.Errorf("%s:%w: %q", r.Name, errRuleOp, r.Op)
+		}
+	case KindAbsence:
+		if r.Window <= 0 {
+			return fmt.Errorf("%s:%w", r.Name, errRuleWindow)
+		}
+	default:
+		return fmt.Errorf("%s:%w: %q", r.Name, errRuleKind, r.Kind)
+	}
+
+	return nil
+}
+
+var operators = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
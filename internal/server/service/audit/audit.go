@@ -0,0 +1,82 @@
+// Package audit records administrative actions to append-only JSON lines file
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/arxon31/metrics-collector/pkg/logger"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+type service struct {
+	mu   sync.Mutex
+	file io.WriteCloser
+}
+
+// NewService opens audit trail file, empty path means records are only logged
+func NewService(path string) (*service, error) {
+	s := &service{}
+	if path == "" {
+		return s, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("can not mkdir: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("can not open audit file: %w", err)
+	}
+	s.file = file
+
+	return s, nil
+}
+
+// Record writes action made by subject from context
+func (s *service) Record(ctx context.Context, action, target string) {
+	record := entity.AuditRecord{
+		Time:   time.Now().UTC(),
+		Actor:  auth.Subject(ctx),
+		Action: action,
+		Target: target,
+		Tenant: auth.Tenant(ctx),
+	}
+
+	logger.Logger.Infoln("audit", "actor", record.Actor, "action", record.Action, "target", record.Target, "tenant", record.Tenant)
+
+	if s.file == nil {
+		return
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		logger.Logger.Errorln("can not marshal audit record:", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.file.Write(append(line, '\n'))
+	if err != nil {
+		logger.Logger.Errorln("can not write audit record:", err)
+	}
+}
+
+// Close closes audit trail file
+func (s *service) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+func TestService_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "trail.jsonl")
+
+	s, err := NewService(path)
+	require.NoError(t, err)
+
+	ctx := auth.WithTenant(auth.WithSubject(context.Background(), "admin"), "acme")
+	s.Record(ctx, entity.AuditDeleteMetric, "gauge/Alloc")
+	s.Record(auth.WithSubject(context.Background(), "ops"), entity.AuditResetCounter, "counter/PollCount")
+	require.NoError(t, s.Close())
+
+	// trail is appended to when reopened
+	s, err = NewService(path)
+	require.NoError(t, err)
+	s.Record(ctx, entity.AuditDumpSnapshot, "")
+	require.NoError(t, s.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []entity.AuditRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record entity.AuditRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		require.False(t, record.Time.IsZero())
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Len(t, records, 3)
+	require.Equal(t, entity.AuditRecord{Time: records[0].Time, Actor: "admin", Action: entity.AuditDeleteMetric, Target: "gauge/Alloc", Tenant: "acme"}, records[0])
+	require.Equal(t, entity.AuditRecord{Time: records[1].Time, Actor: "ops", Action: entity.AuditResetCounter, Target: "counter/PollCount", Tenant: auth.DefaultTenant}, records[1])
+	require.Equal(t, entity.AuditDumpSnapshot, records[2].Action)
+}
+
+func TestService_WithoutFile(t *testing.T) {
+	s, err := NewService("")
+	require.NoError(t, err)
+
+	s.Record(context.Background(), entity.AuditDeleteMetric, "gauge/Alloc")
+	require.NoError(t, s.Close())
+}
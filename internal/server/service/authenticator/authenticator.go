@@ -0,0 +1,165 @@
+// Package authenticator verifies bearer tokens, static tokens and JWT signed with HS256 or RS256 are supported
+package authenticator
+
+import (
+	"crypto/rsa"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+)
+
+var (
+	ErrInvalidToken  = errors.New("invalid token")
+	errStaticFormat  = errors.New("static token must be in token=role format")
+	errUnknownRole   = errors.New("unknown role")
+	errMissingSecret = errors.New("jwt secret and public key are both empty")
+)
+
+// Config describes accepted credentials
+type Config struct {
+	// StaticTokens is a comma separated token=role list
+	StaticTokens string
+	// AdminToken is a static token with admin role
+	AdminToken string
+	// JWTSecret is a HS256 shared secret
+	JWTSecret string
+	// JWTPublicKey is a path to PEM encoded RS256 public key
+	JWTPublicKey string
+	// JWTIssuer and JWTAudience are checked if not empty
+	JWTIssuer   string
+	JWTAudience string
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Role   string `json:"role"`
+	Tenant string `json:"tenant,omitempty"`
+}
+
+type staticToken struct {
+	token []byte
+	role  string
+}
+
+type authenticator struct {
+	static    []staticToken
+	secret    []byte
+	publicKey *rsa.PublicKey
+	parser    *jwt.Parser
+	enabled   bool
+}
+
+// NewAuthenticator initializes a new authenticator.
+func NewAuthenticator(cfg Config) (*authenticator, error) {
+	a := &authenticator{secret: []byte(cfg.JWTSecret)}
+
+	for _, item := range strings.Split(cfg.StaticTokens, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		token, role, ok := strings.Cut(item, "=")
+		if !ok || token == "" {
+			return nil, errStaticFormat
+		}
+		if !auth.ValidRole(role) {
+			return nil, fmt.Errorf("%q:%w", role, errUnknownRole)
+		}
+
+		a.static = append(a.static, staticToken{token: []byte(token), role: role})
+	}
+
+	if cfg.JWTPublicKey != "" {
+		pemBytes, err := os.ReadFile(cfg.JWTPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("can not read jwt public key: %w", err)
+		}
+		a.publicKey, err = jwt.ParseRSAPublicKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse jwt public key: %w", err)
+		}
+	}
+
+	// admin token alone protects only admin endpoints
+	a.enabled = len(a.static) > 0 || a.jwtEnabled()
+
+	if cfg.AdminToken != "" {
+		a.static = append(a.static, staticToken{token: []byte(cfg.AdminToken), role: auth.RoleAdmin})
+	}
+
+	methods := make([]string, 0, 2)
+	if len(a.secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if a.publicKey != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if cfg.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+	if cfg.JWTAudience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.JWTAudience))
+	}
+	a.parser = jwt.NewParser(opts...)
+
+	return a, nil
+}
+
+// Enabled reports whether reader and writer endpoints require authentication
+func (a *authenticator) Enabled() bool {
+	return a.enabled
+}
+
+// Authenticate returns identity of the bearer token owner
+func (a *authenticator) Authenticate(token string) (auth.Identity, error) {
+	for _, st := range a.static {
+		if subtle.ConstantTimeCompare([]byte(token), st.token) == 1 {
+			return auth.Identity{Subject: st.role, Role: st.role}, nil
+		}
+	}
+
+	if !a.jwtEnabled() {
+		return auth.Identity{}, ErrInvalidToken
+	}
+
+	var c claims
+	_, err := a.parser.ParseWithClaims(token, &c, a.key)
+	if err != nil {
+		return auth.Identity{}, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	if !auth.ValidRole(c.Role) {
+		return auth.Identity{}, fmt.Errorf("%w: %q:%s", ErrInvalidToken, c.Role, errUnknownRole)
+	}
+
+	return auth.Identity{Subject: c.Subject, Role: c.Role, Tenant: c.Tenant}, nil
+}
+
+func (a *authenticator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if len(a.secret) == 0 {
+			return nil, errMissingSecret
+		}
+		return a.secret, nil
+	case *jwt.SigningMethodRSA:
+		if a.publicKey == nil {
+			return nil, errMissingSecret
+		}
+		return a.publicKey, nil
+	}
+	return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+}
+
+func (a *authenticator) jwtEnabled() bool {
+	return len(a.secret) > 0 || a.publicKey != nil
+}
@@ -0,0 +1,143 @@
+package authenticator
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+)
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, c claims) string {
+	token, err := jwt.NewWithClaims(method, c).SignedString(key)
+	require.NoError(t, err)
+	return token
+}
+
+func validClaims(role string) claims {
+	return claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "agent-1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Role: role,
+	}
+}
+
+func TestAuthenticator_Static(t *testing.T) {
+	a, err := NewAuthenticator(Config{StaticTokens: "r0=reader, w0=writer", AdminToken: "adm"})
+	require.NoError(t, err)
+	require.True(t, a.Enabled())
+
+	id, err := a.Authenticate("w0")
+	require.NoError(t, err)
+	require.Equal(t, auth.RoleWriter, id.Role)
+
+	id, err = a.Authenticate("adm")
+	require.NoError(t, err)
+	require.Equal(t, auth.RoleAdmin, id.Role)
+
+	_, err = a.Authenticate("unknown")
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = NewAuthenticator(Config{StaticTokens: "t=root"})
+	require.ErrorIs(t, err, errUnknownRole)
+
+	_, err = NewAuthenticator(Config{StaticTokens: "reader"})
+	require.ErrorIs(t, err, errStaticFormat)
+}
+
+func TestAuthenticator_AdminTokenOnly(t *testing.T) {
+	a, err := NewAuthenticator(Config{AdminToken: "adm"})
+	require.NoError(t, err)
+	require.False(t, a.Enabled(), "admin token must not protect reader and writer endpoints")
+}
+
+func TestAuthenticator_HS256(t *testing.T) {
+	secret := []byte("secret")
+	a, err := NewAuthenticator(Config{JWTSecret: string(secret), JWTIssuer: "metrics"})
+	require.NoError(t, err)
+
+	t.Run("valid", func(t *testing.T) {
+		c := validClaims(auth.RoleReader)
+		c.Issuer = "metrics"
+		c.Tenant = "acme"
+
+		id, err := a.Authenticate(sign(t, jwt.SigningMethodHS256, secret, c))
+		require.NoError(t, err)
+		require.Equal(t, auth.Identity{Subject: "agent-1", Role: auth.RoleReader, Tenant: "acme"}, id)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		c := validClaims(auth.RoleReader)
+		c.Issuer = "metrics"
+		c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+
+		_, err := a.Authenticate(sign(t, jwt.SigningMethodHS256, secret, c))
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("without_expiration", func(t *testing.T) {
+		c := validClaims(auth.RoleReader)
+		c.Issuer = "metrics"
+		c.ExpiresAt = nil
+
+		_, err := a.Authenticate(sign(t, jwt.SigningMethodHS256, secret, c))
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("wrong_issuer", func(t *testing.T) {
+		c := validClaims(auth.RoleReader)
+		c.Issuer = "other"
+
+		_, err := a.Authenticate(sign(t, jwt.SigningMethodHS256, secret, c))
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("wrong_secret", func(t *testing.T) {
+		c := validClaims(auth.RoleReader)
+		c.Issuer = "metrics"
+
+		_, err := a.Authenticate(sign(t, jwt.SigningMethodHS256, []byte("guess"), c))
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("unknown_role", func(t *testing.T) {
+		c := validClaims("root")
+		c.Issuer = "metrics"
+
+		_, err := a.Authenticate(sign(t, jwt.SigningMethodHS256, secret, c))
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestAuthenticator_RS256(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwt.pub")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+
+	a, err := NewAuthenticator(Config{JWTPublicKey: path})
+	require.NoError(t, err)
+
+	id, err := a.Authenticate(sign(t, jwt.SigningMethodRS256, privateKey, validClaims(auth.RoleWriter)))
+	require.NoError(t, err)
+	require.Equal(t, auth.RoleWriter, id.Role)
+
+	// HS256 token signed with public key must not be accepted when only RS256 is configured
+	_, err = a.Authenticate(sign(t, jwt.SigningMethodHS256, der, validClaims(auth.RoleAdmin)))
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
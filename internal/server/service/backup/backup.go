@@ -0,0 +1,264 @@
+// Package backup dumps, exports and imports repository snapshots on demand
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/arxon31/metrics-collector/pkg/logger"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+	"github.com/arxon31/metrics-collector/internal/snapshot"
+)
+
+var (
+	ErrDumpDisabled    = errors.New("file storage is disabled")
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+)
+
+type repo interface {
+	// Tenants returns names of all tenants having metrics
+	Tenants(ctx context.Context) ([]string, error)
+	// Metrics returns all metrics values
+	Metrics(ctx context.Context) ([]entity.MetricDTO, error)
+	// Metadata returns metadata of all metrics
+	Metadata(ctx context.Context) ([]entity.Metadata, error)
+	// Tokens returns all api tokens
+	Tokens(ctx context.Context) ([]entity.APIToken, error)
+	// StoreBatch stores batch of metrics
+	StoreBatch(ctx context.Context, metrics []entity.MetricDTO) error
+	// StoreMetadata replaces metric metadata
+	StoreMetadata(ctx context.Context, metadata entity.Metadata) error
+	// StoreToken saves api token
+	StoreToken(ctx context.Context, token entity.APIToken) error
+	// Delete removes metric
+	Delete(ctx context.Context, metricType, name string) error
+	// ResetCounter sets counter value to zero
+	ResetCounter(ctx context.Context, name string) error
+}
+
+// writers are paused while snapshot is restored, restored tenants must fit their quota
+type writers interface {
+	// Exclusive runs op with all writes paused
+	Exclusive(op func() error) error
+	// CheckQuota returns entity.ErrQuotaExceeded if tenant of ctx can not hold count metrics
+	CheckQuota(ctx context.Context, count int) error
+}
+
+type dumper interface {
+	Dump(ctx context.Context) error
+}
+
+// cache is invalidated after restore, it may keep types of metrics or tokens replaced by snapshot
+type cache interface {
+	Invalidate()
+}
+
+type auditor interface {
+	Record(ctx context.Context, action, target string)
+}
+
+type backupService struct {
+	repo        repo
+	writers     writers
+	dumper      dumper
+	compression snapshot.Compression
+	auditor     auditor
+	caches      []cache
+}
+
+// NewBackupService initializes a new backup service, nil dumper means there is no file storage to dump to
+func NewBackupService(repo repo, writers writers, dumper dumper, compression snapshot.Compression, auditor auditor, caches ...cache) *backupService {
+	return &backupService{
+		repo:        repo,
+		writers:     writers,
+		dumper:      dumper,
+		compression: compression,
+		auditor:     auditor,
+		caches:      caches,
+	}
+}
+
+// Dump writes file storage snapshot now instead of waiting for store interval
+func (s *backupService) Dump(ctx context.Context) error {
+	if s.dumper == nil {
+		return ErrDumpDisabled
+	}
+
+	err := s.dumper.Dump(ctx)
+	if err != nil {
+		logger.Logger.Error(err)
+		return err
+	}
+
+	s.auditor.Record(ctx, entity.AuditDumpSnapshot, "file")
+
+	return nil
+}
+
+// Export streams snapshot of all tenants to w in the file storage format
+func (s *backupService) Export(ctx context.Context, w io.Writer) error {
+	err := snapshot.Stream(ctx, s.repo, w, s.compression)
+	if err != nil {
+		logger.Logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// Import loads snapshot read from r. Merge overwrites metrics present in snapshot and keeps the others,
+// replace removes all metrics first. Metadata and tokens of snapshot are upserted in both modes.
+// Writes are paused during import and nothing is changed if a tenant would exceed its quota,
+// otherwise import is not atomic, a failed import may leave snapshot loaded partially.
+func (s *backupService) Import(ctx context.Context, r io.Reader, mode string) (entity.RestoreSummary, error) {
+	summary := entity.RestoreSummary{Mode: mode}
+
+	if mode != entity.RestoreMerge && mode != entity.RestoreReplace {
+		return summary, entity.ErrRestoreMode
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return summary, err
+	}
+
+	snap, err := snapshot.Decode(data)
+	if err != nil {
+		return summary, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	// write-ahead log position is meaningful only for the server which dumped it
+	snap.WALSeq = 0
+
+	err = s.writers.Exclusive(func() error {
+		err := s.checkQuota(ctx, snap, mode)
+		if err != nil {
+			return err
+		}
+
+		if mode == entity.RestoreReplace {
+			summary.Removed, err = s.removeAll(ctx)
+		} else {
+			err = s.clear(ctx, snap)
+		}
+		if err != nil {
+			return err
+		}
+
+		return snapshot.Load(ctx, s.repo, snap)
+	})
+	if err != nil {
+		logger.Logger.Error(err)
+		return summary, err
+	}
+
+	for _, c := range s.caches {
+		c.Invalidate()
+	}
+
+	namespaces := snap.Namespaces()
+	for _, ns := range namespaces {
+		if len(ns.Metrics) > 0 || len(ns.Metadata) > 0 {
+			summary.Tenants++
+		}
+		summary.Metrics += len(ns.Metrics)
+		summary.Metadata += len(ns.Metadata)
+	}
+	summary.Tokens = len(snap.Tokens)
+
+	s.auditor.Record(ctx, entity.AuditRestore, mode)
+
+	return summary, nil
+}
+
+// checkQuota checks every tenant of snapshot holds no more metrics than allowed once it is restored
+func (s *backupService) checkQuota(ctx context.Context, snap snapshot.Snapshot, mode string) error {
+	for tenant, ns := range snap.Namespaces() {
+		tenantCtx := auth.WithTenant(ctx, tenant)
+
+		names := make(map[string]struct{}, len(ns.Metrics))
+		for _, m := range ns.Metrics {
+			names[m.Name] = struct{}{}
+		}
+
+		if mode == entity.RestoreMerge {
+			metrics, err := s.repo.Metrics(tenantCtx)
+			if err != nil {
+				return err
+			}
+			for _, m := range metrics {
+				names[m.Name] = struct{}{}
+			}
+		}
+
+		err := s.writers.CheckQuota(tenantCtx, len(names))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeAll deletes metrics of all tenants
+func (s *backupService) removeAll(ctx context.Context) (int, error) {
+	tenants, err := s.repo.Tenants(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, tenant := range tenants {
+		tenantCtx := auth.WithTenant(ctx, tenant)
+
+		metrics, err := s.repo.Metrics(tenantCtx)
+		if err != nil {
+			return removed, err
+		}
+
+		for _, m := range metrics {
+			err = s.repo.Delete(tenantCtx, m.MetricType, m.Name)
+			if err != nil && !errors.Is(err, repoerr.ErrMetricNotFound) {
+				return removed, err
+			}
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// clear prepares metrics of snapshot to be overwritten: counters are reset
+// and metrics stored with another type are removed
+func (s *backupService) clear(ctx context.Context, snap snapshot.Snapshot) error {
+	for tenant, ns := range snap.Namespaces() {
+		tenantCtx := auth.WithTenant(ctx, tenant)
+
+		for _, m := range ns.Metrics {
+			var other string
+			switch m.MetricType {
+			case entity.GaugeType:
+				other = entity.CounterType
+			case entity.CounterType:
+				other = entity.GaugeType
+				err := s.repo.ResetCounter(tenantCtx, m.Name)
+				if err != nil && !errors.Is(err, repoerr.ErrMetricNotFound) {
+					return err
+				}
+			default:
+				continue
+			}
+
+			err := s.repo.Delete(tenantCtx, other, m.Name)
+			if err != nil && !errors.Is(err, repoerr.ErrMetricNotFound) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/memory"
+	"github.com/arxon31/metrics-collector/internal/snapshot"
+)
+
+type nopAuditor struct{}
+
+func (nopAuditor) Record(context.Context, string, string) {}
+
+type countingCache struct{ invalidated int }
+
+func (c *countingCache) Invalidate() { c.invalidated++ }
+
+// limitedWriters allows limit metrics to every tenant, zero limit means no quota
+type limitedWriters struct {
+	limit     int
+	exclusive int
+}
+
+func (w *limitedWriters) Exclusive(op func() error) error {
+	w.exclusive++
+	return op()
+}
+
+func (w *limitedWriters) CheckQuota(_ context.Context, count int) error {
+	if w.limit > 0 && count > w.limit {
+		return entity.ErrQuotaExceeded
+	}
+	return nil
+}
+
+func TestBackupService(t *testing.T) {
+	ctx := context.Background()
+	acme := auth.WithTenant(ctx, "acme")
+
+	source := memory.NewMapStorage()
+	require.NoError(t, source.StoreCounter(ctx, "requests", 5))
+	require.NoError(t, source.StoreGauge(acme, "load", 0.5))
+	require.NoError(t, source.StoreToken(ctx, entity.APIToken{ID: "t1", Tenant: "acme"}))
+
+	var exported bytes.Buffer
+	err := NewBackupService(source, &limitedWriters{}, nil, snapshot.CompressionGzip, nopAuditor{}).Export(ctx, &exported)
+	require.NoError(t, err)
+
+	newTarget := func(t *testing.T) *memory.MapStorage {
+		target := memory.NewMapStorage()
+		require.NoError(t, target.StoreCounter(ctx, "requests", 100))
+		require.NoError(t, target.StoreCounter(ctx, "load", 1), "counter replaced by gauge of snapshot")
+		require.NoError(t, target.StoreGauge(ctx, "kept", 1))
+		return target
+	}
+
+	t.Run("merge", func(t *testing.T) {
+		target := newTarget(t)
+		writers := &limitedWriters{limit: 3}
+		cache := &countingCache{}
+
+		summary, err := NewBackupService(target, writers, nil, snapshot.CompressionNone, nopAuditor{}, cache).
+			Import(ctx, bytes.NewReader(exported.Bytes()), entity.RestoreMerge)
+		require.NoError(t, err)
+		require.Equal(t, entity.RestoreSummary{Mode: entity.RestoreMerge, Tenants: 2, Metrics: 2, Tokens: 1}, summary)
+		require.Equal(t, 1, writers.exclusive, "writes are paused during import")
+		require.Equal(t, 1, cache.invalidated)
+
+		counter, err := target.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Equal(t, int64(5), counter, "counter is set to snapshot value")
+
+		_, err = target.Gauge(ctx, "kept")
+		require.NoError(t, err)
+
+		gauge, err := target.Gauge(acme, "load")
+		require.NoError(t, err)
+		require.Equal(t, 0.5, gauge)
+
+		tokens, err := target.Tokens(ctx)
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		target := newTarget(t)
+
+		summary, err := NewBackupService(target, &limitedWriters{limit: 1}, nil, snapshot.CompressionNone, nopAuditor{}).
+			Import(ctx, bytes.NewReader(exported.Bytes()), entity.RestoreReplace)
+		require.NoError(t, err)
+		require.Equal(t, 3, summary.Removed)
+
+		_, err = target.Gauge(ctx, "kept")
+		require.Error(t, err)
+		_, err = target.Counter(ctx, "load")
+		require.Error(t, err)
+
+		counter, err := target.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Equal(t, int64(5), counter)
+	})
+
+	t.Run("quota_exceeded", func(t *testing.T) {
+		target := newTarget(t)
+
+		_, err := NewBackupService(target, &limitedWriters{limit: 2}, nil, snapshot.CompressionNone, nopAuditor{}).
+			Import(ctx, bytes.NewReader(exported.Bytes()), entity.RestoreMerge)
+		require.ErrorIs(t, err, entity.ErrQuotaExceeded)
+
+		counter, err := target.Counter(ctx, "requests")
+		require.NoError(t, err)
+		require.Equal(t, int64(100), counter, "nothing is restored")
+		_, err = target.Counter(ctx, "load")
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid_snapshot", func(t *testing.T) {
+		data := append([]byte(nil), exported.Bytes()...)
+		data[len(data)-1] ^= 0xff
+
+		_, err := NewBackupService(memory.NewMapStorage(), &limitedWriters{}, nil, snapshot.CompressionNone, nopAuditor{}).
+			Import(ctx, bytes.NewReader(data), entity.RestoreMerge)
+		require.ErrorIs(t, err, ErrInvalidSnapshot)
+	})
+
+	t.Run("unknown_mode", func(t *testing.T) {
+		_, err := NewBackupService(memory.NewMapStorage(), &limitedWriters{}, nil, snapshot.CompressionNone, nopAuditor{}).
+			Import(ctx, bytes.NewReader(exported.Bytes()), "append")
+		require.ErrorIs(t, err, entity.ErrRestoreMode)
+	})
+
+	t.Run("dump_disabled", func(t *testing.T) {
+		err := NewBackupService(memory.NewMapStorage(), &limitedWriters{}, nil, snapshot.CompressionNone, nopAuditor{}).Dump(ctx)
+		require.ErrorIs(t, err, ErrDumpDisabled)
+	})
+}
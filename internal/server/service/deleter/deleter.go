@@ -0,0 +1,103 @@
+package deleter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/arxon31/metrics-collector/pkg/logger"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+)
+
+type repo interface {
+	// Metrics returns all metrics values
+	Metrics(ctx context.Context) ([]entity.MetricDTO, error)
+	// Delete removes metric
+	Delete(ctx context.Context, metricType, name string) error
+	// ResetCounter sets counter value to zero
+	ResetCounter(ctx context.Context, name string) error
+}
+
+type auditor interface {
+	Record(ctx context.Context, action, target string)
+}
+
+type deleterService struct {
+	repo    repo
+	auditor auditor
+}
+
+// NewDeleterService initializes a new deleter service.
+func NewDeleterService(repo repo, auditor auditor) *deleterService {
+	return &deleterService{
+		repo:    repo,
+		auditor: auditor,
+	}
+}
+
+// DeleteMetric deletes metric by type and name
+func (s *deleterService) DeleteMetric(ctx context.Context, metricType, name string) error {
+	if metricType != entity.GaugeType && metricType != entity.CounterType {
+		return fmt.Errorf("%s:%w", name, entity.ErrMetricType)
+	}
+
+	err := s.repo.Delete(ctx, metricType, name)
+	if err != nil {
+		logger.Logger.Error(err)
+		return err
+	}
+
+	s.auditor.Record(ctx, entity.AuditDeleteMetric, metricType+"/"+name)
+
+	return nil
+}
+
+// DeleteMetrics deletes all metrics matching selector and returns deleted ones
+func (s *deleterService) DeleteMetrics(ctx context.Context, selector entity.MetricSelector) ([]entity.MetricDTO, error) {
+	match, err := selector.Matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := s.repo.Metrics(ctx)
+	if err != nil {
+		logger.Logger.Error(err)
+		return nil, err
+	}
+
+	deleted := make([]entity.MetricDTO, 0)
+	for _, m := range metrics {
+		if !match(m) {
+			continue
+		}
+
+		err = s.repo.Delete(ctx, m.MetricType, m.Name)
+		if err != nil {
+			if errors.Is(err, repoerr.ErrMetricNotFound) {
+				continue
+			}
+			logger.Logger.Error(err)
+			return deleted, err
+		}
+
+		deleted = append(deleted, entity.MetricDTO{Name: m.Name, MetricType: m.MetricType})
+		s.auditor.Record(ctx, entity.AuditDeleteMetrics, m.MetricType+"/"+m.Name)
+	}
+
+	return deleted, nil
+}
+
+// ResetCounter sets counter value to zero
+func (s *deleterService) ResetCounter(ctx context.Context, name string) error {
+	err := s.repo.ResetCounter(ctx, name)
+	if err != nil {
+		logger.Logger.Error(err)
+		return err
+	}
+
+	s.auditor.Record(ctx, entity.AuditResetCounter, entity.CounterType+"/"+name)
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package deleter
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/memory"
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+)
+
+type auditorStub struct {
+	records []string
+}
+
+func (a *auditorStub) Record(_ context.Context, action, target string) {
+	a.records = append(a.records, action+" "+target)
+}
+
+func newTestRepo(t *testing.T) *memory.MapStorage {
+	ctx := context.Background()
+	repo := memory.NewMapStorage()
+	require.NoError(t, repo.StoreGauge(ctx, entity.HeapAlloc, 1))
+	require.NoError(t, repo.StoreGauge(ctx, entity.HeapIdle, 2))
+	require.NoError(t, repo.StoreGauge(ctx, entity.Alloc, 3))
+	require.NoError(t, repo.StoreCounter(ctx, "HeapEvents", 4))
+	return repo
+}
+
+func TestDeleterService_DeleteMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("by_glob_and_type", func(t *testing.T) {
+		repo := newTestRepo(t)
+		auditor := &auditorStub{}
+		s := NewDeleterService(repo, auditor)
+
+		deleted, err := s.DeleteMetrics(ctx, entity.MetricSelector{Type: entity.GaugeType, Glob: "Heap*"})
+		require.NoError(t, err)
+
+		sort.Slice(deleted, func(i, j int) bool { return deleted[i].Name < deleted[j].Name })
+		require.Equal(t, []entity.MetricDTO{
+			{Name: entity.HeapAlloc, MetricType: entity.GaugeType},
+			{Name: entity.HeapIdle, MetricType: entity.GaugeType},
+		}, deleted)
+
+		metrics, err := repo.Metrics(ctx)
+		require.NoError(t, err)
+		require.Len(t, metrics, 2, "other type and names are kept")
+
+		sort.Strings(auditor.records)
+		require.Equal(t, []string{
+			entity.AuditDeleteMetrics + " gauge/" + entity.HeapAlloc,
+			entity.AuditDeleteMetrics + " gauge/" + entity.HeapIdle,
+		}, auditor.records)
+	})
+
+	t.Run("by_regex", func(t *testing.T) {
+		repo := newTestRepo(t)
+		s := NewDeleterService(repo, &auditorStub{})
+
+		deleted, err := s.DeleteMetrics(ctx, entity.MetricSelector{Regex: "^Heap(Alloc|Events)$"})
+		require.NoError(t, err)
+		require.Len(t, deleted, 2)
+	})
+
+	t.Run("bad_selector", func(t *testing.T) {
+		auditor := &auditorStub{}
+		s := NewDeleterService(newTestRepo(t), auditor)
+
+		_, err := s.DeleteMetrics(ctx, entity.MetricSelector{})
+		require.ErrorIs(t, err, entity.ErrSelectorEmpty)
+		require.Empty(t, auditor.records)
+	})
+}
+
+func TestDeleterService_ResetCounter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reset", func(t *testing.T) {
+		repo := newTestRepo(t)
+		auditor := &auditorStub{}
+		s := NewDeleterService(repo, auditor)
+
+		require.NoError(t, s.ResetCounter(ctx, "HeapEvents"))
+
+		value, err := repo.Counter(ctx, "HeapEvents")
+		require.NoError(t, err)
+		require.Equal(t, int64(0), value)
+		require.Equal(t, []string{entity.AuditResetCounter + " counter/HeapEvents"}, auditor.records)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		auditor := &auditorStub{}
+		s := NewDeleterService(newTestRepo(t), auditor)
+
+		err := s.ResetCounter(ctx, "missing")
+		require.ErrorIs(t, err, repoerr.ErrMetricNotFound)
+		require.Empty(t, auditor.records, "failed reset is not audited")
+	})
+}
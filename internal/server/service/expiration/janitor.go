@@ -0,0 +1,128 @@
+// Package expiration evicts metrics that were not updated for longer than their TTL
+package expiration
+
+import (
+	"context"
+	"time"
+
+	"github.com/arxon31/metrics-collector/pkg/logger"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+const (
+	maxSweepInterval = time.Minute
+	minSweepInterval = time.Second
+)
+
+type repo interface {
+	// Metrics returns all metrics values
+	Metrics(ctx context.Context) ([]entity.MetricDTO, error)
+	// Evict removes metrics which were not updated since metric.UpdatedAt
+	Evict(ctx context.Context, metrics []entity.MetricDTO) error
+	// Tenants returns names of all tenants having metrics
+	Tenants(ctx context.Context) ([]string, error)
+}
+
+type janitor struct {
+	repo     repo
+	policy   *Policy
+	interval time.Duration
+}
+
+// NewJanitor initializes a new janitor service.
+func NewJanitor(repo repo, policy *Policy) *janitor {
+	return &janitor{
+		repo:     repo,
+		policy:   policy,
+		interval: sweepInterval(policy),
+	}
+}
+
+// Run sweeps expired metrics by interval until context is done
+func (j *janitor) Run(ctx context.Context) {
+	if !j.policy.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(j.interval)
+	logger.Logger.Infof("evicting expired metrics every %s", j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.sweep(ctx); err != nil {
+				logger.Logger.Errorln("can not evict expired metrics:", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (j *janitor) sweep(ctx context.Context) error {
+	tenants, err := j.repo.Tenants(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, tenant := range tenants {
+		err = j.sweepTenant(auth.WithTenant(ctx, tenant))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *janitor) sweepTenant(ctx context.Context) error {
+	metrics, err := j.repo.Metrics(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expired := make([]entity.MetricDTO, 0)
+	for _, m := range metrics {
+		if j.policy.Expired(m, now) {
+			expired = append(expired, m)
+		}
+	}
+
+	if len(expired) == 0 {
+		return nil
+	}
+
+	err = j.repo.Evict(ctx, expired)
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.Infof("evicted %d expired metrics of tenant %q", len(expired), auth.Tenant(ctx))
+	return nil
+}
+
+// sweepInterval returns half of the smallest positive TTL bounded by min and max sweep intervals
+func sweepInterval(p *Policy) time.Duration {
+	interval := maxSweepInterval
+
+	ttls := []time.Duration{p.defaultTTL}
+	for _, o := range p.overrides {
+		ttls = append(ttls, o.ttl)
+	}
+
+	for _, ttl := range ttls {
+		if ttl > 0 && ttl/2 < interval {
+			interval = ttl / 2
+		}
+	}
+
+	if interval < minSweepInterval {
+		interval = minSweepInterval
+	}
+
+	return interval
+}
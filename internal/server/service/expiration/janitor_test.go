@@ -0,0 +1,49 @@
+package expiration
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/memory"
+)
+
+func TestJanitor_Sweep(t *testing.T) {
+	ctx := context.Background()
+	acme := auth.WithTenant(ctx, "acme")
+
+	p, err := NewPolicy(time.Minute, "Heap*=1h,PollCount=0")
+	require.NoError(t, err)
+
+	repo := memory.NewMapStorage()
+	stale := time.Now().Add(-2 * time.Minute)
+	for _, tenantCtx := range []context.Context{ctx, acme} {
+		value := 1.0
+		delta := int64(1)
+		require.NoError(t, repo.StoreBatch(tenantCtx, []entity.MetricDTO{
+			{Name: entity.Alloc, MetricType: entity.GaugeType, Gauge: &value, UpdatedAt: stale},
+			{Name: entity.HeapAlloc, MetricType: entity.GaugeType, Gauge: &value, UpdatedAt: stale},
+			{Name: entity.PollCount, MetricType: entity.CounterType, Counter: &delta, UpdatedAt: stale},
+		}))
+		require.NoError(t, repo.StoreGauge(tenantCtx, entity.Frees, value))
+	}
+
+	require.NoError(t, NewJanitor(repo, p).sweep(ctx))
+
+	for _, tenantCtx := range []context.Context{ctx, acme} {
+		metrics, err := repo.Metrics(tenantCtx)
+		require.NoError(t, err)
+
+		names := make([]string, 0, len(metrics))
+		for _, m := range metrics {
+			names = append(names, m.Name)
+		}
+		sort.Strings(names)
+		require.Equal(t, []string{entity.Frees, entity.HeapAlloc, entity.PollCount}, names, "tenant %q", auth.Tenant(tenantCtx))
+	}
+}
@@ -0,0 +1,87 @@
+package expiration
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+var errOverrideFormat = errors.New("ttl override must be in pattern=duration format")
+
+type override struct {
+	pattern string
+	ttl     time.Duration
+}
+
+// Policy decides when metric becomes stale
+type Policy struct {
+	defaultTTL time.Duration
+	overrides  []override
+}
+
+// NewPolicy creates TTL policy from default TTL and comma separated pattern=duration overrides,
+// e.g. "Heap*=1m,PollCount=0". Zero TTL means metric never expires, first matching pattern wins.
+func NewPolicy(defaultTTL time.Duration, overrides string) (*Policy, error) {
+	p := &Policy{defaultTTL: defaultTTL}
+
+	for _, item := range strings.Split(overrides, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		pattern, value, ok := strings.Cut(item, "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("%s: %w", item, errOverrideFormat)
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s: %w", item, err)
+		}
+
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", item, err)
+		}
+
+		p.overrides = append(p.overrides, override{pattern: pattern, ttl: ttl})
+	}
+
+	return p, nil
+}
+
+// Enabled reports whether any metric can expire
+func (p *Policy) Enabled() bool {
+	if p.defaultTTL > 0 {
+		return true
+	}
+	for _, o := range p.overrides {
+		if o.ttl > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// TTL returns time to live for metric name
+func (p *Policy) TTL(name string) time.Duration {
+	for _, o := range p.overrides {
+		if ok, _ := path.Match(o.pattern, name); ok {
+			return o.ttl
+		}
+	}
+	return p.defaultTTL
+}
+
+// Expired reports whether metric was not updated for longer than its TTL
+func (p *Policy) Expired(metric entity.MetricDTO, now time.Time) bool {
+	ttl := p.TTL(metric.Name)
+	if ttl <= 0 || metric.UpdatedAt.IsZero() {
+		return false
+	}
+	return now.Sub(metric.UpdatedAt) > ttl
+}
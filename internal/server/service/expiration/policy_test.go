@@ -0,0 +1,51 @@
+package expiration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+func TestNewPolicy(t *testing.T) {
+	t.Run("overrides", func(t *testing.T) {
+		p, err := NewPolicy(time.Hour, "Heap*=1m, PollCount=0")
+		require.NoError(t, err)
+		require.True(t, p.Enabled())
+		require.Equal(t, time.Minute, p.TTL(entity.HeapAlloc))
+		require.Equal(t, time.Duration(0), p.TTL(entity.PollCount))
+		require.Equal(t, time.Hour, p.TTL(entity.Alloc))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		p, err := NewPolicy(0, "")
+		require.NoError(t, err)
+		require.False(t, p.Enabled())
+	})
+
+	t.Run("bad_format", func(t *testing.T) {
+		_, err := NewPolicy(0, "Heap*")
+		require.ErrorIs(t, err, errOverrideFormat)
+
+		_, err = NewPolicy(0, "Heap*=soon")
+		require.Error(t, err)
+	})
+}
+
+func TestPolicy_Expired(t *testing.T) {
+	p, err := NewPolicy(time.Minute, "PollCount=0")
+	require.NoError(t, err)
+
+	now := time.Now()
+	stale := entity.MetricDTO{Name: entity.Alloc, UpdatedAt: now.Add(-2 * time.Minute)}
+	fresh := entity.MetricDTO{Name: entity.Alloc, UpdatedAt: now.Add(-time.Second)}
+	forever := entity.MetricDTO{Name: entity.PollCount, UpdatedAt: now.Add(-time.Hour)}
+	unknown := entity.MetricDTO{Name: entity.Alloc}
+
+	require.True(t, p.Expired(stale, now))
+	require.False(t, p.Expired(fresh, now))
+	require.False(t, p.Expired(forever, now))
+	require.False(t, p.Expired(unknown, now))
+}
@@ -2,76 +2,163 @@ package failover
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
-	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/arxon31/metrics-collector/pkg/logger"
 
 	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/snapshot"
 )
 
 type repo interface {
 	// Metrics returns all metrics values
 	Metrics(ctx context.Context) ([]entity.MetricDTO, error)
-	StoreGauge(ctx context.Context, name string, value float64) error
-	StoreCounter(ctx context.Context, name string, value int64) error
+	// StoreBatch stores batch of metrics
+	StoreBatch(ctx context.Context, metrics []entity.MetricDTO) error
+	// Metadata returns metadata of all metrics
+	Metadata(ctx context.Context) ([]entity.Metadata, error)
+	// StoreMetadata replaces metric metadata
+	StoreMetadata(ctx context.Context, metadata entity.Metadata) error
+	// Tenants returns names of all tenants having metrics
+	Tenants(ctx context.Context) ([]string, error)
+	// StoreToken saves api token
+	StoreToken(ctx context.Context, token entity.APIToken) error
+	// Tokens returns all api tokens
+	Tokens(ctx context.Context) ([]entity.APIToken, error)
 }
 
+// writeAheadLog makes writes durable between dumps, dumps are its checkpoints
+type writeAheadLog interface {
+	// Checkpoint calls capture with writes paused and returns the first log segment not covered by captured state
+	Checkpoint(capture func() error) (uint64, error)
+	// Truncate removes log segments before seq
+	Truncate(seq uint64) error
+	// Replay applies log segments starting from seq to repository
+	Replay(ctx context.Context, from uint64) error
+}
+
+// checkpointInterval is used when store interval is 0, writes are durable in write-ahead log
+// and dumps only bound its size and replay time
+const checkpointInterval = 5 * time.Minute
+
 type service struct {
 	repo         repo
 	path         string
 	dumpInterval time.Duration
 	isRestore    bool
+	wal          writeAheadLog
+	compression  snapshot.Compression
+	keep         int
+
+	// dumpMu serializes dumps by interval and on demand, they rotate the same files
+	dumpMu sync.Mutex
+}
+
+// Option configures failover service
+type Option func(*service)
+
+// WithWAL makes dumps checkpoints of wal, repo must be the repository wal applies writes to
+func WithWAL(wal writeAheadLog) Option {
+	return func(s *service) {
+		s.wal = wal
+	}
+}
+
+// WithCompression compresses snapshot payload
+func WithCompression(compression snapshot.Compression) Option {
+	return func(s *service) {
+		s.compression = compression
+	}
 }
 
-func NewService(repo repo, path string, dumpInterval time.Duration, isRestore bool) *service {
-	return &service{
+// WithHistory keeps keep previous snapshots as path.1 ... path.keep, restore falls back to them
+func WithHistory(keep int) Option {
+	return func(s *service) {
+		s.keep = keep
+	}
+}
+
+func NewService(repo repo, path string, dumpInterval time.Duration, isRestore bool, opts ...Option) *service {
+	s := &service{
 		repo:         repo,
 		path:         path,
 		dumpInterval: dumpInterval,
 		isRestore:    isRestore,
+		compression:  snapshot.CompressionNone,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
-// Run runs the service with the given context.
-func (s *service) Run(ctx context.Context) {
-	if s.isRestore {
-		if err := s.restore(ctx); err != nil {
-			logger.Logger.Errorln("can not restore data:", err)
-		} else {
-			logger.Logger.Infoln("restored data from:", s.path)
+// Restore loads dumped data and replays write-ahead log written after the dump,
+// it must complete before repository accepts writes. Missing dump is not an error, the whole log is replayed then,
+// but if no dump is valid nothing is replayed so the log is not applied on top of missing data.
+func (s *service) Restore(ctx context.Context) error {
+	if !s.isRestore {
+		if s.wal == nil || s.path == "" {
+			return nil
 		}
+		// log of the previous run is discarded together with its dump
+		return s.dump()
+	}
+
+	seq, err := s.restore(ctx)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		logger.Logger.Infoln("nothing to restore from:", s.path)
+	case err != nil:
+		return fmt.Errorf("can not restore data: %w", err)
 	}
 
+	if s.wal == nil {
+		return nil
+	}
+
+	err = s.wal.Replay(ctx, seq)
+	if err != nil {
+		return fmt.Errorf("can not replay wal: %w", err)
+	}
+	logger.Logger.Infoln("replayed wal from segment:", seq)
+
+	return nil
+}
+
+// Run dumps data by interval until context is done, then dumps it once more.
+func (s *service) Run(ctx context.Context) {
 	if s.path == "" {
 		return
 	}
 
-	if s.dumpInterval > 0 {
-		err := s.dumpByInterval(ctx)
-		if err != nil {
-			logger.Logger.Errorln("can not dump data by interval:", err)
-			return
-		}
-
-	} else {
-		err := s.dumpSynchronously(ctx)
-		if err != nil {
-			logger.Logger.Errorln("can not dump data synchronously:", err)
+	interval := s.dumpInterval
+	if interval <= 0 {
+		if s.wal == nil {
+			logger.Logger.Errorln("store interval is 0 but wal is disabled, data is dumped only on shutdown")
+			<-ctx.Done()
+			if err := s.dump(); err != nil {
+				logger.Logger.Errorln("can not dump data after shutdown:", err)
+			}
 			return
 		}
+		interval = checkpointInterval
 	}
 
+	err := s.dumpByInterval(ctx, interval)
+	if err != nil {
+		logger.Logger.Errorln("can not dump data by interval:", err)
+	}
 }
 
-func (s *service) dumpByInterval(ctx context.Context) error {
-	ticker := time.NewTicker(s.dumpInterval)
-	logger.Logger.Infof("dumping data by interval %s to %s", s.dumpInterval, s.path)
+func (s *service) dumpByInterval(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	logger.Logger.Infof("dumping data by interval %s to %s", interval, s.path)
 	defer ticker.Stop()
 	for {
 		select {
@@ -93,95 +180,99 @@ func (s *service) dumpByInterval(ctx context.Context) error {
 	}
 }
 
-func (s *service) dumpSynchronously(ctx context.Context) error {
-	logger.Logger.Infof("dumping data synchronously to %s", s.path)
-	for {
-		select {
-		case <-ctx.Done():
-			err := s.dump()
-			if err != nil {
-				logger.Logger.Errorln("can not dump data after shutdown:", err)
-				return err
-			}
-			logger.Logger.Infoln("dumped data after shutdown to:", s.path)
-			return nil
-		default:
-			err := s.dump()
-			if err != nil {
-				logger.Logger.Errorln("can not dump data:", err)
-				return err
-			}
-		}
-	}
+// Dump writes snapshot now, it is a checkpoint of write-ahead log if enabled
+func (s *service) Dump(_ context.Context) error {
+	return s.dump()
 }
 
 func (s *service) dump() error {
-	dir := filepath.Dir(s.path)
-	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-		return fmt.Errorf("can not mkdir: %w", err)
-	}
+	s.dumpMu.Lock()
+	defer s.dumpMu.Unlock()
 
-	file, err := os.OpenFile(s.path, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+	snap, err := s.capture(context.Background())
 	if err != nil {
-		return fmt.Errorf("can not open dump file: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	metrics, err := s.repo.Metrics(context.Background())
+	data, err := snapshot.Encode(snap, s.compression)
 	if err != nil {
 		return err
 	}
 
-	metricsJSON, err := json.Marshal(metrics)
+	err = snapshot.WriteFile(s.path, data, s.keep)
 	if err != nil {
-		return fmt.Errorf("can not marshal to JSON: %w", err)
+		return err
 	}
 
-	_, err = file.Write(metricsJSON)
-	if err != nil {
-		return fmt.Errorf("can not write to file: %w", err)
+	if s.wal == nil {
+		return nil
 	}
 
-	return nil
+	// segments are removed only when no kept snapshot needs them, restore may fall back to the oldest one
+	return s.wal.Truncate(s.oldestWALSeq(snap.WALSeq))
 }
 
-func (s *service) restore(ctx context.Context) error {
-	if _, err := os.Stat(s.path); errors.Is(err, os.ErrNotExist) {
-		return err
+// oldestWALSeq returns the first wal segment needed by kept snapshots
+func (s *service) oldestWALSeq(seq uint64) uint64 {
+	for i := 1; i <= s.keep; i++ {
+		hdr, err := snapshot.ReadHeader(snapshot.HistoryPath(s.path, i))
+		if err != nil {
+			continue
+		}
+		if hdr.WALSeq < seq {
+			seq = hdr.WALSeq
+		}
 	}
+	return seq
+}
 
-	file, err := os.Open(s.path)
-	if err != nil {
-		return fmt.Errorf("can not open restoring file: %w", err)
+// capture takes snapshot, with wal it is a checkpoint consistent with the log
+func (s *service) capture(ctx context.Context) (snapshot.Snapshot, error) {
+	if s.wal == nil {
+		return snapshot.Capture(ctx, s.repo)
 	}
-	defer file.Close()
 
-	rawMetrics, err := io.ReadAll(file)
+	var snap snapshot.Snapshot
+	seq, err := s.wal.Checkpoint(func() error {
+		var err error
+		snap, err = snapshot.Capture(ctx, s.repo)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("can not read restoring file: %w", err)
+		return snap, err
 	}
+	snap.WALSeq = seq
 
-	metrics := make([]entity.MetricDTO, 0)
+	return snap, nil
+}
 
-	err = json.Unmarshal(rawMetrics, &metrics)
-	if err != nil {
-		return fmt.Errorf("can not unmarshal to DTO: %w", err)
-	}
+// restore loads the newest valid snapshot and returns the first wal segment not included in it,
+// broken snapshots are skipped and reported
+func (s *service) restore(ctx context.Context) (uint64, error) {
+	var skipped []error
 
-	for _, m := range metrics {
-		switch m.MetricType {
-		case entity.GaugeType:
-			err = s.repo.StoreGauge(ctx, m.Name, *m.Gauge)
-			if err != nil {
-				return fmt.Errorf("can not store gauge: %w", err)
-			}
-		case entity.CounterType:
-			err = s.repo.StoreCounter(ctx, m.Name, *m.Counter)
-			if err != nil {
-				return fmt.Errorf("can not store counter: %w", err)
+	for i := 0; i <= s.keep; i++ {
+		path := snapshot.HistoryPath(s.path, i)
+
+		snap, err := snapshot.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			if len(skipped) > 0 {
+				logger.Logger.Warnf("restoring from previous snapshot %s, skipped %d broken: %v", path, len(skipped), errors.Join(skipped...))
 			}
+			logger.Logger.Infoln("restored data from:", path)
+			return snap.WALSeq, snapshot.Load(ctx, s.repo, snap)
 		}
+
+		logger.Logger.Warnf("skipped snapshot %s: %v", path, err)
+		skipped = append(skipped, fmt.Errorf("%s: %w", path, err))
 	}
 
-	return nil
+	if len(skipped) > 0 {
+		return 0, fmt.Errorf("no valid snapshot: %w", errors.Join(skipped...))
+	}
+
+	return 0, os.ErrNotExist
 }
@@ -0,0 +1,56 @@
+package failover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/memory"
+	"github.com/arxon31/metrics-collector/internal/snapshot"
+)
+
+func TestRestoreFallback(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	for _, delta := range []int64{1, 2, 3} {
+		snap := snapshot.Snapshot{
+			Namespace: snapshot.Namespace{Metrics: []entity.MetricDTO{{Name: "requests", MetricType: entity.CounterType, Counter: &delta}}},
+			WALSeq:    uint64(delta),
+		}
+		data, err := snapshot.Encode(snap, snapshot.CompressionZstd)
+		require.NoError(t, err)
+		require.NoError(t, snapshot.WriteFile(path, data, 2))
+	}
+
+	// the newest snapshot is broken, the previous one is restored
+	require.NoError(t, os.WriteFile(path, []byte("{broken"), 0o644))
+
+	repo := memory.NewMapStorage()
+	s := NewService(repo, path, 0, true, WithHistory(2))
+	seq, err := s.restore(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), seq)
+
+	counter, err := repo.Counter(ctx, "requests")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), counter)
+
+	// no valid snapshot at all
+	require.NoError(t, os.WriteFile(snapshot.HistoryPath(path, 1), nil, 0o644))
+	require.NoError(t, os.Remove(snapshot.HistoryPath(path, 2)))
+	_, err = NewService(memory.NewMapStorage(), path, 0, true, WithHistory(2)).restore(ctx)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, os.ErrNotExist)
+
+	err = NewService(memory.NewMapStorage(), path, 0, true, WithHistory(2)).Restore(ctx)
+	require.Error(t, err, "broken snapshots fail restore")
+
+	// no snapshot at all
+	err = NewService(memory.NewMapStorage(), filepath.Join(t.TempDir(), "metrics.json"), 0, true, WithHistory(2)).Restore(ctx)
+	require.NoError(t, err)
+}
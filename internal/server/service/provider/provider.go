@@ -2,6 +2,8 @@ package provider
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/arxon31/metrics-collector/pkg/logger"
 
@@ -12,16 +14,23 @@ type provider interface {
 	Gauge(ctx context.Context, name string) (float64, error)
 	Counter(ctx context.Context, name string) (int64, error)
 	Metrics(ctx context.Context) ([]entity.MetricDTO, error)
+	Metadata(ctx context.Context) ([]entity.Metadata, error)
+}
+
+type expirationPolicy interface {
+	Expired(metric entity.MetricDTO, now time.Time) bool
 }
 
 type providerService struct {
-	provider provider
+	provider   provider
+	expiration expirationPolicy
 }
 
 // NewProviderService initializes a new provider service.
-func NewProviderService(provider provider) *providerService {
+func NewProviderService(provider provider, expiration expirationPolicy) *providerService {
 	return &providerService{
-		provider: provider,
+		provider:   provider,
+		expiration: expiration,
 	}
 }
 
@@ -44,7 +53,7 @@ func (s *providerService) GetGaugeValue(ctx context.Context, name string) (float
 	return val, nil
 }
 
-// GetMetrics returns all metrics
+// GetMetrics returns all not expired metrics
 func (s *providerService) GetMetrics(ctx context.Context) ([]entity.MetricDTO, error) {
 	vals, err := s.provider.Metrics(ctx)
 	if err != nil {
@@ -52,7 +61,11 @@ func (s *providerService) GetMetrics(ctx context.Context) ([]entity.MetricDTO, e
 	}
 	validMetrics := make([]entity.MetricDTO, 0, len(vals))
 
+	now := time.Now()
 	for _, metric := range vals {
+		if s.expiration.Expired(metric, now) {
+			continue
+		}
 		err = metric.Validate()
 		if err != nil {
 			logger.Logger.Error(err)
@@ -62,3 +75,17 @@ func (s *providerService) GetMetrics(ctx context.Context) ([]entity.MetricDTO, e
 
 	return validMetrics, nil
 }
+
+// GetMetadata returns metadata of all metrics sorted by name
+func (s *providerService) GetMetadata(ctx context.Context) ([]entity.Metadata, error) {
+	metadata, err := s.provider.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(metadata, func(i, j int) bool {
+		return metadata[i].Name < metadata[j].Name
+	})
+
+	return metadata, nil
+}
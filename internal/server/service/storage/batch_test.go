@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/memory"
+)
+
+func TestStorageService_SaveBatchMetrics(t *testing.T) {
+	ctx := context.Background()
+	gaugeVal := 1.5
+	counterVal := int64(2)
+
+	batch := []entity.MetricDTO{
+		{Name: "first", MetricType: entity.CounterType, Counter: &counterVal},
+		{Name: "second", MetricType: entity.CounterType, Counter: &counterVal},
+		{Name: "broken", MetricType: entity.GaugeType},
+		{Name: "first", MetricType: entity.GaugeType, Gauge: &gaugeVal},
+	}
+
+	t.Run("best_effort_stores_valid_metrics", func(t *testing.T) {
+		repo := memory.NewMapStorage()
+		s := NewStorageService(repo, nil)
+
+		results, err := s.SaveBatchMetrics(ctx, batch, true)
+		require.NoError(t, err)
+		require.Len(t, results, len(batch))
+		require.Equal(t, entity.BatchAccepted, results[0].Status)
+		require.Equal(t, entity.BatchAccepted, results[1].Status)
+		require.ErrorIs(t, results[2].Err, entity.ErrGaugeValue)
+		require.ErrorIs(t, results[3].Err, entity.ErrTypeConflict)
+
+		// every new counter of the batch is stored, not only the first one
+		for _, name := range []string{"first", "second"} {
+			value, err := repo.Counter(ctx, name)
+			require.NoError(t, err)
+			require.Equal(t, counterVal, value)
+		}
+	})
+
+	t.Run("atomic_stores_nothing_on_rejection", func(t *testing.T) {
+		repo := memory.NewMapStorage()
+		s := NewStorageService(repo, nil)
+
+		results, err := s.SaveBatchMetrics(ctx, batch, false)
+		require.ErrorIs(t, err, entity.ErrGaugeValue)
+
+		var batchErr *entity.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, entity.BatchSkipped, results[0].Status)
+		require.Equal(t, entity.BatchRejected, results[2].Status)
+
+		metrics, err := repo.Metrics(ctx)
+		require.NoError(t, err)
+		require.Empty(t, metrics)
+	})
+
+	t.Run("best_effort_rejects_metrics_over_quota", func(t *testing.T) {
+		quota, err := NewQuota(1, "")
+		require.NoError(t, err)
+		s := NewStorageService(memory.NewMapStorage(), quota)
+		acme := auth.WithTenant(ctx, "acme")
+
+		results, err := s.SaveBatchMetrics(acme, batch[:2], true)
+		require.NoError(t, err)
+		require.Equal(t, entity.BatchAccepted, results[0].Status)
+		require.ErrorIs(t, results[1].Err, entity.ErrQuotaExceeded)
+	})
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+var errQuotaFormat = errors.New("quota override must be in tenant=limit format")
+
+// Quota limits number of metrics stored by a single tenant
+type Quota struct {
+	defaultLimit int
+	limits       map[string]int
+}
+
+// NewQuota creates quota from default limit and comma separated tenant=limit overrides,
+// e.g. "acme=1000,trial=50". Zero limit means unlimited.
+func NewQuota(defaultLimit int, overrides string) (*Quota, error) {
+	q := &Quota{defaultLimit: defaultLimit, limits: make(map[string]int)}
+
+	for _, item := range strings.Split(overrides, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		tenant, value, ok := strings.Cut(item, "=")
+		if !ok || tenant == "" {
+			return nil, fmt.Errorf("%s: %w", item, errQuotaFormat)
+		}
+
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("%s: %w", item, errQuotaFormat)
+		}
+
+		q.limits[tenant] = limit
+	}
+
+	return q, nil
+}
+
+// Limit returns maximum number of metrics for tenant, zero means unlimited
+func (q *Quota) Limit(tenant string) int {
+	if q == nil {
+		return 0
+	}
+	if limit, ok := q.limits[tenant]; ok {
+		return limit
+	}
+	return q.defaultLimit
+}
+
+type counter interface {
+	// Metrics returns all metrics values
+	Metrics(ctx context.Context) ([]entity.MetricDTO, error)
+}
+
+// usage counts metrics of every tenant, new names reserve slots under a single lock so concurrent writers
+// of different names can not exceed quota together. Deletes and evictions bypass the service and leave counts
+// higher than actual, so a count reaching the limit is always confirmed by repository.
+type usage struct {
+	repo    counter
+	mu      sync.Mutex
+	stored  map[string]int
+	pending map[string]int
+}
+
+func newUsage(repo counter) *usage {
+	return &usage{
+		repo:    repo,
+		stored:  make(map[string]int),
+		pending: make(map[string]int),
+	}
+}
+
+// reserve takes up to n slots of tenant quota, it returns number of taken slots and slots used before.
+// done must be called once taken slots are stored or given up.
+func (u *usage) reserve(ctx context.Context, n, limit int) (taken, used int, done func(stored bool), err error) {
+	tenant := auth.Tenant(ctx)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	stored, ok := u.stored[tenant]
+	if !ok || stored+u.pending[tenant]+n > limit {
+		metrics, err := u.repo.Metrics(ctx)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		stored = len(metrics)
+		u.stored[tenant] = stored
+	}
+
+	// names being stored may be counted by repository already, counting them twice only errs on the safe side
+	used = stored + u.pending[tenant]
+	taken = min(n, max(limit-used, 0))
+	u.pending[tenant] += taken
+
+	return taken, used, func(saved bool) {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		u.pending[tenant] -= taken
+		if saved {
+			u.stored[tenant] += taken
+		}
+	}, nil
+}
+
+// reset forgets counts, repository was changed bypassing the service
+func (u *usage) reset() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.stored = make(map[string]int)
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/memory"
+)
+
+func TestNewQuota(t *testing.T) {
+	q, err := NewQuota(10, "acme=2, trial=0")
+	require.NoError(t, err)
+	require.Equal(t, 2, q.Limit("acme"))
+	require.Equal(t, 0, q.Limit("trial"))
+	require.Equal(t, 10, q.Limit("other"))
+
+	_, err = NewQuota(0, "acme")
+	require.ErrorIs(t, err, errQuotaFormat)
+
+	_, err = NewQuota(0, "acme=-1")
+	require.ErrorIs(t, err, errQuotaFormat)
+}
+
+func TestStorageService_Tenants(t *testing.T) {
+	gaugeVal := 1.5
+	acme := auth.WithTenant(context.Background(), "acme")
+	beta := auth.WithTenant(context.Background(), "beta")
+
+	t.Run("tenants_are_isolated", func(t *testing.T) {
+		repo := memory.NewMapStorage()
+		s := NewStorageService(repo, nil)
+
+		err := s.SaveGaugeMetric(acme, entity.MetricDTO{Name: entity.Alloc, MetricType: entity.GaugeType, Gauge: &gaugeVal})
+		require.NoError(t, err)
+
+		_, err = repo.Gauge(beta, entity.Alloc)
+		require.Error(t, err)
+
+		counterVal := int64(1)
+		err = s.SaveCounterMetric(beta, entity.MetricDTO{Name: entity.Alloc, MetricType: entity.CounterType, Counter: &counterVal})
+		require.NoError(t, err, "same name with another type in another tenant is not a conflict")
+
+		metrics, err := repo.Metrics(acme)
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		require.Equal(t, entity.GaugeType, metrics[0].MetricType)
+	})
+
+	t.Run("quota_exceeded", func(t *testing.T) {
+		quota, err := NewQuota(2, "")
+		require.NoError(t, err)
+		s := NewStorageService(memory.NewMapStorage(), quota)
+
+		for _, name := range []string{"first", "second"} {
+			err = s.SaveGaugeMetric(acme, entity.MetricDTO{Name: name, MetricType: entity.GaugeType, Gauge: &gaugeVal})
+			require.NoError(t, err)
+		}
+
+		err = s.SaveGaugeMetric(acme, entity.MetricDTO{Name: "first", MetricType: entity.GaugeType, Gauge: &gaugeVal})
+		require.NoError(t, err, "updating existing metric is allowed")
+
+		err = s.SaveGaugeMetric(acme, entity.MetricDTO{Name: "third", MetricType: entity.GaugeType, Gauge: &gaugeVal})
+		require.ErrorIs(t, err, entity.ErrQuotaExceeded)
+
+		_, err = s.SaveBatchMetrics(beta, []entity.MetricDTO{
+			{Name: "first", MetricType: entity.GaugeType, Gauge: &gaugeVal},
+			{Name: "second", MetricType: entity.GaugeType, Gauge: &gaugeVal},
+			{Name: "third", MetricType: entity.GaugeType, Gauge: &gaugeVal},
+		}, false)
+		require.ErrorIs(t, err, entity.ErrQuotaExceeded)
+	})
+}
+
+// scanCounter counts full scans of repository
+type scanCounter struct {
+	*memory.MapStorage
+	scans atomic.Int32
+}
+
+func (r *scanCounter) Metrics(ctx context.Context) ([]entity.MetricDTO, error) {
+	r.scans.Add(1)
+	return r.MapStorage.Metrics(ctx)
+}
+
+func TestStorageService_QuotaUsage(t *testing.T) {
+	gaugeVal := 1.5
+	acme := auth.WithTenant(context.Background(), "acme")
+
+	t.Run("concurrent_new_names", func(t *testing.T) {
+		const limit = 10
+
+		quota, err := NewQuota(limit, "")
+		require.NoError(t, err)
+		repo := memory.NewMapStorage()
+		s := NewStorageService(repo, quota)
+
+		var wg sync.WaitGroup
+		var exceeded atomic.Int32
+		for i := 0; i < 5*limit; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				err := s.SaveGaugeMetric(acme, entity.MetricDTO{Name: fmt.Sprintf("m%d", i), MetricType: entity.GaugeType, Gauge: &gaugeVal})
+				if err != nil {
+					require.ErrorIs(t, err, entity.ErrQuotaExceeded)
+					exceeded.Add(1)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		metrics, err := repo.Metrics(acme)
+		require.NoError(t, err)
+		require.Len(t, metrics, limit)
+		require.Equal(t, int32(4*limit), exceeded.Load())
+	})
+
+	t.Run("new_names_do_not_scan", func(t *testing.T) {
+		quota, err := NewQuota(10, "")
+		require.NoError(t, err)
+		repo := &scanCounter{MapStorage: memory.NewMapStorage()}
+		s := NewStorageService(repo, quota)
+
+		for i := 0; i < 5; i++ {
+			err = s.SaveGaugeMetric(acme, entity.MetricDTO{Name: fmt.Sprintf("m%d", i), MetricType: entity.GaugeType, Gauge: &gaugeVal})
+			require.NoError(t, err)
+		}
+		require.Equal(t, int32(1), repo.scans.Load(), "count is loaded once")
+	})
+
+	t.Run("deleted_names_free_quota", func(t *testing.T) {
+		quota, err := NewQuota(2, "")
+		require.NoError(t, err)
+		repo := memory.NewMapStorage()
+		s := NewStorageService(repo, quota)
+
+		for _, name := range []string{"first", "second"} {
+			err = s.SaveGaugeMetric(acme, entity.MetricDTO{Name: name, MetricType: entity.GaugeType, Gauge: &gaugeVal})
+			require.NoError(t, err)
+		}
+		require.NoError(t, repo.Delete(acme, entity.GaugeType, "first"))
+
+		err = s.SaveGaugeMetric(acme, entity.MetricDTO{Name: "third", MetricType: entity.GaugeType, Gauge: &gaugeVal})
+		require.NoError(t, err, "full quota is confirmed by repository")
+
+		err = s.SaveGaugeMetric(acme, entity.MetricDTO{Name: "fourth", MetricType: entity.GaugeType, Gauge: &gaugeVal})
+		require.ErrorIs(t, err, entity.ErrQuotaExceeded)
+	})
+
+	t.Run("exclusive_pauses_writes", func(t *testing.T) {
+		quota, err := NewQuota(1, "")
+		require.NoError(t, err)
+		repo := memory.NewMapStorage()
+		s := NewStorageService(repo, quota)
+
+		require.NoError(t, s.SaveGaugeMetric(acme, entity.MetricDTO{Name: "old", MetricType: entity.GaugeType, Gauge: &gaugeVal}))
+		require.ErrorIs(t, s.CheckQuota(acme, 2), entity.ErrQuotaExceeded)
+
+		saved := make(chan error)
+		err = s.Exclusive(func() error {
+			go func() {
+				saved <- s.SaveGaugeMetric(acme, entity.MetricDTO{Name: "new", MetricType: entity.GaugeType, Gauge: &gaugeVal})
+			}()
+
+			select {
+			case err := <-saved:
+				return fmt.Errorf("write was not paused: %v", err)
+			case <-time.After(50 * time.Millisecond):
+			}
+			return repo.Delete(acme, entity.GaugeType, "old")
+		})
+		require.NoError(t, err)
+		require.NoError(t, <-saved, "usage counted before exclusive change is forgotten")
+	})
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/repoerr"
+)
+
+type lookup interface {
+	// Gauge returns gauge metric value
+	Gauge(ctx context.Context, name string) (float64, error)
+	// Counter returns counter metric value
+	Counter(ctx context.Context, name string) (int64, error)
+}
+
+// lockStripes is a number of mutexes names are hashed to, it bounds memory regardless of number of names
+const lockStripes = 256
+
+// typeRegistry remembers type of every written metric name per tenant.
+// Cached types are only a fast path, conflicts are always confirmed by repository
+// so deleted or evicted metrics can be recreated with another type.
+type typeRegistry struct {
+	repo  lookup
+	mu    sync.RWMutex
+	types map[string]string
+	locks [lockStripes]sync.Mutex
+}
+
+func newTypeRegistry(repo lookup) *typeRegistry {
+	return &typeRegistry{
+		repo:  repo,
+		types: make(map[string]string),
+	}
+}
+
+// check returns *entity.TypeConflictError if name is stored with another type
+func (r *typeRegistry) check(ctx context.Context, name, metricType string) error {
+	if metricType != entity.GaugeType && metricType != entity.CounterType {
+		return nil
+	}
+
+	key := registryKey(ctx, name)
+
+	r.mu.RLock()
+	known, ok := r.types[key]
+	r.mu.RUnlock()
+
+	if ok && known == metricType {
+		return nil
+	}
+
+	other := entity.GaugeType
+	if metricType == entity.GaugeType {
+		other = entity.CounterType
+	}
+
+	exists, err := r.exists(ctx, other, name)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		r.remember(key, other)
+		return &entity.TypeConflictError{Name: name, Existing: other, Requested: metricType}
+	}
+
+	r.remember(key, metricType)
+	return nil
+}
+
+// lock must be held across check and store of names so concurrent writers can not store one name with both types.
+// Stripes are locked in ascending order so batches sharing names do not deadlock.
+func (r *typeRegistry) lock(ctx context.Context, names ...string) (unlock func()) {
+	stripes := make([]int, 0, len(names))
+	seen := make(map[int]bool, len(names))
+	for _, name := range names {
+		i := stripe(registryKey(ctx, name))
+		if !seen[i] {
+			seen[i] = true
+			stripes = append(stripes, i)
+		}
+	}
+	sort.Ints(stripes)
+
+	for _, i := range stripes {
+		r.locks[i].Lock()
+	}
+
+	return func() {
+		for _, i := range stripes {
+			r.locks[i].Unlock()
+		}
+	}
+}
+
+func stripe(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % lockStripes)
+}
+
+func (r *typeRegistry) remember(key, metricType string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types[key] = metricType
+}
+
+func (r *typeRegistry) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types = make(map[string]string)
+}
+
+func registryKey(ctx context.Context, name string) string {
+	return auth.Tenant(ctx) + "\x00" + name
+}
+
+func (r *typeRegistry) exists(ctx context.Context, metricType, name string) (bool, error) {
+	var err error
+	switch metricType {
+	case entity.GaugeType:
+		_, err = r.repo.Gauge(ctx, name)
+	case entity.CounterType:
+		_, err = r.repo.Counter(ctx, name)
+	}
+
+	if errors.Is(err, repoerr.ErrMetricNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/memory"
+)
+
+func TestStorageService_TypeConflict(t *testing.T) {
+	ctx := context.Background()
+	gaugeVal := 1.5
+	counterVal := int64(2)
+
+	t.Run("reject_counter_for_existing_gauge", func(t *testing.T) {
+		s := NewStorageService(memory.NewMapStorage(), nil)
+
+		err := s.SaveGaugeMetric(ctx, entity.MetricDTO{Name: entity.Alloc, MetricType: entity.GaugeType, Gauge: &gaugeVal})
+		require.NoError(t, err)
+
+		err = s.SaveCounterMetric(ctx, entity.MetricDTO{Name: entity.Alloc, MetricType: entity.CounterType, Counter: &counterVal})
+		require.ErrorIs(t, err, entity.ErrTypeConflict)
+
+		var conflict *entity.TypeConflictError
+		require.ErrorAs(t, err, &conflict)
+		require.Equal(t, entity.GaugeType, conflict.Existing)
+		require.Equal(t, entity.CounterType, conflict.Requested)
+	})
+
+	t.Run("allow_new_type_after_delete", func(t *testing.T) {
+		repo := memory.NewMapStorage()
+		s := NewStorageService(repo, nil)
+
+		err := s.SaveGaugeMetric(ctx, entity.MetricDTO{Name: entity.Alloc, MetricType: entity.GaugeType, Gauge: &gaugeVal})
+		require.NoError(t, err)
+
+		err = repo.Delete(ctx, entity.GaugeType, entity.Alloc)
+		require.NoError(t, err)
+
+		err = s.SaveCounterMetric(ctx, entity.MetricDTO{Name: entity.Alloc, MetricType: entity.CounterType, Counter: &counterVal})
+		require.NoError(t, err)
+	})
+
+	t.Run("reject_conflict_inside_batch", func(t *testing.T) {
+		s := NewStorageService(memory.NewMapStorage(), nil)
+
+		_, err := s.SaveBatchMetrics(ctx, []entity.MetricDTO{
+			{Name: entity.Alloc, MetricType: entity.GaugeType, Gauge: &gaugeVal},
+			{Name: entity.Alloc, MetricType: entity.CounterType, Counter: &counterVal},
+		}, false)
+		require.ErrorIs(t, err, entity.ErrTypeConflict)
+	})
+
+	t.Run("reject_batch_conflicting_with_stored", func(t *testing.T) {
+		s := NewStorageService(memory.NewMapStorage(), nil)
+
+		err := s.SaveCounterMetric(ctx, entity.MetricDTO{Name: entity.PollCount, MetricType: entity.CounterType, Counter: &counterVal})
+		require.NoError(t, err)
+
+		_, err = s.SaveBatchMetrics(ctx, []entity.MetricDTO{
+			{Name: entity.PollCount, MetricType: entity.GaugeType, Gauge: &gaugeVal},
+		}, false)
+		require.ErrorIs(t, err, entity.ErrTypeConflict)
+	})
+
+	t.Run("concurrent_writers_of_both_types", func(t *testing.T) {
+		s := NewStorageService(slowLookup{memory.NewMapStorage()}, nil)
+
+		for i := 0; i < 100; i++ {
+			name := "metric" + strconv.Itoa(i)
+			errs := make([]error, 2)
+
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				errs[0] = s.SaveGaugeMetric(ctx, entity.MetricDTO{Name: name, MetricType: entity.GaugeType, Gauge: &gaugeVal})
+			}()
+			go func() {
+				defer wg.Done()
+				_, errs[1] = s.SaveBatchMetrics(ctx, []entity.MetricDTO{{Name: name, MetricType: entity.CounterType, Counter: &counterVal}}, false)
+			}()
+			wg.Wait()
+
+			require.True(t, (errs[0] == nil) != (errs[1] == nil), "exactly one type of %s is stored: %v", name, errs)
+		}
+	})
+}
+
+// slowLookup widens the window between type check and store
+type slowLookup struct {
+	*memory.MapStorage
+}
+
+func (s slowLookup) Gauge(ctx context.Context, name string) (float64, error) {
+	value, err := s.MapStorage.Gauge(ctx, name)
+	time.Sleep(time.Millisecond)
+	return value, err
+}
+
+func (s slowLookup) Counter(ctx context.Context, name string) (int64, error) {
+	value, err := s.MapStorage.Counter(ctx, name)
+	time.Sleep(time.Millisecond)
+	return value, err
+}
@@ -2,30 +2,74 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/arxon31/metrics-collector/pkg/logger"
 
+	"github.com/arxon31/metrics-collector/internal/auth"
 	"github.com/arxon31/metrics-collector/internal/entity"
 )
 
 type storage interface {
+	lookup
+	// Metrics returns all metrics values
+	Metrics(ctx context.Context) ([]entity.MetricDTO, error)
 	// StoreGauge replaces gauge metric value
 	StoreGauge(ctx context.Context, name string, value float64) error
 	// StoreCounter increases counter metric value
 	StoreCounter(ctx context.Context, name string, value int64) error
 	// StoreBatch stores batch of metrics
 	StoreBatch(ctx context.Context, metrics []entity.MetricDTO) error
+	// StoreMetadata replaces metric metadata
+	StoreMetadata(ctx context.Context, metadata entity.Metadata) error
 }
 
 type storageService struct {
-	repo storage
+	repo     storage
+	registry *typeRegistry
+	quota    *Quota
+	usage    *usage
+
+	// exclusive is held for reading by writes and for writing by changes bypassing the service
+	exclusive sync.RWMutex
 }
 
-// NewStorageService initializes a new storage service.
-func NewStorageService(repo storage) *storageService {
+// NewStorageService initializes a new storage service, nil quota means unlimited.
+func NewStorageService(repo storage, quota *Quota) *storageService {
 	return &storageService{
-		repo: repo,
+		repo:     repo,
+		registry: newTypeRegistry(repo),
+		quota:    quota,
+		usage:    newUsage(repo),
+	}
+}
+
+// Invalidate forgets cached metric types and counts, repository was changed bypassing the service
+func (s *storageService) Invalidate() {
+	s.registry.reset()
+	s.usage.reset()
+}
+
+// Exclusive runs op changing repository bypassing the service with all writes paused,
+// cached types and counts are forgotten once it is done
+func (s *storageService) Exclusive(op func() error) error {
+	s.exclusive.Lock()
+	defer s.exclusive.Unlock()
+	defer s.Invalidate()
+
+	return op()
+}
+
+// CheckQuota returns entity.ErrQuotaExceeded if tenant of ctx can not hold count metrics
+func (s *storageService) CheckQuota(ctx context.Context, count int) error {
+	tenant := auth.Tenant(ctx)
+	limit := s.quota.Limit(tenant)
+	if limit > 0 && count > limit {
+		return fmt.Errorf("%q would have %d of %d metrics:%w", tenant, count, limit, entity.ErrQuotaExceeded)
 	}
+	return nil
 }
 
 // SaveGaugeMetric saves the metric in repo
@@ -36,7 +80,26 @@ func (s *storageService) SaveGaugeMetric(ctx context.Context, metric entity.Metr
 		return err
 	}
 
+	s.exclusive.RLock()
+	defer s.exclusive.RUnlock()
+
+	unlock := s.registry.lock(ctx, metric.Name)
+	defer unlock()
+
+	err = s.registry.check(ctx, metric.Name, entity.GaugeType)
+	if err != nil {
+		logger.Logger.Error(err)
+		return err
+	}
+
+	done, err := s.reserveQuota(ctx, metric.Name, entity.GaugeType)
+	if err != nil {
+		logger.Logger.Error(err)
+		return err
+	}
+
 	err = s.repo.StoreGauge(ctx, metric.Name, *metric.Gauge)
+	done(err == nil)
 	if err != nil {
 		logger.Logger.Error(err)
 		return err
@@ -53,7 +116,26 @@ func (s *storageService) SaveCounterMetric(ctx context.Context, metric entity.Me
 		return err
 	}
 
+	s.exclusive.RLock()
+	defer s.exclusive.RUnlock()
+
+	unlock := s.registry.lock(ctx, metric.Name)
+	defer unlock()
+
+	err = s.registry.check(ctx, metric.Name, entity.CounterType)
+	if err != nil {
+		logger.Logger.Error(err)
+		return err
+	}
+
+	done, err := s.reserveQuota(ctx, metric.Name, entity.CounterType)
+	if err != nil {
+		logger.Logger.Error(err)
+		return err
+	}
+
 	err = s.repo.StoreCounter(ctx, metric.Name, *metric.Counter)
+	done(err == nil)
 	if err != nil {
 		logger.Logger.Error(err)
 		return err
@@ -62,22 +144,199 @@ func (s *storageService) SaveCounterMetric(ctx context.Context, metric entity.Me
 	return nil
 }
 
-// SaveBatchMetrics saves metrics in repo
-func (s *storageService) SaveBatchMetrics(ctx context.Context, metrics []entity.MetricDTO) error {
-	validMetrics := make([]entity.MetricDTO, 0, len(metrics))
+// SaveBatchMetrics validates every metric and stores accepted ones in a single repo call, it returns result per metric.
+// Without bestEffort any rejected metric rejects the whole batch with *entity.BatchError and nothing is stored.
+func (s *storageService) SaveBatchMetrics(ctx context.Context, metrics []entity.MetricDTO, bestEffort bool) ([]entity.BatchResult, error) {
+	names := make([]string, len(metrics))
+	for i, metric := range metrics {
+		names[i] = metric.Name
+	}
+
+	s.exclusive.RLock()
+	defer s.exclusive.RUnlock()
+
+	unlock := s.registry.lock(ctx, names...)
+	defer unlock()
+
+	results := make([]entity.BatchResult, len(metrics))
+	batchTypes := make(map[string]string, len(metrics))
+	rejected := 0
+
+	reject := func(i int, err error) {
+		logger.Logger.Error(err)
+		results[i].Status, results[i].Err = entity.BatchRejected, err
+		rejected++
+	}
+
+	for i, metric := range metrics {
+		results[i] = entity.BatchResult{Name: metric.Name, MetricType: metric.MetricType, Status: entity.BatchAccepted}
 
-	for _, metric := range metrics {
 		err := metric.Validate()
+		if err != nil {
+			reject(i, err)
+			continue
+		}
+
+		if existing, ok := batchTypes[metric.Name]; ok && existing != metric.MetricType {
+			reject(i, &entity.TypeConflictError{Name: metric.Name, Existing: existing, Requested: metric.MetricType})
+			continue
+		}
+
+		err = s.registry.check(ctx, metric.Name, metric.MetricType)
+		if errors.As(err, new(*entity.TypeConflictError)) {
+			reject(i, err)
+			continue
+		}
 		if err != nil {
 			logger.Logger.Error(err)
+			return nil, err
+		}
+
+		batchTypes[metric.Name] = metric.MetricType
+	}
+
+	admitted, done, err := s.admitBatch(ctx, metrics, results)
+	if err != nil {
+		logger.Logger.Error(err)
+		return nil, err
+	}
+	rejected += admitted
+
+	accepted := make([]entity.MetricDTO, 0, len(metrics)-rejected)
+	for i, metric := range metrics {
+		if results[i].Status == entity.BatchAccepted {
+			accepted = append(accepted, metric)
+		}
+	}
+
+	if rejected > 0 && !bestEffort {
+		done(false)
+		for i := range results {
+			if results[i].Status == entity.BatchAccepted {
+				results[i].Status = entity.BatchSkipped
+			}
+		}
+		return results, &entity.BatchError{Results: results}
+	}
+
+	if len(accepted) == 0 {
+		done(false)
+		return results, nil
+	}
+
+	err = s.repo.StoreBatch(ctx, accepted)
+	done(err == nil)
+	if err != nil {
+		logger.Logger.Error(err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// admitBatch reserves quota of accepted metrics creating new names and rejects ones over tenant quota,
+// it returns number of rejected metrics. done must be called once admitted metrics are stored or given up.
+func (s *storageService) admitBatch(ctx context.Context, metrics []entity.MetricDTO, results []entity.BatchResult) (int, func(stored bool), error) {
+	tenant := auth.Tenant(ctx)
+	limit := s.quota.Limit(tenant)
+	if limit <= 0 {
+		return 0, func(bool) {}, nil
+	}
+
+	// new names in order of batch, the ones over quota are rejected
+	fresh := make(map[string]bool)
+	names := make([]string, 0)
+	for i, metric := range metrics {
+		if fresh[metric.Name] || results[i].Status != entity.BatchAccepted {
+			continue
+		}
+
+		exists, err := s.registry.exists(ctx, metric.MetricType, metric.Name)
+		if err != nil {
+			return 0, nil, err
+		}
+		if exists {
+			continue
+		}
+		fresh[metric.Name] = true
+		names = append(names, metric.Name)
+	}
+	if len(names) == 0 {
+		return 0, func(bool) {}, nil
+	}
+
+	taken, used, done, err := s.usage.reserve(ctx, len(names), limit)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if taken == len(names) {
+		return 0, done, nil
+	}
+
+	over := make(map[string]bool, len(names)-taken)
+	for _, name := range names[taken:] {
+		over[name] = true
+	}
+
+	err = fmt.Errorf("%q has %d of %d metrics:%w", tenant, used+taken, limit, entity.ErrQuotaExceeded)
+	logger.Logger.Error(err)
+	rejected := 0
+	for i, metric := range metrics {
+		if over[metric.Name] && results[i].Status == entity.BatchAccepted {
+			results[i].Status, results[i].Err = entity.BatchRejected, err
+			rejected++
 		}
-		validMetrics = append(validMetrics, metric)
 	}
 
-	err := s.repo.StoreBatch(ctx, validMetrics)
+	return rejected, done, nil
+}
+
+// SaveMetadata saves metric metadata in repo
+func (s *storageService) SaveMetadata(ctx context.Context, metadata entity.Metadata) error {
+	err := metadata.Validate()
+	if err != nil {
+		logger.Logger.Error(err)
+		return err
+	}
+
+	s.exclusive.RLock()
+	defer s.exclusive.RUnlock()
+
+	err = s.repo.StoreMetadata(ctx, metadata)
 	if err != nil {
 		logger.Logger.Error(err)
 		return err
 	}
+
 	return nil
 }
+
+// reserveQuota reserves quota slot if metric creates a new name, it returns entity.ErrQuotaExceeded if tenant quota is full.
+// done must be called once metric is stored or given up.
+func (s *storageService) reserveQuota(ctx context.Context, name, metricType string) (func(stored bool), error) {
+	tenant := auth.Tenant(ctx)
+	limit := s.quota.Limit(tenant)
+	if limit <= 0 {
+		return func(bool) {}, nil
+	}
+
+	exists, err := s.registry.exists(ctx, metricType, name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return func(bool) {}, nil
+	}
+
+	taken, used, done, err := s.usage.reserve(ctx, 1, limit)
+	if err != nil {
+		return nil, err
+	}
+	if taken == 0 {
+		done(false)
+		return nil, fmt.Errorf("%q has %d of %d metrics:%w", tenant, used, limit, entity.ErrQuotaExceeded)
+	}
+
+	return done, nil
+}
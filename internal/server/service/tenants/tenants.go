@@ -0,0 +1,224 @@
+// Package tenants issues, revokes and resolves api tokens identifying tenants
+package tenants
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/arxon31/metrics-collector/pkg/logger"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+const (
+	tokenBytes = 32
+	idBytes    = 8
+
+	// cacheTTL bounds how long a token revoked by another server instance sharing the store is still accepted
+	cacheTTL = 30 * time.Second
+	// minReload limits how often unknown tokens can trigger reload of all tokens from the store
+	minReload = time.Second
+)
+
+type tokenStore interface {
+	// StoreToken saves api token
+	StoreToken(ctx context.Context, token entity.APIToken) error
+	// Tokens returns all api tokens
+	Tokens(ctx context.Context) ([]entity.APIToken, error)
+	// RevokeToken marks api token as revoked
+	RevokeToken(ctx context.Context, id string) error
+}
+
+type auditor interface {
+	Record(ctx context.Context, action, target string)
+}
+
+type tenantService struct {
+	store   tokenStore
+	auditor auditor
+
+	mu       sync.RWMutex
+	byHash   map[string]entity.APIToken
+	loadedAt time.Time
+	reloads  singleflight.Group
+	now      func() time.Time
+}
+
+// NewTenantService initializes a new tenant service.
+func NewTenantService(store tokenStore, auditor auditor) *tenantService {
+	return &tenantService{
+		store:   store,
+		auditor: auditor,
+		byHash:  make(map[string]entity.APIToken),
+		now:     time.Now,
+	}
+}
+
+// CreateToken issues a new token for tenant, the secret is returned only once
+func (s *tenantService) CreateToken(ctx context.Context, tenant string) (entity.APIToken, string, error) {
+	if err := entity.ValidateTenant(tenant); err != nil {
+		return entity.APIToken{}, "", err
+	}
+
+	id, err := randomHex(idBytes)
+	if err != nil {
+		return entity.APIToken{}, "", err
+	}
+	secret, err := randomHex(tokenBytes)
+	if err != nil {
+		return entity.APIToken{}, "", err
+	}
+
+	token := entity.APIToken{
+		ID:        id,
+		Tenant:    tenant,
+		Hash:      hash(secret),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err = s.store.StoreToken(ctx, token)
+	if err != nil {
+		logger.Logger.Error(err)
+		return entity.APIToken{}, "", err
+	}
+
+	s.mu.Lock()
+	s.byHash[token.Hash] = token
+	s.mu.Unlock()
+
+	s.auditor.Record(ctx, entity.AuditCreateToken, tenant+"/"+id)
+
+	token.Hash = ""
+	return token, secret, nil
+}
+
+// Tokens returns all issued tokens without hashes sorted by creation time
+func (s *tenantService) Tokens(ctx context.Context) ([]entity.APIToken, error) {
+	tokens, err := s.store.Tokens(ctx)
+	if err != nil {
+		logger.Logger.Error(err)
+		return nil, err
+	}
+
+	for i := range tokens {
+		tokens[i].Hash = ""
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+
+	return tokens, nil
+}
+
+// RevokeToken revokes token by id
+func (s *tenantService) RevokeToken(ctx context.Context, id string) error {
+	err := s.store.RevokeToken(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	for h, token := range s.byHash {
+		if token.ID == id {
+			delete(s.byHash, h)
+		}
+	}
+	s.mu.Unlock()
+
+	s.auditor.Record(ctx, entity.AuditRevokeToken, id)
+
+	return nil
+}
+
+// ResolveToken returns tenant owning active token.
+// Tokens are reloaded from the store once cache is older than cacheTTL, unknown tokens reload it at most once per minReload.
+func (s *tenantService) ResolveToken(ctx context.Context, secret string) (string, error) {
+	h := hash(secret)
+
+	token, ok, age := s.cached(h)
+	if ok && age < cacheTTL {
+		return token.Tenant, nil
+	}
+	if !ok && age < minReload {
+		return "", entity.ErrUnknownToken
+	}
+
+	// token could be issued or revoked by another server instance sharing the store
+	_, err, _ := s.reloads.Do("tokens", func() (interface{}, error) {
+		return nil, s.reload(ctx)
+	})
+	if err != nil {
+		if ok {
+			logger.Logger.Errorf("serving cached token, can not reload tokens: %v", err)
+			return token.Tenant, nil
+		}
+		return "", fmt.Errorf("can not load tokens: %w", err)
+	}
+
+	if token, ok, _ = s.cached(h); ok {
+		return token.Tenant, nil
+	}
+
+	return "", entity.ErrUnknownToken
+}
+
+// cached returns active token by hash and age of the cache
+func (s *tenantService) cached(h string) (entity.APIToken, bool, time.Duration) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.byHash[h]
+	return token, ok, s.now().Sub(s.loadedAt)
+}
+
+// reload replaces cache with active tokens from the store
+func (s *tenantService) reload(ctx context.Context) error {
+	tokens, err := s.store.Tokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	byHash := make(map[string]entity.APIToken, len(tokens))
+	for _, t := range tokens {
+		if t.Active() {
+			byHash[t.Hash] = t
+		}
+	}
+
+	s.mu.Lock()
+	s.byHash = byHash
+	s.loadedAt = s.now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Invalidate forgets cached tokens, store was changed bypassing the service
+func (s *tenantService) Invalidate() {
+	s.mu.Lock()
+	s.byHash = make(map[string]entity.APIToken)
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("can not generate random: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,74 @@
+package tenants
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+type countingStore struct {
+	tokens []entity.APIToken
+	loads  int
+}
+
+func (s *countingStore) StoreToken(ctx context.Context, token entity.APIToken) error {
+	s.tokens = append(s.tokens, token)
+	return nil
+}
+
+func (s *countingStore) Tokens(ctx context.Context) ([]entity.APIToken, error) {
+	s.loads++
+	return append([]entity.APIToken(nil), s.tokens...), nil
+}
+
+func (s *countingStore) RevokeToken(ctx context.Context, id string) error {
+	revokedAt := time.Now()
+	for i := range s.tokens {
+		if s.tokens[i].ID == id {
+			s.tokens[i].RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func TestTenantService_ResolveToken(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	store := &countingStore{tokens: []entity.APIToken{{ID: "1", Tenant: "acme", Hash: hash("secret")}}}
+	s := NewTenantService(store, nil)
+	s.now = func() time.Time { return now }
+
+	tenant, err := s.ResolveToken(ctx, "secret")
+	require.NoError(t, err)
+	require.Equal(t, "acme", tenant)
+	require.Equal(t, 1, store.loads)
+
+	t.Run("unknown_tokens_do_not_reload_more_than_once_per_interval", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			_, err = s.ResolveToken(ctx, "guess")
+			require.ErrorIs(t, err, entity.ErrUnknownToken)
+		}
+		require.Equal(t, 1, store.loads)
+
+		now = now.Add(minReload)
+		_, err = s.ResolveToken(ctx, "guess")
+		require.ErrorIs(t, err, entity.ErrUnknownToken)
+		require.Equal(t, 2, store.loads)
+	})
+
+	t.Run("token_revoked_by_other_instance_expires", func(t *testing.T) {
+		require.NoError(t, store.RevokeToken(ctx, "1"))
+
+		_, err = s.ResolveToken(ctx, "secret")
+		require.NoError(t, err, "cached token is served until ttl")
+
+		now = now.Add(cacheTTL)
+		_, err = s.ResolveToken(ctx, "secret")
+		require.ErrorIs(t, err, entity.ErrUnknownToken)
+	})
+}
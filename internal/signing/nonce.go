@@ -0,0 +1,59 @@
+package signing
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrReplay         = errors.New("nonce was already used")
+	ErrNonceCacheFull = errors.New("nonce cache is full")
+)
+
+// NonceCache remembers nonces until their timestamps leave the skew window
+type NonceCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	max     int
+	nonces  map[string]time.Time
+	sweptAt time.Time
+}
+
+// NewNonceCache creates cache keeping nonces for ttl, at most max nonces are kept
+func NewNonceCache(ttl time.Duration, max int) *NonceCache {
+	return &NonceCache{
+		ttl:    ttl,
+		max:    max,
+		nonces: make(map[string]time.Time),
+	}
+}
+
+// Add remembers nonce seen at now, returns ErrReplay if nonce is already known
+func (c *NonceCache) Add(nonce string, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if expiresAt, ok := c.nonces[nonce]; ok && now.Before(expiresAt) {
+		return ErrReplay
+	}
+
+	if len(c.nonces) >= c.max || now.Sub(c.sweptAt) > c.ttl {
+		c.sweep(now)
+	}
+	if len(c.nonces) >= c.max {
+		return ErrNonceCacheFull
+	}
+
+	c.nonces[nonce] = now.Add(c.ttl)
+	return nil
+}
+
+func (c *NonceCache) sweep(now time.Time) {
+	c.sweptAt = now
+	for nonce, expiresAt := range c.nonces {
+		if !now.Before(expiresAt) {
+			delete(c.nonces, nonce)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Package signing signs and verifies requests and responses with HMAC-SHA256 keys identified by key ID.
+// Signatures always cover the body exactly as it is sent over the wire, i.e. after Content-Encoding is applied.
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	HashHeader      = "HashSHA256"
+	KeyIDHeader     = "X-Signature-Key-ID"
+	TimestampHeader = "X-Signature-Timestamp"
+	NonceHeader     = "X-Signature-Nonce"
+)
+
+const nonceBytes = 16
+
+var errKeyFormat = errors.New("hash key must be a secret or comma separated id:secret list")
+
+// Key is a HMAC secret with its identifier
+type Key struct {
+	ID     string
+	Secret string
+}
+
+// Keyring keeps signing keys, the first key is used for signing and all keys are used for verification
+type Keyring struct {
+	keys []Key
+}
+
+// ParseKeyring parses "id:secret,id2:secret2" list, a bare secret is a single key with empty ID
+func ParseKeyring(s string) (*Keyring, error) {
+	k := &Keyring{}
+	if s == "" {
+		return k, nil
+	}
+
+	if !strings.Contains(s, ":") {
+		k.keys = append(k.keys, Key{Secret: s})
+		return k, nil
+	}
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		id, secret, ok := strings.Cut(item, ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("%q: %w", id, errKeyFormat)
+		}
+
+		k.keys = append(k.keys, Key{ID: id, Secret: secret})
+	}
+
+	return k, nil
+}
+
+// Empty reports whether keyring has no keys
+func (k *Keyring) Empty() bool {
+	return k == nil || len(k.keys) == 0
+}
+
+// Current returns signing key
+func (k *Keyring) Current() (Key, bool) {
+	if k.Empty() {
+		return Key{}, false
+	}
+	return k.keys[0], true
+}
+
+// Key returns verification key by ID
+func (k *Keyring) Key(id string) (Key, bool) {
+	if k.Empty() {
+		return Key{}, false
+	}
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// Keys returns all verification keys
+func (k *Keyring) Keys() []Key {
+	if k.Empty() {
+		return nil
+	}
+	return k.keys
+}
+
+// Sign returns base64 HMAC-SHA256 of request method, URI, timestamp, nonce and body,
+// uri is the request path with query as returned by url.URL.RequestURI
+func Sign(secret, method, uri, timestamp, nonce string, body []byte) string {
+	return sign(secret, body, method, uri, timestamp, nonce)
+}
+
+// SignResponse returns base64 HMAC-SHA256 of response status and body bound to the request method, URI and nonce,
+// timestamp is the time of response
+func SignResponse(secret string, status int, method, uri, timestamp, nonce string, body []byte) string {
+	return sign(secret, body, strconv.Itoa(status), method, uri, timestamp, nonce)
+}
+
+func sign(secret string, body []byte, fields ...string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	for _, field := range fields {
+		h.Write([]byte(field + "\n"))
+	}
+	h.Write(body)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// SignBody returns base64 HMAC-SHA256 of body only, used by legacy clients
+func SignBody(secret string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(body)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Equal compares signatures in constant time
+func Equal(a, b string) bool {
+	return hmac.Equal([]byte(a), []byte(b))
+}
+
+// NewNonce returns random hex nonce
+func NewNonce() (string, error) {
+	buf := make([]byte, nonceBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("can not generate nonce: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,60 @@
+package signing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeyring(t *testing.T) {
+	t.Run("bare_secret", func(t *testing.T) {
+		k, err := ParseKeyring("secret")
+		require.NoError(t, err)
+
+		key, ok := k.Current()
+		require.True(t, ok)
+		require.Equal(t, Key{Secret: "secret"}, key)
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		k, err := ParseKeyring("v2:new, v1:old")
+		require.NoError(t, err)
+
+		key, ok := k.Current()
+		require.True(t, ok)
+		require.Equal(t, "v2", key.ID)
+
+		key, ok = k.Key("v1")
+		require.True(t, ok)
+		require.Equal(t, "old", key.Secret)
+
+		_, ok = k.Key("v0")
+		require.False(t, ok)
+	})
+
+	t.Run("bad_format", func(t *testing.T) {
+		_, err := ParseKeyring("v1:,v2:new")
+		require.ErrorIs(t, err, errKeyFormat)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		k, err := ParseKeyring("")
+		require.NoError(t, err)
+		require.True(t, k.Empty())
+	})
+}
+
+func TestNonceCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewNonceCache(time.Minute, 2)
+
+	require.NoError(t, c.Add("a", now))
+	require.ErrorIs(t, c.Add("a", now.Add(time.Second)), ErrReplay)
+	require.NoError(t, c.Add("b", now))
+	require.ErrorIs(t, c.Add("c", now), ErrNonceCacheFull)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, c.Add("c", now), "expired nonces are swept")
+	require.NoError(t, c.Add("a", now), "nonce can be reused after ttl")
+}
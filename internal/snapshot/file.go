@@ -0,0 +1,451 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+)
+
+// Compression of snapshot payload
+type Compression string
+
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+const (
+	snapshotMagic = "metrics-collector-snapshot"
+	// snapshotVersion 1 is headerless JSON written before versioning
+	snapshotVersion = 2
+)
+
+var (
+	ErrCompression     = errors.New("snapshot compression must be none, gzip or zstd")
+	ErrSnapshotVersion = errors.New("unsupported snapshot version")
+	ErrChecksum        = errors.New("snapshot checksum mismatch")
+)
+
+// ParseCompression parses compression name, empty name means no compression
+func ParseCompression(name string) (Compression, error) {
+	switch c := Compression(strings.ToLower(name)); c {
+	case "", CompressionNone:
+		return CompressionNone, nil
+	case CompressionGzip, CompressionZstd:
+		return c, nil
+	default:
+		return "", fmt.Errorf("%q: %w", name, ErrCompression)
+	}
+}
+
+// Header is the first line of snapshot file, checksum and size are of the stored payload following it
+type Header struct {
+	Magic       string      `json:"magic"`
+	Version     int         `json:"version"`
+	Compression Compression `json:"compression"`
+	Checksum    string      `json:"sha256"`
+	Size        int64       `json:"size"`
+	WALSeq      uint64      `json:"wal_seq,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// Encode serializes snapshot with header, payload is compressed with compression
+func Encode(snap Snapshot, compression Compression) ([]byte, error) {
+	raw, err := json.Marshal(snap)
+	if err != nil {
+		return nil, fmt.Errorf("can not marshal to JSON: %w", err)
+	}
+
+	payload, err := compress(raw, compression)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(payload)
+	hdr, err := encodeHeader(compression, sum[:], int64(len(payload)), snap.WALSeq)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, len(hdr)+len(payload))
+	data = append(data, hdr...)
+	return append(data, payload...), nil
+}
+
+// Stream captures state of all tenants from repo and writes it to w in the same format as Encode.
+// Only one tenant is kept in memory at a time, compressed payload is spooled to a temporary file
+// because its checksum and size are written in the header before it.
+func Stream(ctx context.Context, repo source, w io.Writer, compression Compression) error {
+	spool, err := os.CreateTemp("", "snapshot-*")
+	if err != nil {
+		return fmt.Errorf("can not create temporary file: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	sum := sha256.New()
+	cw, err := compressWriter(io.MultiWriter(spool, sum), compression)
+	if err != nil {
+		return err
+	}
+
+	err = writePayload(ctx, repo, cw)
+	if closeErr := cw.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	size, err := spool.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	_, err = spool.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	hdr, err := encodeHeader(compression, sum.Sum(nil), size, 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, spool)
+	return err
+}
+
+// writePayload writes JSON of Snapshot namespace by namespace, default tenant is inlined on the top level
+func writePayload(ctx context.Context, repo source, w io.Writer) error {
+	tenants, err := repo.Tenants(ctx)
+	if err != nil {
+		return err
+	}
+
+	var defaultNamespace Namespace
+	others := false
+
+	_, err = io.WriteString(w, "{")
+	if err != nil {
+		return err
+	}
+
+	for _, tenant := range tenants {
+		ns, err := captureNamespace(auth.WithTenant(ctx, tenant), repo)
+		if err != nil {
+			return err
+		}
+
+		if tenant == auth.DefaultTenant {
+			defaultNamespace = ns
+			continue
+		}
+
+		prefix := ","
+		if !others {
+			prefix = `"tenants":{`
+			others = true
+		}
+		name, err := json.Marshal(tenant)
+		if err != nil {
+			return err
+		}
+		err = writeJSON(w, prefix+string(name)+":", ns)
+		if err != nil {
+			return err
+		}
+	}
+
+	if others {
+		_, err = io.WriteString(w, "},")
+		if err != nil {
+			return err
+		}
+	}
+
+	tokens, err := repo.Tokens(ctx)
+	if err != nil {
+		return err
+	}
+	if len(tokens) > 0 {
+		err = writeJSON(w, `"tokens":`, tokens)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, ",")
+		if err != nil {
+			return err
+		}
+	}
+
+	// namespace object is inlined without its braces
+	ns, err := json.Marshal(defaultNamespace)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(ns[1:len(ns)-1], '}'))
+	return err
+}
+
+func writeJSON(w io.Writer, prefix string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("can not marshal to JSON: %w", err)
+	}
+	_, err = io.WriteString(w, prefix)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// encodeHeader returns header line of payload with checksum sum
+func encodeHeader(compression Compression, sum []byte, size int64, walSeq uint64) ([]byte, error) {
+	hdr, err := json.Marshal(Header{
+		Magic:       snapshotMagic,
+		Version:     snapshotVersion,
+		Compression: compression,
+		Checksum:    hex.EncodeToString(sum),
+		Size:        size,
+		WALSeq:      walSeq,
+		CreatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(hdr, '\n'), nil
+}
+
+// ReadFile reads and verifies snapshot file
+func ReadFile(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return Decode(data)
+}
+
+// Decode verifies and deserializes snapshot, headerless snapshots of older versions are accepted
+func Decode(data []byte) (Snapshot, error) {
+	var snap Snapshot
+
+	hdr, payload, ok := splitHeader(data)
+	if !ok {
+		return snap, decodeLegacy(data, &snap)
+	}
+
+	if hdr.Version > snapshotVersion {
+		return snap, fmt.Errorf("%w %d", ErrSnapshotVersion, hdr.Version)
+	}
+	if int64(len(payload)) != hdr.Size {
+		return snap, fmt.Errorf("%w: size %d, expected %d", ErrChecksum, len(payload), hdr.Size)
+	}
+	sum := sha256.Sum256(payload)
+	if hex.EncodeToString(sum[:]) != hdr.Checksum {
+		return snap, ErrChecksum
+	}
+
+	raw, err := decompress(payload, hdr.Compression)
+	if err != nil {
+		return snap, err
+	}
+
+	err = json.Unmarshal(raw, &snap)
+	if err != nil {
+		return snap, fmt.Errorf("can not unmarshal to DTO: %w", err)
+	}
+
+	return snap, nil
+}
+
+// decodeLegacy reads headerless snapshots, the oldest ones are plain arrays of metrics
+func decodeLegacy(data []byte, snap *Snapshot) error {
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &snap.Metrics)
+	} else {
+		err = json.Unmarshal(data, snap)
+	}
+	if err != nil {
+		return fmt.Errorf("can not unmarshal to DTO: %w", err)
+	}
+	return nil
+}
+
+func splitHeader(data []byte) (Header, []byte, bool) {
+	var hdr Header
+
+	line, payload, found := bytes.Cut(data, []byte{'\n'})
+	if !found || json.Unmarshal(line, &hdr) != nil || hdr.Magic != snapshotMagic {
+		return hdr, nil, false
+	}
+
+	return hdr, payload, true
+}
+
+// ReadHeader reads only header of snapshot file, headerless snapshots have zero header
+func ReadHeader(path string) (Header, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Header{}, err
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return Header{}, err
+	}
+
+	hdr, _, _ := splitHeader(line)
+	return hdr, nil
+}
+
+func compress(raw []byte, compression Compression) ([]byte, error) {
+	switch compression {
+	case "", CompressionNone:
+		return raw, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(raw)
+		if err != nil {
+			return nil, err
+		}
+		err = w.Close()
+		return buf.Bytes(), err
+	case CompressionZstd:
+		w, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer w.Close()
+		return w.EncodeAll(raw, nil), nil
+	default:
+		return nil, fmt.Errorf("%q: %w", compression, ErrCompression)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// compressWriter returns writer compressing to w, it must be closed to flush compressed data
+func compressWriter(w io.Writer, compression Compression) (io.WriteCloser, error) {
+	switch compression {
+	case "", CompressionNone:
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("%q: %w", compression, ErrCompression)
+	}
+}
+
+func decompress(payload []byte, compression Compression) ([]byte, error) {
+	switch compression {
+	case "", CompressionNone:
+		return payload, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case CompressionZstd:
+		r, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return r.DecodeAll(payload, nil)
+	default:
+		return nil, fmt.Errorf("%q: %w", compression, ErrCompression)
+	}
+}
+
+// HistoryPath returns path of i-th previous snapshot, 0 is the current one
+func HistoryPath(path string, i int) string {
+	if i == 0 {
+		return path
+	}
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// WriteFile replaces snapshot at path atomically keeping keep previous snapshots,
+// a crash leaves either the old or the new snapshot, never a partially written one
+func WriteFile(path string, data []byte, keep int) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("can not mkdir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("can not create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err != nil {
+		return fmt.Errorf("can not write to file: %w", err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("can not write to file: %w", closeErr)
+	}
+
+	for i := keep; i > 0; i-- {
+		err = os.Rename(HistoryPath(path, i-1), HistoryPath(path, i))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("can not rotate snapshot: %w", err)
+		}
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return fmt.Errorf("can not replace snapshot: %w", err)
+	}
+
+	return syncDir(dir)
+}
+
+// syncDir makes renames in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,158 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+	"github.com/arxon31/metrics-collector/internal/repository/memory"
+)
+
+func testSnapshot(delta int64) Snapshot {
+	return Snapshot{
+		Namespace: Namespace{Metrics: []entity.MetricDTO{{Name: "requests", MetricType: entity.CounterType, Counter: &delta}}},
+		WALSeq:    7,
+	}
+}
+
+func TestSnapshotEncoding(t *testing.T) {
+	for _, compression := range []Compression{CompressionNone, CompressionGzip, CompressionZstd} {
+		t.Run(string(compression), func(t *testing.T) {
+			data, err := Encode(testSnapshot(5), compression)
+			require.NoError(t, err)
+
+			snap, err := Decode(data)
+			require.NoError(t, err)
+			require.Equal(t, testSnapshot(5), snap)
+		})
+	}
+
+	t.Run("corrupt_payload", func(t *testing.T) {
+		data, err := Encode(testSnapshot(5), CompressionGzip)
+		require.NoError(t, err)
+
+		data[len(data)-1] ^= 0xff
+		_, err = Decode(data)
+		require.ErrorIs(t, err, ErrChecksum)
+
+		_, err = Decode(data[:len(data)-1])
+		require.ErrorIs(t, err, ErrChecksum, "truncated snapshot")
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		snap, err := Decode([]byte(`{"metrics":[{"id":"requests","type":"counter","delta":5}]}`))
+		require.NoError(t, err)
+		require.Len(t, snap.Metrics, 1)
+
+		snap, err = Decode([]byte(`[{"id":"load","type":"gauge","value":1.5}]`))
+		require.NoError(t, err)
+		require.Len(t, snap.Metrics, 1)
+	})
+
+	t.Run("unknown_compression", func(t *testing.T) {
+		_, err := ParseCompression("lz4")
+		require.ErrorIs(t, err, ErrCompression)
+	})
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	for _, delta := range []int64{1, 2, 3} {
+		data, err := Encode(testSnapshot(delta), CompressionNone)
+		require.NoError(t, err)
+		require.NoError(t, WriteFile(path, data, 2))
+	}
+
+	_, err := os.Stat(HistoryPath(path, 3))
+	require.ErrorIs(t, err, os.ErrNotExist, "only 2 previous snapshots are kept")
+
+	for i, delta := range []int64{3, 2, 1} {
+		snap, err := ReadFile(HistoryPath(path, i))
+		require.NoError(t, err)
+		require.Equal(t, delta, *snap.Metrics[0].Counter)
+	}
+
+	hdr, err := ReadHeader(path)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), hdr.WALSeq)
+
+	matches, err := filepath.Glob(path + ".tmp-*")
+	require.NoError(t, err)
+	require.Empty(t, matches, "temporary files are removed")
+}
+
+func TestStream(t *testing.T) {
+	ctx := context.Background()
+	acme := auth.WithTenant(ctx, "acme")
+
+	repo := memory.NewMapStorage()
+	require.NoError(t, repo.StoreCounter(ctx, "requests", 5))
+	require.NoError(t, repo.StoreGauge(acme, "load", 0.5))
+	require.NoError(t, repo.StoreMetadata(acme, entity.Metadata{Name: "load", Type: entity.GaugeType, Unit: "ratio"}))
+	require.NoError(t, repo.StoreToken(ctx, entity.APIToken{ID: "t1", Tenant: "acme"}))
+
+	captured, err := Capture(ctx, repo)
+	require.NoError(t, err)
+	encoded, err := Encode(captured, CompressionNone)
+	require.NoError(t, err)
+	want, err := Decode(encoded)
+	require.NoError(t, err)
+
+	for _, compression := range []Compression{CompressionNone, CompressionGzip, CompressionZstd} {
+		t.Run(string(compression), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Stream(ctx, repo, &buf, compression))
+
+			snap, err := Decode(buf.Bytes())
+			require.NoError(t, err)
+			require.Equal(t, want, snap)
+		})
+	}
+
+	t.Run("default_tenant_only", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Stream(ctx, memory.NewMapStorage(), &buf, CompressionNone))
+
+		snap, err := Decode(buf.Bytes())
+		require.NoError(t, err)
+		require.Empty(t, snap.Metrics)
+		require.Nil(t, snap.Tenants)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	acme := auth.WithTenant(ctx, "acme")
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	delta := int64(5)
+	value := 0.5
+	repo := memory.NewMapStorage()
+	require.NoError(t, repo.StoreBatch(ctx, []entity.MetricDTO{{Name: "requests", MetricType: entity.CounterType, Counter: &delta, UpdatedAt: updatedAt}}))
+	require.NoError(t, repo.StoreBatch(acme, []entity.MetricDTO{{Name: "load", MetricType: entity.GaugeType, Gauge: &value, UpdatedAt: updatedAt}}))
+
+	captured, err := Capture(ctx, repo)
+	require.NoError(t, err)
+	encoded, err := Encode(captured, CompressionZstd)
+	require.NoError(t, err)
+	snap, err := Decode(encoded)
+	require.NoError(t, err)
+
+	restored := memory.NewMapStorage()
+	require.NoError(t, Load(ctx, restored, snap))
+
+	for _, tenantCtx := range []context.Context{ctx, acme} {
+		metrics, err := restored.Metrics(tenantCtx)
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		require.True(t, updatedAt.Equal(metrics[0].UpdatedAt), "update time is restored, got %s", metrics[0].UpdatedAt)
+	}
+}
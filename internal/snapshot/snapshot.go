@@ -0,0 +1,161 @@
+// Package snapshot captures state of repository into a versioned, checksummed file format and loads it back,
+// it is shared by failover dumps and admin backups.
+package snapshot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/arxon31/metrics-collector/internal/auth"
+	"github.com/arxon31/metrics-collector/internal/entity"
+)
+
+// Namespace is a captured state of a single tenant
+type Namespace struct {
+	Metrics  entity.StoredMetrics `json:"metrics"`
+	Metadata []entity.Metadata    `json:"metadata,omitempty"`
+}
+
+// Snapshot is a captured state of repository, default tenant is kept on the top level
+type Snapshot struct {
+	Namespace
+	Tenants map[string]Namespace `json:"tenants,omitempty"`
+	Tokens  []entity.APIToken    `json:"tokens,omitempty"`
+	// WALSeq is the first write-ahead log segment not included in snapshot
+	WALSeq uint64 `json:"wal_seq,omitempty"`
+}
+
+type source interface {
+	// Tenants returns names of all tenants having metrics
+	Tenants(ctx context.Context) ([]string, error)
+	// Metrics returns all metrics values
+	Metrics(ctx context.Context) ([]entity.MetricDTO, error)
+	// Metadata returns metadata of all metrics
+	Metadata(ctx context.Context) ([]entity.Metadata, error)
+	// Tokens returns all api tokens
+	Tokens(ctx context.Context) ([]entity.APIToken, error)
+}
+
+type target interface {
+	// StoreBatch stores batch of metrics
+	StoreBatch(ctx context.Context, metrics []entity.MetricDTO) error
+	// StoreMetadata replaces metric metadata
+	StoreMetadata(ctx context.Context, metadata entity.Metadata) error
+	// StoreToken saves api token
+	StoreToken(ctx context.Context, token entity.APIToken) error
+}
+
+// Capture reads state of all tenants from repo
+func Capture(ctx context.Context, repo source) (Snapshot, error) {
+	var snap Snapshot
+
+	tenants, err := repo.Tenants(ctx)
+	if err != nil {
+		return snap, err
+	}
+
+	for _, tenant := range tenants {
+		ns, err := captureNamespace(auth.WithTenant(ctx, tenant), repo)
+		if err != nil {
+			return snap, err
+		}
+
+		if tenant == auth.DefaultTenant {
+			snap.Namespace = ns
+			continue
+		}
+		if snap.Tenants == nil {
+			snap.Tenants = make(map[string]Namespace)
+		}
+		snap.Tenants[tenant] = ns
+	}
+
+	snap.Tokens, err = repo.Tokens(ctx)
+	if err != nil {
+		return snap, err
+	}
+
+	return snap, nil
+}
+
+// captureNamespace reads state of tenant of ctx from repo
+func captureNamespace(ctx context.Context, repo source) (Namespace, error) {
+	var ns Namespace
+	var err error
+
+	ns.Metrics, err = repo.Metrics(ctx)
+	if err != nil {
+		return ns, err
+	}
+	ns.Metadata, err = repo.Metadata(ctx)
+	if err != nil {
+		return ns, err
+	}
+
+	return ns, nil
+}
+
+// Load writes snapshot to repo, counters are added to stored values so repo is expected to be empty.
+// Captured update times are restored so metrics expire as if there was no restart.
+func Load(ctx context.Context, repo target, snap Snapshot) error {
+	for _, token := range snap.Tokens {
+		err := repo.StoreToken(ctx, token)
+		if err != nil {
+			return fmt.Errorf("can not store token: %w", err)
+		}
+	}
+
+	err := loadNamespace(auth.WithTenant(ctx, auth.DefaultTenant), repo, snap.Namespace)
+	if err != nil {
+		return err
+	}
+
+	for tenant, ns := range snap.Tenants {
+		err = loadNamespace(auth.WithTenant(ctx, tenant), repo, ns)
+		if err != nil {
+			return fmt.Errorf("tenant %q: %w", tenant, err)
+		}
+	}
+
+	return nil
+}
+
+// Namespaces returns all namespaces of snapshot by tenant
+func (s Snapshot) Namespaces() map[string]Namespace {
+	namespaces := make(map[string]Namespace, len(s.Tenants)+1)
+	namespaces[auth.DefaultTenant] = s.Namespace
+	for tenant, ns := range s.Tenants {
+		namespaces[tenant] = ns
+	}
+	return namespaces
+}
+
+func loadNamespace(ctx context.Context, repo target, ns Namespace) error {
+	for _, md := range ns.Metadata {
+		err := repo.StoreMetadata(ctx, md)
+		if err != nil {
+			return fmt.Errorf("can not store metadata: %w", err)
+		}
+	}
+
+	metrics := make([]entity.MetricDTO, 0, len(ns.Metrics))
+	for _, m := range ns.Metrics {
+		switch {
+		case m.MetricType == entity.GaugeType && m.Gauge != nil:
+		case m.MetricType == entity.CounterType && m.Counter != nil:
+		default:
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	err := repo.StoreBatch(ctx, metrics)
+	if err != nil {
+		return fmt.Errorf("can not store metrics: %w", err)
+	}
+
+	return nil
+}
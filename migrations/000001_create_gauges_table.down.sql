@@ -1 +1,8 @@
-DROP TABLE IF EXISTS gauges;
\ No newline at end of file
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM gauges) THEN
+        RAISE EXCEPTION 'gauges table holds data, export a snapshot and empty it before rolling back';
+    END IF;
+END
+$$;
+DROP TABLE IF EXISTS gauges;
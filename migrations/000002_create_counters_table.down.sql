@@ -1 +1,8 @@
-DROP TABLE IF EXISTS counters;
\ No newline at end of file
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM counters) THEN
+        RAISE EXCEPTION 'counters table holds data, export a snapshot and empty it before rolling back';
+    END IF;
+END
+$$;
+DROP TABLE IF EXISTS counters;
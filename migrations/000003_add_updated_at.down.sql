@@ -0,0 +1,9 @@
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM gauges) OR EXISTS (SELECT 1 FROM counters) THEN
+        RAISE EXCEPTION 'tables hold metrics, dropping updated_at loses their update time used by expiration, export a snapshot and empty them before rolling back';
+    END IF;
+END
+$$;
+ALTER TABLE gauges DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE counters DROP COLUMN IF EXISTS updated_at;
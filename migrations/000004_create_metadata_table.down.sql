@@ -0,0 +1,8 @@
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM metadata) THEN
+        RAISE EXCEPTION 'metadata table holds data, export a snapshot and empty it before rolling back';
+    END IF;
+END
+$$;
+DROP TABLE IF EXISTS metadata;
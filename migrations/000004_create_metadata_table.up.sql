@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS metadata (
+    name text PRIMARY KEY NOT NULL,
+    type text NOT NULL DEFAULT '',
+    unit text NOT NULL DEFAULT '',
+    description text NOT NULL DEFAULT '',
+    owner text NOT NULL DEFAULT ''
+);
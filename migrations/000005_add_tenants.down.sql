@@ -0,0 +1,25 @@
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM gauges WHERE tenant <> '')
+        OR EXISTS (SELECT 1 FROM counters WHERE tenant <> '')
+        OR EXISTS (SELECT 1 FROM metadata WHERE tenant <> '') THEN
+        RAISE EXCEPTION 'tables hold metrics of non-default tenants, export a snapshot and remove them before rolling back';
+    END IF;
+    IF EXISTS (SELECT 1 FROM api_tokens) THEN
+        RAISE EXCEPTION 'api_tokens table holds data, revoke and remove tokens before rolling back';
+    END IF;
+END
+$$;
+DROP TABLE IF EXISTS api_tokens;
+
+ALTER TABLE metadata DROP CONSTRAINT IF EXISTS metadata_pkey;
+ALTER TABLE metadata DROP COLUMN IF EXISTS tenant;
+ALTER TABLE metadata ADD PRIMARY KEY (name);
+
+ALTER TABLE counters DROP CONSTRAINT IF EXISTS counters_pkey;
+ALTER TABLE counters DROP COLUMN IF EXISTS tenant;
+ALTER TABLE counters ADD PRIMARY KEY (name);
+
+ALTER TABLE gauges DROP CONSTRAINT IF EXISTS gauges_pkey;
+ALTER TABLE gauges DROP COLUMN IF EXISTS tenant;
+ALTER TABLE gauges ADD PRIMARY KEY (name);
@@ -0,0 +1,19 @@
+ALTER TABLE gauges ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT '';
+ALTER TABLE gauges DROP CONSTRAINT IF EXISTS gauges_pkey;
+ALTER TABLE gauges ADD PRIMARY KEY (tenant, name);
+
+ALTER TABLE counters ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT '';
+ALTER TABLE counters DROP CONSTRAINT IF EXISTS counters_pkey;
+ALTER TABLE counters ADD PRIMARY KEY (tenant, name);
+
+ALTER TABLE metadata ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT '';
+ALTER TABLE metadata DROP CONSTRAINT IF EXISTS metadata_pkey;
+ALTER TABLE metadata ADD PRIMARY KEY (tenant, name);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id text PRIMARY KEY NOT NULL,
+    tenant text NOT NULL,
+    hash text NOT NULL UNIQUE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    revoked_at timestamptz
+);
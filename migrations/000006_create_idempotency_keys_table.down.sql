@@ -0,0 +1,2 @@
+-- idempotency keys only cache responses of batches for a limited time, dropping them loses no metrics
+DROP TABLE IF EXISTS idempotency_keys;
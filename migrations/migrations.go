@@ -0,0 +1,10 @@
+// Package migrations embeds versioned database schema migrations, so the server does not depend on working directory.
+// Migrations are sequential, up migrations never drop data and down migrations refuse to run on tables holding data.
+package migrations
+
+import "embed"
+
+// FS contains up and down migrations named <version>_<title>.<up|down>.sql
+//
+//go:embed *.sql
+var FS embed.FS
@@ -1,19 +1,88 @@
 package httpclient
 
 import (
+	"compress/gzip"
+	"io"
 	"net/http"
+	"strings"
 )
 
+type verifier interface {
+	Verify(resp *http.Response) error
+}
+
 type client struct {
-	client *http.Client
+	client    *http.Client
+	transport *http.Transport
+	headers   map[string]string
+	verifier  verifier
 }
 
-func NewClient() *client {
-	return &client{
-		client: &http.Client{},
+func NewClient(opts ...Option) *client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	c := &client{
+		client:    &http.Client{Transport: transport},
+		transport: transport,
+		headers:   make(map[string]string),
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
 func (c *client) Do(req *http.Request) (*http.Response, error) {
-	return c.client.Do(req)
+	for key, value := range c.headers {
+		if req.Header.Get(key) == "" {
+			req.Header.Set(key, value)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil || c.verifier == nil {
+		return resp, err
+	}
+
+	err = c.verifier.Verify(resp)
+	if err == nil {
+		err = decompress(resp)
+	}
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// decompress replaces gzipped body of verified response with decompressed one,
+// as transport does when transparent decompression is enabled
+func decompress(resp *http.Response) error {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	resp.Body = &gzipBody{Reader: zr, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return nil
+}
+
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	return b.body.Close()
 }
@@ -0,0 +1,42 @@
+package httpclient
+
+import "crypto/tls"
+
+type Option func(c *client)
+
+// WithHeader sets header to every request unless request already has it
+func WithHeader(key, value string) Option {
+	return func(c *client) {
+		if value == "" {
+			return
+		}
+		c.headers[key] = value
+	}
+}
+
+// WithBearerToken sets Authorization header with bearer token to every request
+func WithBearerToken(token string) Option {
+	return func(c *client) {
+		if token == "" {
+			return
+		}
+		c.headers["Authorization"] = "Bearer " + token
+	}
+}
+
+// WithResponseVerifier checks every response with verifier, transparent decompression is disabled
+// so verifier gets the body exactly as it was sent by server, gzipped body is decompressed after check
+func WithResponseVerifier(v verifier) Option {
+	return func(c *client) {
+		c.transport.DisableCompression = true
+		c.headers["Accept-Encoding"] = "gzip"
+		c.verifier = v
+	}
+}
+
+// WithTLSConfig sets TLS config of https connections
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *client) {
+		c.transport.TLSClientConfig = cfg
+	}
+}
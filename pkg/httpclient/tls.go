@@ -0,0 +1,32 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/arxon31/metrics-collector/pkg/httpserver"
+)
+
+// NewTLSConfig creates TLS config trusting only CA from caFile, system roots are used if caFile is empty.
+// Client certificate is presented if certFile and keyFile are set.
+func NewTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		pool, err := httpserver.LoadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
@@ -16,3 +16,26 @@ func WithAddr(addr string) Option {
 	}
 
 }
+
+// WithTLS serves https with certificate and key files reloaded on change
+func WithTLS(certFile, keyFile string) Option {
+	return func(s *server) {
+		s.certFile = certFile
+		s.keyFile = keyFile
+	}
+}
+
+// WithTLSPolicy sets minimal TLS version and allowed cipher suites, nil suites mean defaults
+func WithTLSPolicy(minVersion uint16, cipherSuites []uint16) Option {
+	return func(s *server) {
+		s.tlsConfig.MinVersion = minVersion
+		s.tlsConfig.CipherSuites = cipherSuites
+	}
+}
+
+// WithClientCA requires client certificates signed by CA from PEM bundle
+func WithClientCA(caFile string) Option {
+	return func(s *server) {
+		s.clientCAFile = caFile
+	}
+}
@@ -2,6 +2,7 @@ package httpserver
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
 	"time"
 )
@@ -15,6 +16,10 @@ type server struct {
 	server          *http.Server
 	notify          chan error
 	shutdownTimeout time.Duration
+	tlsConfig       *tls.Config
+	certFile        string
+	keyFile         string
+	clientCAFile    string
 }
 
 func NewHTTPServer(handler http.Handler, opts ...Option) *server {
@@ -27,6 +32,7 @@ func NewHTTPServer(handler http.Handler, opts ...Option) *server {
 		server:          httpServer,
 		notify:          make(chan error, 1),
 		shutdownTimeout: _defaultShutdownTimeout,
+		tlsConfig:       &tls.Config{MinVersion: tls.VersionTLS12},
 	}
 
 	for _, opt := range opts {
@@ -41,11 +47,35 @@ func NewHTTPServer(handler http.Handler, opts ...Option) *server {
 
 func (s *server) start() {
 	go func() {
-		s.notify <- s.server.ListenAndServe()
+		s.notify <- s.serve()
 		close(s.notify)
 	}()
 }
 
+func (s *server) serve() error {
+	if s.certFile == "" {
+		return s.server.ListenAndServe()
+	}
+
+	reloader, err := newCertReloader(s.certFile, s.keyFile)
+	if err != nil {
+		return err
+	}
+	s.tlsConfig.GetCertificate = reloader.GetCertificate
+
+	if s.clientCAFile != "" {
+		pool, err := LoadCertPool(s.clientCAFile)
+		if err != nil {
+			return err
+		}
+		s.tlsConfig.ClientCAs = pool
+		s.tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	s.server.TLSConfig = s.tlsConfig
+	return s.server.ListenAndServeTLS("", "")
+}
+
 func (s *server) Notify() chan error {
 	return s.notify
 }